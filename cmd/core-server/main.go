@@ -1,14 +1,22 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
+	"time"
 
 	cmmn "github.com/kptm-tools/common/common/events"
 	"github.com/kptm-tools/core-service/pkg/api"
 	"github.com/kptm-tools/core-service/pkg/config"
 	"github.com/kptm-tools/core-service/pkg/handlers"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/notifiers"
+	"github.com/kptm-tools/core-service/pkg/scheduler"
 	"github.com/kptm-tools/core-service/pkg/services"
 	"github.com/kptm-tools/core-service/pkg/storage"
+	"github.com/kptm-tools/core-service/pkg/subscribers"
 )
 
 func main() {
@@ -30,8 +38,8 @@ func main() {
 		log.Fatalf("Failed to create Core DB store: `%+v`", err)
 	}
 
-	if err := coreStore.InitCoreDB(); err != nil {
-		log.Fatalf("Error initializing Core DB: `%+v`", err)
+	if err := coreStore.CheckMigrations(); err != nil {
+		log.Fatalf("Error checking Core DB schema: `%+v`", err)
 	}
 
 	eventBus, err := cmmn.NewNatsEventBus(c.GetNatsConnStr())
@@ -42,21 +50,139 @@ func main() {
 	// Services
 	healthService := services.NewHealthcheckService(coreStore)
 	authService := services.NewAuthService(coreStore)
-	hostService := services.NewHostService(coreStore)
+	credentialKeyring, err := c.GetCredentialKeyring()
+	if err != nil {
+		log.Fatalf("Failed to load credential keyring: `%+v`", err)
+	}
+	hostService := services.NewHostService(coreStore, authService, credentialKeyring, c.GetHostImportLimit())
 	tenantService := services.NewTenantService(coreStore)
 	scanService := services.NewScanService(coreStore)
+	scheduleService := services.NewScheduleService(coreStore)
+	notifier, err := newNotifier(c)
+	if err != nil {
+		log.Fatalf("Failed to create notifier: `%+v`", err)
+	}
+	notificationService := services.NewNotificationService(coreStore, notifier, c.GetNotificationMaxAttempts())
+	groupService := services.NewGroupService(coreStore)
+	auditService := services.NewAuditService(coreStore)
 
 	// Handlers
 	healthHandler := handlers.NewHealthcheckHandlers(healthService)
-	authHandlers := handlers.NewAuthHandlers(authService)
+	authHandlers := handlers.NewAuthHandlers(authService, handlers.AuthCookies{
+		Domain:          c.AuthCookieDomain,
+		Secure:          c.GetAuthCookieSecure(),
+		RefreshTokenTTL: c.GetRefreshTokenTTL(),
+	})
+	userCache := middleware.NewUserCache(authService, c.GetUserCacheSize(), c.GetUserCacheTTL(), c.GetUserCacheNegativeTTL())
+	middleware.SetUserCache(userCache)
+	webhookHandlers := handlers.NewWebhookHandlers(userCache, c.FusionAuthWebhookKey)
+	permissionService := services.NewPermissionService(coreStore, c.GetPermissionsCacheTTL())
+	middleware.SetPermissions(permissionService)
+	permissionHandlers := handlers.NewPermissionHandlers(permissionService)
+	apiKeyService := services.NewAPIKeyService(coreStore)
+	middleware.SetAPIKeys(apiKeyService)
+	apiKeyHandlers := handlers.NewAPIKeyHandlers(apiKeyService)
 	hostHandlers := handlers.NewHostHandlers(hostService)
 	tenantHandlers := handlers.NewTenantHandlers(tenantService)
-	scanHandlers := handlers.NewScanHandlers(scanService, eventBus)
+	scanUpdatesBroker := subscribers.NewScanUpdatesBroker(eventBus)
+	if err := scanUpdatesBroker.Init(); err != nil {
+		log.Fatalf("Failed to initialize scan updates broker: `%+v`", err)
+	}
+	scanHandlers := handlers.NewScanHandlers(scanService, eventBus, scanUpdatesBroker)
+	scheduleHandlers := handlers.NewScheduleHandlers(scheduleService)
+	notificationHandlers := handlers.NewNotificationHandlers(notificationService)
+	groupHandlers := handlers.NewGroupHandlers(groupService)
+	auditHandlers := handlers.NewAuditHandlers(auditService)
+
+	// Subscribers
+	scanSubscribers := subscribers.NewScanSubscribers(scanService, eventBus)
+	if err := scanSubscribers.Init(); err != nil {
+		log.Fatalf("Failed to initialize scan subscribers: `%+v`", err)
+	}
+	notificationSubscribers := subscribers.NewNotificationSubscribers(notificationService, eventBus)
+	if err := notificationSubscribers.Init(); err != nil {
+		log.Fatalf("Failed to initialize notification subscribers: `%+v`", err)
+	}
+
+	go watchScanTimeouts(scanService, eventBus, c.GetScanTimeout())
+	go purgeDeletedHosts(hostService, c.GetHostRetention())
+	go retryNotifications(notificationService)
+
+	// Scheduler
+	scanScheduler := scheduler.NewScheduler(scheduleService, scanService, eventBus, time.Minute)
+	go scanScheduler.Run()
+
 	// Server
-	s := api.NewAPIServer(":8000", healthHandler, hostHandlers, tenantHandlers, authHandlers, scanHandlers)
+	s := api.NewAPIServer(":8000", healthHandler, hostHandlers, tenantHandlers, authHandlers, scanHandlers, scheduleHandlers, notificationHandlers, groupHandlers, auditHandlers, webhookHandlers, permissionHandlers, apiKeyHandlers)
 
 	if err := s.Init(); err != nil {
 		log.Fatalf("Failed to initialize APIServer: `%+v`", err)
 	}
 
 }
+
+// newNotifier picks how rapporteurs are notified from the NOTIFIER setting
+func newNotifier(c *config.Config) (interfaces.INotifier, error) {
+	switch c.Notifier {
+	case "smtp":
+		return notifiers.NewSMTPNotifier(c.SMTPHost, c.SMTPPort, c.SMTPUsername, c.SMTPPassword, c.SMTPFrom), nil
+	case "file":
+		return notifiers.NewFileNotifier(c.NotificationsFile)
+	case "log", "":
+		return notifiers.NewLogNotifier(os.Stdout), nil
+	}
+	return nil, fmt.Errorf("unknown notifier `%s`", c.Notifier)
+}
+
+// watchScanTimeouts periodically times out scans that have been active for too long
+func watchScanTimeouts(scanService *services.ScanService, eventBus cmmn.EventBus, timeout time.Duration) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		scans, err := scanService.TimeoutScans(timeout)
+		if err != nil {
+			log.Printf("Error timing out scans: `%+v`", err)
+		}
+		for _, scan := range scans {
+			log.Printf("Scan `%s` timed out", scan.ID)
+			if err := services.PublishScanUpdated(eventBus, scan); err != nil {
+				log.Printf("Error publishing scan update: `%+v`", err)
+			}
+		}
+	}
+}
+
+// retryNotifications periodically sends again the notifications that failed,
+// until they run out of attempts
+func retryNotifications(notificationService *services.NotificationService) {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sent, err := notificationService.RetryFailedNotifications()
+		if err != nil {
+			log.Printf("Error retrying notifications: `%+v`", err)
+		}
+		if len(sent) > 0 {
+			log.Printf("Retried %d notifications", len(sent))
+		}
+	}
+}
+
+// purgeDeletedHosts periodically removes the hosts deleted longer than retention ago
+func purgeDeletedHosts(hostService *services.HostService, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := hostService.PurgeDeletedHosts(retention)
+		if err != nil {
+			log.Printf("Error purging deleted hosts: `%+v`", err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("Purged %d deleted hosts", purged)
+		}
+	}
+}
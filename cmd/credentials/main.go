@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kptm-tools/core-service/pkg/config"
+	"github.com/kptm-tools/core-service/pkg/services"
+	"github.com/kptm-tools/core-service/pkg/storage"
+)
+
+const usage = "Usage: go run main.go [rotate]"
+
+func main() {
+
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		return
+	}
+
+	c := config.LoadConfig()
+	coreStore, err := storage.NewPostgreSQLStore(c.PostgreSQLCoreConnStr())
+	if err != nil {
+		panic(err)
+	}
+
+	command := os.Args[1]
+	switch command {
+
+	case "rotate":
+		credentialKeyring, err := c.GetCredentialKeyring()
+		if err != nil {
+			panic(err)
+		}
+		hostService := services.NewHostService(coreStore, nil, credentialKeyring, c.GetHostImportLimit())
+
+		fmt.Printf("Rotating credentials to key `%s`...\n", credentialKeyring.ActiveKeyID())
+		rotated, err := hostService.RotateCredentials(c.CredentialLegacyPass)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%d credentials rotated\n", rotated)
+	default:
+		fmt.Printf("Unknown command `%s`\n", command)
+		fmt.Println(usage)
+	}
+}
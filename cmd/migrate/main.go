@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/kptm-tools/core-service/pkg/config"
+	"github.com/kptm-tools/core-service/pkg/storage"
+)
+
+const (
+	usage = "Usage: go run main.go [up [n]|down [n]|status|create <name>]"
+	// migrationsDir is where create writes new migrations, relative to the repository root
+	migrationsDir = "pkg/storage/migrations"
+)
+
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+func main() {
+
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		return
+	}
+
+	command := os.Args[1]
+	switch command {
+
+	case "up":
+		steps, err := parseSteps(0)
+		if err != nil {
+			panic(err)
+		}
+		applied, err := newCoreStore(true).MigrateUp(steps)
+		for _, m := range applied {
+			fmt.Printf("Applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%d migrations applied\n", len(applied))
+	case "down":
+		steps, err := parseSteps(1)
+		if err != nil {
+			panic(err)
+		}
+		reverted, err := newCoreStore(false).MigrateDown(steps)
+		for _, m := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%d migrations reverted\n", len(reverted))
+	case "status":
+		status, err := newCoreStore(false).MigrationStatus()
+		if err != nil {
+			panic(err)
+		}
+		for _, m := range status {
+			appliedAt := "pending"
+			if m.AppliedAt != nil {
+				appliedAt = m.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-40s %s\n", m.Version, m.Name, appliedAt)
+		}
+	case "create":
+		if len(os.Args) < 3 || !migrationName.MatchString(os.Args[2]) {
+			fmt.Println("Migration name must be lowercase letters, digits and underscores")
+			fmt.Println(usage)
+			return
+		}
+		files, err := createMigration(migrationsDir, os.Args[2])
+		if err != nil {
+			panic(err)
+		}
+		for _, f := range files {
+			fmt.Printf("Created %s\n", f)
+		}
+	default:
+		fmt.Printf("Unknown command `%s`\n", command)
+		fmt.Println(usage)
+	}
+}
+
+// newCoreStore connects to the Core DB as its owner, which bypasses row-level
+// security. When create is set the Core DB is created first if it doesn't exist.
+func newCoreStore(create bool) *storage.PostgreSQLStore {
+	c := config.LoadConfig()
+
+	if create {
+		rootStore, err := storage.NewPostgreSQLStore(c.PostgreSQLRootConnStr())
+		if err != nil {
+			panic(err)
+		}
+		if err := rootStore.Init(); err != nil {
+			panic(err)
+		}
+	}
+
+	coreStore, err := storage.NewPostgreSQLStore(c.PostgreSQLCoreConnStr())
+	if err != nil {
+		panic(err)
+	}
+	return coreStore
+}
+
+func parseSteps(defaultSteps int) (int, error) {
+	if len(os.Args) < 3 {
+		return defaultSteps, nil
+	}
+	steps, err := strconv.Atoi(os.Args[2])
+	if err != nil || steps < 1 {
+		return 0, fmt.Errorf("invalid number of migrations: `%s`", os.Args[2])
+	}
+	return steps, nil
+}
+
+// createMigration writes an empty up and down pair numbered after the latest migration in dir
+func createMigration(dir string, name string) ([]string, error) {
+	migrations, err := storage.LoadMigrations(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	version := int64(1)
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	files := []string{}
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%04d_%s.%s.sql", version, name, direction))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return files, err
+		}
+		if _, err := fmt.Fprintf(f, "-- %04d_%s %s\n", version, name, direction); err != nil {
+			f.Close()
+			return files, err
+		}
+		if err := f.Close(); err != nil {
+			return files, err
+		}
+		files = append(files, path)
+	}
+
+	return files, nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/kptm-tools/core-service/pkg/config"
+	"github.com/kptm-tools/core-service/pkg/domain"
 	"github.com/kptm-tools/core-service/pkg/interfaces"
 	"github.com/kptm-tools/core-service/pkg/samples"
 	"github.com/kptm-tools/core-service/pkg/services"
@@ -19,7 +20,8 @@ func main() {
 	}
 
 	c := config.LoadConfig()
-	coreStore, err := storage.NewPostgreSQLStore(c.PostgreSQLCoreConnStr())
+	// Sample data spans every tenant, so bypass row-level security
+	coreStore, err := storage.NewPostgreSQLStore(c.PostgreSQLAdminConnStr())
 	if err != nil {
 		panic(err)
 	}
@@ -28,7 +30,7 @@ func main() {
 	switch command {
 
 	case "populate":
-		populateDB(coreStore)
+		populateDB(coreStore, c)
 	case "clear":
 		fmt.Println("Clearing DB...")
 		if err := coreStore.ClearCoreDB(); err != nil {
@@ -41,7 +43,7 @@ func main() {
 	}
 }
 
-func populateDB(store interfaces.IStorage) {
+func populateDB(store interfaces.IStorage, c *config.Config) {
 	fmt.Println("Populating DB with sample data...")
 
 	if err := populateTenants(store); err != nil {
@@ -49,7 +51,7 @@ func populateDB(store interfaces.IStorage) {
 	}
 	fmt.Println("Tenants populated successfully")
 
-	if err := populateHosts(store); err != nil {
+	if err := populateHosts(store, c); err != nil {
 		panic(err)
 	}
 	fmt.Println("Hosts populated successfully")
@@ -69,13 +71,18 @@ func populateTenants(store interfaces.IStorage) error {
 	return nil
 }
 
-func populateHosts(store interfaces.IStorage) error {
-	hostService := services.NewHostService(store)
+func populateHosts(store interfaces.IStorage, c *config.Config) error {
+	credentialKeyring, err := c.GetCredentialKeyring()
+	if err != nil {
+		return fmt.Errorf("error loading credential keyring: %w", err)
+	}
+	hostService := services.NewHostService(store, nil, credentialKeyring, c.GetHostImportLimit())
 	sampleHosts := samples.SampleHosts()
 
 	for _, host := range sampleHosts {
 
-		_, err := hostService.CreateHost(&host)
+		actor := domain.Actor{TenantID: host.TenantID, UserID: host.OperatorID}
+		_, err := hostService.CreateHost(actor, &host)
 		if err != nil {
 			return fmt.Errorf("error populating host %s: %w", host.Name, err)
 		}
@@ -13,14 +13,15 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/jpillora/go-tld v1.2.1
 	github.com/kptm-tools/common v1.2.14
+	github.com/likexian/whois-parser v1.24.20
+	github.com/nats-io/nats.go v1.38.0
 	github.com/prometheus-community/pro-bing v0.5.0
+	github.com/robfig/cron/v3 v3.0.1
 )
 
 require (
 	github.com/klauspost/compress v1.17.9 // indirect
 	github.com/likexian/gokit v0.25.15 // indirect
-	github.com/likexian/whois-parser v1.24.20 // indirect
-	github.com/nats-io/nats.go v1.38.0 // indirect
 	github.com/nats-io/nkeys v0.4.9 // indirect
 	github.com/nats-io/nuid v1.0.1 // indirect
 	golang.org/x/crypto v0.31.0 // indirect
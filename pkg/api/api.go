@@ -2,12 +2,14 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"reflect"
 	"runtime"
 	"strings"
 
+	"github.com/kptm-tools/core-service/pkg/domain"
 	"github.com/kptm-tools/core-service/pkg/interfaces"
 	"github.com/kptm-tools/core-service/pkg/middleware"
 )
@@ -20,6 +22,14 @@ type APIServer struct {
 	authHandlers   interfaces.IAuthHandlers
 	tenantHandlers interfaces.ITenantHandlers
 	scanHandlers   interfaces.IScanHandlers
+
+	scheduleHandlers     interfaces.IScheduleHandlers
+	notificationHandlers interfaces.INotificationHandlers
+	groupHandlers        interfaces.IGroupHandlers
+	auditHandlers        interfaces.IAuditHandlers
+	webhookHandlers      interfaces.IWebhookHandlers
+	permissionHandlers   interfaces.IPermissionHandlers
+	apiKeyHandlers       interfaces.IAPIKeyHandlers
 }
 
 type APIError struct {
@@ -28,6 +38,17 @@ type APIError struct {
 
 type APIFunc func(http.ResponseWriter, *http.Request) error
 
+// route is an endpoint that requires a token whose roles have every permission in permissions
+type route struct {
+	pattern     string
+	handler     APIFunc
+	permissions []domain.Permission
+}
+
+func requires(permissions ...domain.Permission) []domain.Permission {
+	return permissions
+}
+
 func NewAPIServer(
 	listenAddr string,
 	heHandlers interfaces.IHealthcheckHandlers,
@@ -35,6 +56,13 @@ func NewAPIServer(
 	teHandlers interfaces.ITenantHandlers,
 	aHandlers interfaces.IAuthHandlers,
 	sHandlers interfaces.IScanHandlers,
+	scHandlers interfaces.IScheduleHandlers,
+	nHandlers interfaces.INotificationHandlers,
+	gHandlers interfaces.IGroupHandlers,
+	auHandlers interfaces.IAuditHandlers,
+	wHandlers interfaces.IWebhookHandlers,
+	pHandlers interfaces.IPermissionHandlers,
+	kHandlers interfaces.IAPIKeyHandlers,
 ) *APIServer {
 	return &APIServer{
 		listenAddr: listenAddr,
@@ -44,6 +72,14 @@ func NewAPIServer(
 		authHandlers:   aHandlers,
 		tenantHandlers: teHandlers,
 		scanHandlers:   sHandlers,
+
+		scheduleHandlers:     scHandlers,
+		notificationHandlers: nHandlers,
+		groupHandlers:        gHandlers,
+		auditHandlers:        auHandlers,
+		webhookHandlers:      wHandlers,
+		permissionHandlers:   pHandlers,
+		apiKeyHandlers:       kHandlers,
 	}
 }
 
@@ -56,24 +92,26 @@ func (s *APIServer) Init() error {
 
 	// Auth routes
 	router.HandleFunc("POST /api/login", makeHTTPHandlerFunc(s.authHandlers.Login))
+	router.HandleFunc("POST /api/token/refresh", makeHTTPHandlerFunc(s.authHandlers.RefreshToken))
+	router.HandleFunc("POST /api/token/logout", makeHTTPHandlerFunc(s.authHandlers.Logout))
 	router.HandleFunc("POST /api/forgot-password", makeHTTPHandlerFunc(s.authHandlers.ForgotPassword))
 	router.HandleFunc("POST /api/change-password", makeHTTPHandlerFunc(s.authHandlers.ChangePassword))
 	router.HandleFunc("POST /api/users", makeHTTPHandlerFunc(s.authHandlers.RegisterUser))
 	router.HandleFunc("POST /api/users/{id}/verify-email", makeHTTPHandlerFunc(s.authHandlers.VerifyEmail))
 	router.HandleFunc("POST /api/tenants", makeHTTPHandlerFunc(s.authHandlers.RegisterTenant))
-	router.HandleFunc("GET /api/users/{id}", middleware.WithAuth(makeHTTPHandlerFunc(s.authHandlers.GetUser), "getUser"))
 
-	router.HandleFunc("POST /api/hosts", middleware.WithAuth(makeHTTPHandlerFunc(s.hostHandlers.CreateHost), "newHost"))
-	router.HandleFunc("POST /api/hosts/validate", middleware.WithAuth(makeHTTPHandlerFunc(s.hostHandlers.ValidateHost), "validateHost"))
-	router.HandleFunc("GET /api/hosts", middleware.WithAuth(makeHTTPHandlerFunc(s.hostHandlers.GetHostsByTenantIDAndUserID), "getHostsByTenantAndUser"))
-	router.HandleFunc("GET /api/hosts/{id}", middleware.WithAuth(makeHTTPHandlerFunc(s.hostHandlers.GetHostByID), "getHostByID"))
-	router.HandleFunc("DELETE /api/hosts/{id}", middleware.WithAuth(makeHTTPHandlerFunc(s.hostHandlers.DeleteHostByID), "deleteHostByID"))
-	router.HandleFunc("PATCH /api/hosts/{id}", middleware.WithAuth(makeHTTPHandlerFunc(s.hostHandlers.PatchHostByID), "patchHostByID"))
-	router.HandleFunc("GET /tenants", middleware.WithAuth(makeHTTPHandlerFunc(s.tenantHandlers.GetTenants), "tenants"))
+	routes := s.authRoutes()
+	if err := checkRoutes(routes); err != nil {
+		return err
+	}
+	for _, r := range routes {
+		router.HandleFunc(r.pattern, middleware.WithAuth(makeHTTPHandlerFunc(r.handler), r.permissions...))
+	}
 
-	router.HandleFunc("POST /api/scans", middleware.WithAuth(makeHTTPHandlerFunc(s.scanHandlers.CreateScans), "createScans"))
+	router.HandleFunc("POST /api/webhooks/fusionauth", makeHTTPHandlerFunc(s.webhookHandlers.FusionAuthEvent))
 
 	stack := middleware.CreateStack(
+		middleware.RequestID,
 		middleware.Logging,
 		middleware.CheckCORS,
 	)
@@ -90,6 +128,84 @@ func (s *APIServer) Init() error {
 
 }
 
+// authRoutes lists the endpoints that need a token, each one declares the
+// permissions it requires
+func (s *APIServer) authRoutes() []route {
+	return []route{
+		{"GET /api/users/{id}", s.authHandlers.GetUser, requires(domain.PermUsersRead)},
+		{"POST /api/hosts", s.hostHandlers.CreateHost, requires(domain.PermHostsCreate)},
+		{"POST /api/hosts/import", s.hostHandlers.ImportHosts, requires(domain.PermHostsCreate)},
+		{"POST /api/hosts/validate", s.hostHandlers.ValidateHost, requires(domain.PermHostsCreate)},
+		{"GET /api/hosts", s.hostHandlers.GetHostsByTenantIDAndUserID, requires(domain.PermHostsRead)},
+		{"GET /api/hosts/{id}", s.hostHandlers.GetHostByID, requires(domain.PermHostsRead)},
+		{"DELETE /api/hosts/{id}", s.hostHandlers.DeleteHostByID, requires(domain.PermHostsWrite)},
+		{"PATCH /api/hosts/{id}", s.hostHandlers.PatchHostByID, requires(domain.PermHostsWrite)},
+		{"POST /api/hosts/{id}/restore", s.hostHandlers.RestoreHost, requires(domain.PermHostsManage)},
+		{"POST /api/hosts/{id}/transfer", s.hostHandlers.TransferHost, requires(domain.PermHostsManage)},
+		{"PUT /api/hosts/{id}/tags", s.groupHandlers.SetHostTags, requires(domain.PermHostsWrite)},
+		{"GET /tenants", s.tenantHandlers.GetTenants, requires(domain.PermTenantsRead)},
+
+		{"POST /api/tags", s.groupHandlers.CreateTag, requires(domain.PermTagsWrite)},
+		{"GET /api/tags", s.groupHandlers.GetTags, requires(domain.PermTagsRead)},
+		{"PATCH /api/tags/{id}", s.groupHandlers.PatchTagByID, requires(domain.PermTagsWrite)},
+		{"DELETE /api/tags/{id}", s.groupHandlers.DeleteTagByID, requires(domain.PermTagsWrite)},
+
+		{"POST /api/groups", s.groupHandlers.CreateHostGroup, requires(domain.PermGroupsWrite)},
+		{"GET /api/groups", s.groupHandlers.GetHostGroups, requires(domain.PermGroupsRead)},
+		{"GET /api/groups/{id}", s.groupHandlers.GetHostGroupByID, requires(domain.PermGroupsRead)},
+		{"PATCH /api/groups/{id}", s.groupHandlers.PatchHostGroupByID, requires(domain.PermGroupsWrite)},
+		{"DELETE /api/groups/{id}", s.groupHandlers.DeleteHostGroupByID, requires(domain.PermGroupsWrite)},
+
+		{"POST /api/scans", s.scanHandlers.CreateScans, requires(domain.PermScansCreate)},
+		{"GET /api/scans", s.scanHandlers.GetScans, requires(domain.PermScansRead)},
+		{"GET /api/scans/diff", s.scanHandlers.DiffScans, requires(domain.PermScansRead)},
+		{"GET /api/scans/{id}", s.scanHandlers.GetScanByID, requires(domain.PermScansRead)},
+		{"GET /api/scans/{id}/events", s.scanHandlers.StreamScanEvents, requires(domain.PermScansRead)},
+		{"GET /api/scans/{id}/report", s.scanHandlers.GetScanReport, requires(domain.PermScansRead)},
+		{"GET /api/scans/{id}/notifications", s.notificationHandlers.GetScanNotifications, requires(domain.PermScansRead)},
+		{"POST /api/scans/{id}/cancel", s.scanHandlers.CancelScan, requires(domain.PermScansCancel)},
+
+		{"POST /api/schedules", s.scheduleHandlers.CreateSchedule, requires(domain.PermSchedulesWrite)},
+		{"GET /api/schedules", s.scheduleHandlers.GetSchedules, requires(domain.PermSchedulesRead)},
+		{"GET /api/schedules/{id}", s.scheduleHandlers.GetScheduleByID, requires(domain.PermSchedulesRead)},
+		{"PATCH /api/schedules/{id}", s.scheduleHandlers.PatchScheduleByID, requires(domain.PermSchedulesWrite)},
+		{"DELETE /api/schedules/{id}", s.scheduleHandlers.DeleteScheduleByID, requires(domain.PermSchedulesWrite)},
+		{"GET /api/schedules/{id}/preview", s.scheduleHandlers.PreviewSchedule, requires(domain.PermSchedulesRead)},
+
+		{"GET /api/notifications/template", s.notificationHandlers.GetNotificationTemplate, requires(domain.PermNotificationsRead)},
+		{"PUT /api/notifications/template", s.notificationHandlers.UpdateNotificationTemplate, requires(domain.PermNotificationsWrite)},
+		{"DELETE /api/notifications/template", s.notificationHandlers.ResetNotificationTemplate, requires(domain.PermNotificationsWrite)},
+
+		{"GET /api/audit", s.auditHandlers.GetAuditEvents, requires(domain.PermAuditRead)},
+
+		{"GET /api/permissions", s.permissionHandlers.GetRolePermissions, requires(domain.PermUsersManage)},
+		{"PUT /api/permissions/{role}", s.permissionHandlers.SetRolePermissions, requires(domain.PermUsersManage)},
+		{"DELETE /api/permissions/{role}", s.permissionHandlers.ResetRolePermissions, requires(domain.PermUsersManage)},
+
+		{"POST /api/api-keys", s.apiKeyHandlers.CreateAPIKey, requires(domain.PermUsersManage)},
+		{"GET /api/api-keys", s.apiKeyHandlers.GetAPIKeys, requires(domain.PermUsersManage)},
+		{"GET /api/api-keys/{id}", s.apiKeyHandlers.GetAPIKeyByID, requires(domain.PermUsersManage)},
+		{"PATCH /api/api-keys/{id}", s.apiKeyHandlers.PatchAPIKeyByID, requires(domain.PermUsersManage)},
+		{"DELETE /api/api-keys/{id}", s.apiKeyHandlers.DeleteAPIKeyByID, requires(domain.PermUsersManage)},
+	}
+}
+
+// checkRoutes fails when a route requires no permission, or an unknown one,
+// so a route can't be left open by mistake
+func checkRoutes(routes []route) error {
+	for _, r := range routes {
+		if len(r.permissions) == 0 {
+			return fmt.Errorf("route `%s` requires no permission", r.pattern)
+		}
+		for _, p := range r.permissions {
+			if _, err := domain.ParsePermission(p.String()); err != nil {
+				return fmt.Errorf("route `%s`: %w", r.pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
 // This function wraps our APIFunc struct so we can handle errors gracefully
 func makeHTTPHandlerFunc(f APIFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+func Test_checkRoutes(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) error { return nil }
+
+	tests := []struct {
+		name    string
+		routes  []route
+		wantErr bool
+	}{
+		{
+			name:   "Every route requires permissions",
+			routes: []route{{"GET /api/hosts", handler, requires(domain.PermHostsRead)}},
+		},
+		{
+			name: "Route without permissions",
+			routes: []route{
+				{"GET /api/hosts", handler, requires(domain.PermHostsRead)},
+				{"GET /api/tags", handler, requires()},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Route with an unknown permission",
+			routes:  []route{{"GET /api/hosts", handler, requires("getHostByID")}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRoutes(tt.routes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -3,7 +3,11 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/keyring"
 )
 
 type Config struct {
@@ -21,6 +25,30 @@ type Config struct {
 	DatabasePort           string
 	NatsHost               string
 	NatsPort               string
+	ScanTimeoutMinutes     string
+	Notifier               string
+	NotificationsFile      string
+	SMTPHost               string
+	SMTPPort               string
+	SMTPUsername           string
+	SMTPPassword           string
+	SMTPFrom               string
+	NotificationAttempts   string
+	CredentialKeys         string
+	CredentialActiveKeyID  string
+	CredentialLegacyPass   string
+	HostImportLimit        string
+	HostRetentionDays      string
+	JWKSCacheTTLMinutes    string
+	JWKSCooldownSeconds    string
+	UserCacheSize          string
+	UserCacheTTLSeconds    string
+	UserCacheNegativeTTL   string
+	FusionAuthWebhookKey   string
+	PermissionsTTLSeconds  string
+	AuthCookieDomain       string
+	AuthCookieSecure       string
+	RefreshTokenTTLMinutes string
 }
 
 func fetchEnv(varString string, fallbackString string) string {
@@ -49,6 +77,30 @@ func LoadConfig() *Config {
 		DatabasePort:           fetchEnv("DB_PORT", "5432"),
 		NatsHost:               fetchEnv("NATS_HOST", "localhost"),
 		NatsPort:               fetchEnv("NATS_PORT", "4222"),
+		ScanTimeoutMinutes:     fetchEnv("SCAN_TIMEOUT_MINUTES", "60"),
+		Notifier:               fetchEnv("NOTIFIER", "log"),
+		NotificationsFile:      fetchEnv("NOTIFICATIONS_FILE", ""),
+		SMTPHost:               fetchEnv("SMTP_HOST", "localhost"),
+		SMTPPort:               fetchEnv("SMTP_PORT", "25"),
+		SMTPUsername:           fetchEnv("SMTP_USERNAME", ""),
+		SMTPPassword:           fetchEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:               fetchEnv("SMTP_FROM", "noreply@kriptome.com"),
+		NotificationAttempts:   fetchEnv("NOTIFICATION_MAX_ATTEMPTS", "3"),
+		CredentialKeys:         fetchEnv("CREDENTIAL_KEYS", ""),
+		CredentialActiveKeyID:  fetchEnv("CREDENTIAL_ACTIVE_KEY_ID", ""),
+		CredentialLegacyPass:   fetchEnv("CREDENTIAL_LEGACY_PASSPHRASE", ""),
+		HostImportLimit:        fetchEnv("HOST_IMPORT_LIMIT", "1024"),
+		HostRetentionDays:      fetchEnv("HOST_RETENTION_DAYS", "30"),
+		JWKSCacheTTLMinutes:    fetchEnv("JWKS_CACHE_TTL_MINUTES", "60"),
+		JWKSCooldownSeconds:    fetchEnv("JWKS_REFETCH_COOLDOWN_SECONDS", "30"),
+		UserCacheSize:          fetchEnv("USER_CACHE_SIZE", "10000"),
+		UserCacheTTLSeconds:    fetchEnv("USER_CACHE_TTL_SECONDS", "300"),
+		UserCacheNegativeTTL:   fetchEnv("USER_CACHE_NEGATIVE_TTL_SECONDS", "30"),
+		FusionAuthWebhookKey:   fetchEnv("FUSIONAUTH_WEBHOOK_SECRET", ""),
+		PermissionsTTLSeconds:  fetchEnv("PERMISSIONS_CACHE_TTL_SECONDS", "60"),
+		AuthCookieDomain:       fetchEnv("AUTH_COOKIE_DOMAIN", ""),
+		AuthCookieSecure:       fetchEnv("AUTH_COOKIE_SECURE", "true"),
+		RefreshTokenTTLMinutes: fetchEnv("REFRESH_TOKEN_TTL_MINUTES", "43200"),
 	}
 
 	return config
@@ -69,6 +121,11 @@ func (c *Config) PostgreSQLCoreConnStr() string {
 	)
 }
 
+// PostgreSQLAdminConnStr connects to the Core DB as `core_admin`, which bypasses row-level security
+func (c *Config) PostgreSQLAdminConnStr() string {
+	return fmt.Sprintf("%s options='-c role=core_admin'", c.PostgreSQLCoreConnStr())
+}
+
 func (c *Config) GetAllowedOrigins() []string {
 	return strings.Split(c.AllowedOrigins, ",")
 }
@@ -76,3 +133,130 @@ func (c *Config) GetAllowedOrigins() []string {
 func (c *Config) GetNatsConnStr() string {
 	return fmt.Sprintf("http://%s:%s", c.NatsHost, c.NatsPort)
 }
+
+// GetScanTimeout returns how long a scan may stay active before it times out
+func (c *Config) GetScanTimeout() time.Duration {
+	minutes, err := strconv.Atoi(c.ScanTimeoutMinutes)
+	if err != nil || minutes <= 0 {
+		minutes = 60
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// GetNotificationMaxAttempts returns how many times a notification is
+// sent before it's left as failed
+func (c *Config) GetNotificationMaxAttempts() int {
+	attempts, err := strconv.Atoi(c.NotificationAttempts)
+	if err != nil || attempts <= 0 {
+		attempts = 3
+	}
+	return attempts
+}
+
+// GetHostImportLimit returns the most hosts a single import may create,
+// CIDR blocks count as one host per address
+func (c *Config) GetHostImportLimit() int {
+	limit, err := strconv.Atoi(c.HostImportLimit)
+	if err != nil || limit <= 0 {
+		limit = 1024
+	}
+	return limit
+}
+
+// GetHostRetention returns how long deleted hosts can be restored before
+// they're purged
+func (c *Config) GetHostRetention() time.Duration {
+	days, err := strconv.Atoi(c.HostRetentionDays)
+	if err != nil || days <= 0 {
+		days = 30
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// GetJWKSURL returns the FusionAuth endpoint listing the public keys of every tenant
+func (c *Config) GetJWKSURL() string {
+	return fmt.Sprintf("http://%s:%s/.well-known/jwks.json", c.FusionAuthHost, c.FusionAuthPort)
+}
+
+// GetJWKSCacheTTL returns how long the public keys are cached before they're fetched again
+func (c *Config) GetJWKSCacheTTL() time.Duration {
+	minutes, err := strconv.Atoi(c.JWKSCacheTTLMinutes)
+	if err != nil || minutes <= 0 {
+		minutes = 60
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// GetJWKSRefetchCooldown returns how long to wait between fetches caused by
+// tokens with an unknown kid
+func (c *Config) GetJWKSRefetchCooldown() time.Duration {
+	seconds, err := strconv.Atoi(c.JWKSCooldownSeconds)
+	if err != nil || seconds < 0 {
+		seconds = 30
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// GetUserCacheSize returns the most users remembered by the user cache
+func (c *Config) GetUserCacheSize() int {
+	size, err := strconv.Atoi(c.UserCacheSize)
+	if err != nil || size <= 0 {
+		size = 10000
+	}
+	return size
+}
+
+// GetUserCacheTTL returns how long a user is known to exist before FusionAuth
+// is asked again, users are also dropped when FusionAuth reports them deleted
+func (c *Config) GetUserCacheTTL() time.Duration {
+	seconds, err := strconv.Atoi(c.UserCacheTTLSeconds)
+	if err != nil || seconds <= 0 {
+		seconds = 300
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// GetUserCacheNegativeTTL returns how long a missing or inactive user is remembered
+func (c *Config) GetUserCacheNegativeTTL() time.Duration {
+	seconds, err := strconv.Atoi(c.UserCacheNegativeTTL)
+	if err != nil || seconds < 0 {
+		seconds = 30
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// GetPermissionsCacheTTL returns how long the role permissions of a tenant are
+// cached, changes made through another instance show up after it
+func (c *Config) GetPermissionsCacheTTL() time.Duration {
+	seconds, err := strconv.Atoi(c.PermissionsTTLSeconds)
+	if err != nil || seconds < 0 {
+		seconds = 60
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// GetAuthCookieSecure tells if the token cookies are only sent over HTTPS,
+// it's meant to be turned off for local development only
+func (c *Config) GetAuthCookieSecure() bool {
+	secure, err := strconv.ParseBool(c.AuthCookieSecure)
+	if err != nil {
+		return true
+	}
+	return secure
+}
+
+// GetRefreshTokenTTL returns how long the refresh token cookie is kept, it
+// should match the refresh token TTL of the FusionAuth tenants
+func (c *Config) GetRefreshTokenTTL() time.Duration {
+	minutes, err := strconv.Atoi(c.RefreshTokenTTLMinutes)
+	if err != nil || minutes <= 0 {
+		minutes = 43200
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// GetCredentialKeyring loads the keys used to encrypt host credentials,
+// CREDENTIAL_KEYS is a `id:base64key,id:base64key` list of 32 byte keys
+func (c *Config) GetCredentialKeyring() (*keyring.Keyring, error) {
+	return keyring.Parse(c.CredentialKeys, c.CredentialActiveKeyID)
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every API key, it tells keys apart from JWTs in the
+// Authorization header
+const APIKeyPrefix = "kptm_"
+
+// apiKeyDisplayLength is how much of a key is kept so admins can recognize it
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// APIKey lets automation clients call the API on behalf of a tenant. Scopes are
+// the roles of the key, requests made with it have the permissions of those
+// roles. Only the hash of the key is stored, the key itself is shown once.
+type APIKey struct {
+	ID         string     `json:"id"`
+	TenantID   string     `json:"tenant_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`
+	Scopes     []Role     `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyPatch holds the fields of a key to update, nil fields are kept. ExpiresAt
+// is only updated when SetExpiresAt, a nil ExpiresAt then removes the expiry.
+type APIKeyPatch struct {
+	Name         *string
+	Scopes       *[]string
+	SetExpiresAt bool
+	ExpiresAt    *time.Time
+}
+
+// NewAPIKey generates a key for the tenant and returns it along with the key
+func NewAPIKey(tenantID string, name string, scopes []Role, createdBy string, expiresAt *time.Time) (*APIKey, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	return &APIKey{
+		ID:        uuid.NewString(),
+		TenantID:  tenantID,
+		Name:      name,
+		Prefix:    key[:apiKeyDisplayLength],
+		Hash:      HashAPIKey(key),
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now().UTC(),
+	}, key, nil
+}
+
+// HashAPIKey returns the hash keys are stored and looked up by. Keys are
+// random, so a fast hash is enough.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey tells if s has the format of an API key
+func IsAPIKey(s string) bool {
+	return strings.HasPrefix(s, APIKeyPrefix)
+}
+
+// Expired tells if the key can no longer be used at now, keys without
+// ExpiresAt never expire
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
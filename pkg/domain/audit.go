@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 500
+)
+
+type AuditAction string
+
+const (
+	AuditHostCreated      AuditAction = "host.created"
+	AuditHostImported     AuditAction = "host.imported"
+	AuditHostUpdated      AuditAction = "host.updated"
+	AuditHostDeleted      AuditAction = "host.deleted"
+	AuditHostRestored     AuditAction = "host.restored"
+	AuditHostTransferred  AuditAction = "host.transferred"
+	AuditHostTagsUpdated  AuditAction = "host.tags_updated"
+	AuditTagCreated       AuditAction = "tag.created"
+	AuditTagUpdated       AuditAction = "tag.updated"
+	AuditTagDeleted       AuditAction = "tag.deleted"
+	AuditGroupCreated     AuditAction = "group.created"
+	AuditGroupUpdated     AuditAction = "group.updated"
+	AuditGroupDeleted     AuditAction = "group.deleted"
+	AuditScanCreated      AuditAction = "scan.created"
+	AuditScanCancelled    AuditAction = "scan.cancelled"
+	AuditScheduleCreated  AuditAction = "schedule.created"
+	AuditScheduleUpdated  AuditAction = "schedule.updated"
+	AuditScheduleDeleted  AuditAction = "schedule.deleted"
+	AuditTemplateUpdated  AuditAction = "notification_template.updated"
+	AuditTemplateReset    AuditAction = "notification_template.reset"
+	AuditPermissionsSet   AuditAction = "role_permissions.updated"
+	AuditPermissionsReset AuditAction = "role_permissions.reset"
+	AuditAPIKeyCreated    AuditAction = "api_key.created"
+	AuditAPIKeyUpdated    AuditAction = "api_key.updated"
+	AuditAPIKeyDeleted    AuditAction = "api_key.deleted"
+	AuditUserRegistered   AuditAction = "user.registered"
+	AuditTenantRegistered AuditAction = "tenant.registered"
+)
+
+// Actor is who makes a change, RequestID ties the change to the request logs.
+// Changes made by the scheduler have no RequestID nor Roles. Requests made with
+// an API key act as the user who created it, with the scopes of the key as Roles.
+type Actor struct {
+	TenantID  string
+	UserID    string
+	APIKeyID  string
+	Roles     []Role
+	RequestID string
+}
+
+// AuditUserID is how the actor shows in the audit log, changes made with an
+// API key are attributed to the key
+func (a Actor) AuditUserID() string {
+	if a.APIKeyID != "" {
+		return "api_key:" + a.APIKeyID
+	}
+	return a.UserID
+}
+
+// AuditChange holds a field before and after a change, a nil Before means
+// the resource was created and a nil After that it was deleted
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditEvent records a change made to a resource of a tenant. Changes maps
+// each changed field to its values, secrets are redacted.
+type AuditEvent struct {
+	ID           string                 `json:"id"`
+	TenantID     string                 `json:"tenant_id"`
+	UserID       string                 `json:"user_id"`
+	Action       AuditAction            `json:"action"`
+	ResourceType string                 `json:"resource_type"`
+	ResourceID   string                 `json:"resource_id"`
+	Changes      map[string]AuditChange `json:"changes"`
+	RequestID    string                 `json:"request_id"`
+	CreatedAt    time.Time              `json:"created_at"`
+}
+
+// AuditFilter holds the criteria used to list the audit events of a tenant
+type AuditFilter struct {
+	TenantID     string
+	UserID       string
+	Action       AuditAction
+	ResourceType string
+	ResourceID   string
+	From         *time.Time
+	To           *time.Time
+	Cursor       string
+	Limit        int
+}
+
+// AuditPage is a single page of audit events, NextCursor is empty on the last page
+type AuditPage struct {
+	Events     []*AuditEvent `json:"events"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+func NewAuditEvent(actor Actor, action AuditAction, resourceType string, resourceID string, changes map[string]AuditChange) *AuditEvent {
+	return &AuditEvent{
+		ID:           uuid.NewString(),
+		TenantID:     actor.TenantID,
+		UserID:       actor.AuditUserID(),
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Changes:      changes,
+		RequestID:    actor.RequestID,
+		CreatedAt:    time.Now().UTC(),
+	}
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tag labels hosts of a tenant, e.g. `prod`. Tag names are unique per tenant.
+type Tag struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	Name      string    `json:"name"`
+	HostCount int       `json:"host_count"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HostGroup is a named set of hosts of a tenant, e.g. "production web servers"
+type HostGroup struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	HostIDs     []int     `json:"host_ids"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// HostSelector picks the hosts of a scan, a host matching more than one
+// criteria is only scanned once
+type HostSelector struct {
+	HostIDs  []int
+	GroupIDs []string
+	Tags     []string
+}
+
+func NewTag(tenantID string, name string) *Tag {
+	return &Tag{
+		ID:        uuid.NewString(),
+		TenantID:  tenantID,
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func NewHostGroup(tenantID string, name string, description string, hostIDs []int) *HostGroup {
+	return &HostGroup{
+		ID:          uuid.NewString(),
+		TenantID:    tenantID,
+		Name:        name,
+		Description: description,
+		HostIDs:     hostIDs,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	}
+}
@@ -1,16 +1,89 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
 	"time"
 )
 
+const (
+	DefaultHostPageSize = 50
+	MaxHostPageSize     = 200
+)
+
+// HostSort is the column a list of hosts is ordered by
+type HostSort string
+
+const (
+	HostSortName      HostSort = "name"
+	HostSortCreatedAt HostSort = "created_at"
+	HostSortUpdatedAt HostSort = "updated_at"
+)
+
+func ParseHostSort(s string) (HostSort, error) {
+	var stringToHostSort = map[string]HostSort{
+		"name":       HostSortName,
+		"created_at": HostSortCreatedAt,
+		"updated_at": HostSortUpdatedAt,
+	}
+
+	v, ok := stringToHostSort[s]
+	if !ok {
+		return "", fmt.Errorf("invalid sort: `%s`", s)
+	}
+
+	return v, nil
+}
+
+// HostFilter holds the criteria used to list the hosts of a tenant. Hosts
+// must have every tag of Tags, Domain matches a substring and IP an address
+// or a CIDR block. Hosts are sorted by name when Sort is empty. Every
+// operator of the tenant is listed when OperatorID is empty. Deleted lists
+// the soft deleted hosts instead of the live ones.
+type HostFilter struct {
+	TenantID        string
+	OperatorID      string
+	Owner           string
+	Tags            []string
+	Domain          string
+	IP              string
+	RapporteurEmail string
+	Sort            HostSort
+	Descending      bool
+	Deleted         bool
+	Cursor          string
+	Limit           int
+}
+
+// HostPage is a single page of hosts, Total counts every host matching the
+// filter and NextCursor is empty on the last page
+type HostPage struct {
+	Hosts      []*Host
+	Total      int
+	NextCursor string
+}
+
+// HostPageResponse is the body of a page of hosts
+type HostPageResponse struct {
+	Hosts      []*HostResponse `json:"hosts"`
+	Total      int             `json:"total"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// Credential passwords are only set when coming from a client, or sealed
+// by the service layer on their way to storage. They are never read back.
 type Credential struct {
 	ID       int    `json:"id,omitempty"`
 	HostID   string `json:"host_id,omitempty"`
 	Username string `json:"username"`
-	Password string `json:"password"`
+	Password string `json:"password,omitempty"`
+	KeyID    string `json:"-"`
 }
 
+// ErrMissingPassword is returned when a credential that isn't stored yet
+// comes without a password, only stored credentials can keep theirs
+var ErrMissingPassword = errors.New("credential has no password")
+
 type Rapporteur struct {
 	Name        string `json:"name"`
 	Email       string `json:"email"`
@@ -26,8 +99,10 @@ type Host struct {
 	IP          string       `json:"ip"`
 	Credentials []Credential `json:"credentials"`
 	Rapporteurs []Rapporteur `json:"rapporteurs"`
+	Tags        []string     `json:"tags"`
 	CreatedAt   time.Time    `json:"created_at"`
 	UpdatedAt   time.Time    `json:"updated_at"`
+	DeletedAt   *time.Time   `json:"deleted_at,omitempty"`
 }
 
 type HostResponse struct {
@@ -37,8 +112,10 @@ type HostResponse struct {
 	IP          string       `json:"ip"`
 	Credentials []Credential `json:"credentials"`
 	Rapporteurs []Rapporteur `json:"rapporteurs"`
+	Tags        []string     `json:"tags"`
 	CreatedAt   time.Time    `json:"created_at"`
 	UpdatedAt   time.Time    `json:"updated_at"`
+	DeletedAt   *time.Time   `json:"deleted_at,omitempty"`
 }
 
 func NewHost(domain string, ip string, tenantID string, operatorID string, name string, credentials []Credential, rappporteurs []Rapporteur) *Host {
@@ -54,3 +131,38 @@ func NewHost(domain string, ip string, tenantID string, operatorID string, name
 		UpdatedAt:   time.Now().UTC(),
 	}
 }
+
+type HostImportStatus string
+
+const (
+	HostImportCreated HostImportStatus = "created"
+	HostImportSkipped HostImportStatus = "skipped"
+	HostImportInvalid HostImportStatus = "invalid"
+)
+
+// HostImportRow is an entry of a bulk import, Value is a domain, an IP or a CIDR block
+type HostImportRow struct {
+	Row         int          `json:"-"`
+	Value       string       `json:"value"`
+	Name        string       `json:"name"`
+	Credentials []Credential `json:"credentials"`
+	Rapporteurs []Rapporteur `json:"rapporteurs"`
+}
+
+// HostImportResult is the outcome of a host of an import, rows with a CIDR
+// block have a result for each address of the block
+type HostImportResult struct {
+	Row    int              `json:"row"`
+	Value  string           `json:"value"`
+	Alias  string           `json:"alias,omitempty"`
+	Status HostImportStatus `json:"status"`
+	Reason string           `json:"reason,omitempty"`
+	HostID int              `json:"host_id,omitempty"`
+}
+
+type HostImportReport struct {
+	Created int                `json:"created"`
+	Skipped int                `json:"skipped"`
+	Invalid int                `json:"invalid"`
+	Results []HostImportResult `json:"results"`
+}
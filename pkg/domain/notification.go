@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type NotificationStatus string
+
+const (
+	NotificationStatusPending NotificationStatus = "pending"
+	NotificationStatusSent    NotificationStatus = "sent"
+	NotificationStatusFailed  NotificationStatus = "failed"
+)
+
+// NotificationMessage is what a notifier delivers
+type NotificationMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notification records a message sent to a rapporteur about a scan
+type Notification struct {
+	ID        string             `json:"id"`
+	TenantID  string             `json:"tenant_id"`
+	ScanID    string             `json:"scan_id"`
+	HostAlias string             `json:"host"`
+	Recipient string             `json:"recipient"`
+	Subject   string             `json:"subject"`
+	Status    NotificationStatus `json:"status"`
+	Error     string             `json:"error,omitempty"`
+	Attempts  int                `json:"attempts"`
+	SentAt    *time.Time         `json:"sent_at,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// NotificationTemplate holds the text/template sources a tenant uses for scan notifications
+type NotificationTemplate struct {
+	TenantID  string    `json:"tenant_id"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	IsDefault bool      `json:"is_default"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewNotification(tenantID string, scanID string, hostAlias string, recipient string, subject string) *Notification {
+	return &Notification{
+		ID:        uuid.New().String(),
+		TenantID:  tenantID,
+		ScanID:    scanID,
+		HostAlias: hostAlias,
+		Recipient: recipient,
+		Subject:   subject,
+		Status:    NotificationStatusPending,
+		Attempts:  1,
+		CreatedAt: time.Now().UTC(),
+	}
+}
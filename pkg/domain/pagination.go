@@ -0,0 +1,6 @@
+package domain
+
+import "errors"
+
+// ErrInvalidCursor is returned when a pagination cursor can't be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
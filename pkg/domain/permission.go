@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+type Permission string
+
+const (
+	PermTenantsRead        Permission = "tenants:read"
+	PermUsersRead          Permission = "users:read"
+	PermUsersManage        Permission = "users:manage"
+	PermHostsRead          Permission = "hosts:read"
+	PermHostsCreate        Permission = "hosts:create"
+	PermHostsWrite         Permission = "hosts:write"
+	PermHostsManage        Permission = "hosts:manage"
+	PermTagsRead           Permission = "tags:read"
+	PermTagsWrite          Permission = "tags:write"
+	PermGroupsRead         Permission = "groups:read"
+	PermGroupsWrite        Permission = "groups:write"
+	PermScansRead          Permission = "scans:read"
+	PermScansCreate        Permission = "scans:create"
+	PermScansCancel        Permission = "scans:cancel"
+	PermSchedulesRead      Permission = "schedules:read"
+	PermSchedulesWrite     Permission = "schedules:write"
+	PermNotificationsRead  Permission = "notifications:read"
+	PermNotificationsWrite Permission = "notifications:write"
+	PermAuditRead          Permission = "audit:read"
+)
+
+// AllPermissions lists every permission a route can require
+var AllPermissions = []Permission{
+	PermTenantsRead, PermUsersRead, PermUsersManage,
+	PermHostsRead, PermHostsCreate, PermHostsWrite, PermHostsManage,
+	PermTagsRead, PermTagsWrite, PermGroupsRead, PermGroupsWrite,
+	PermScansRead, PermScansCreate, PermScansCancel,
+	PermSchedulesRead, PermSchedulesWrite,
+	PermNotificationsRead, PermNotificationsWrite,
+	PermAuditRead,
+}
+
+// Roles lists every role permissions can be bound to
+var Roles = []Role{RoleAdmin, RoleOperator, RoleAnalyst}
+
+// DefaultRolePermissions are the permissions of each role in tenants that
+// don't override them
+var DefaultRolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermTenantsRead, PermUsersRead, PermUsersManage,
+		PermHostsRead, PermHostsWrite, PermHostsManage,
+		PermTagsRead, PermTagsWrite, PermGroupsRead, PermGroupsWrite,
+		PermScansRead, PermScansCancel,
+		PermSchedulesRead, PermSchedulesWrite,
+		PermNotificationsRead, PermNotificationsWrite,
+		PermAuditRead,
+	},
+	RoleOperator: {
+		PermUsersRead,
+		PermHostsRead, PermHostsCreate, PermHostsWrite,
+		PermTagsRead, PermTagsWrite, PermGroupsRead, PermGroupsWrite,
+		PermScansRead, PermScansCreate, PermScansCancel,
+		PermSchedulesRead, PermSchedulesWrite,
+		PermNotificationsRead,
+	},
+	RoleAnalyst: {
+		PermTenantsRead, PermUsersRead,
+		PermHostsRead, PermHostsCreate,
+		PermTagsRead, PermGroupsRead,
+		PermScansRead,
+		PermSchedulesRead,
+	},
+}
+
+func (p Permission) String() string {
+	return string(p)
+}
+
+func ParsePermission(s string) (Permission, error) {
+	p := Permission(s)
+	if !slices.Contains(AllPermissions, p) {
+		return "", fmt.Errorf("invalid permission: `%s`", s)
+	}
+	return p, nil
+}
+
+// RolePermissions binds a role to its permissions in a tenant, Overridden is
+// false when the role has the default permissions
+type RolePermissions struct {
+	TenantID    string       `json:"tenant_id"`
+	Role        Role         `json:"role"`
+	Permissions []Permission `json:"permissions"`
+	Overridden  bool         `json:"overridden"`
+	UpdatedAt   *time.Time   `json:"updated_at,omitempty"`
+}
+
+// GrantedPermissions returns the permissions of all the roles, bindings
+// replaces the default permissions of the roles it has
+func GrantedPermissions(roles []Role, bindings map[Role][]Permission) map[Permission]bool {
+	granted := map[Permission]bool{}
+	for _, role := range roles {
+		permissions, ok := bindings[role]
+		if !ok {
+			permissions = DefaultRolePermissions[role]
+		}
+		for _, p := range permissions {
+			granted[p] = true
+		}
+	}
+	return granted
+}
+
+// MissingPermissions returns the required permissions the roles don't have
+func MissingPermissions(roles []Role, bindings map[Role][]Permission, required []Permission) []Permission {
+	granted := GrantedPermissions(roles, bindings)
+	missing := []Permission{}
+	for _, p := range required {
+		if !granted[p] {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/kptm-tools/common/common/enums"
+)
+
+// ScanReport is the client facing view of a scan. It's built from hosts
+// field by field so credentials are never part of a report.
+type ScanReport struct {
+	ScanID      string       `json:"scan_id"`
+	Status      ScanStatus   `json:"status"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	GeneratedAt time.Time    `json:"generated_at"`
+	Hosts       []HostReport `json:"hosts"`
+}
+
+type HostReport struct {
+	Alias       string          `json:"alias"`
+	Domain      string          `json:"domain,omitempty"`
+	IP          string          `json:"ip,omitempty"`
+	Rapporteurs []Rapporteur    `json:"rapporteurs,omitempty"`
+	Services    []ServiceReport `json:"services"`
+}
+
+type ServiceReport struct {
+	Service  enums.ServiceName `json:"service"`
+	Progress string            `json:"progress"`
+	Error    string            `json:"error,omitempty"`
+	Results  json.RawMessage   `json:"results,omitempty"`
+}
+
+// NewHostReport copies the reportable fields of a host, host may be nil if it was deleted
+func NewHostReport(alias string, host *Host) HostReport {
+	hr := HostReport{Alias: alias, Services: []ServiceReport{}}
+	if host != nil {
+		hr.Domain = host.Domain
+		hr.IP = host.IP
+		hr.Rapporteurs = host.Rapporteurs
+	}
+	return hr
+}
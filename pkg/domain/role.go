@@ -45,32 +45,6 @@ func GetRolesFromStringSlice(strSlice []string) ([]Role, error) {
 	return res, nil
 }
 
-func GetValidRoles(funcName string) ([]Role, error) {
-
-	funcRoles := map[string][]Role{
-		"handleHealthcheck-fm":    {RoleAdmin},
-		"targets":                 {RoleAdmin, RoleOperator, RoleAnalyst},
-		"tenants":                 {RoleAdmin, RoleAnalyst},
-		"getUser":                 {RoleAdmin, RoleOperator, RoleAnalyst},
-		"newHost":                 {RoleOperator, RoleAnalyst},
-		"getHostsByTenantAndUser": {RoleAdmin, RoleOperator, RoleAnalyst},
-		"getHostByID":             {RoleAdmin, RoleOperator, RoleAnalyst},
-		"deleteHostByID":          {RoleAdmin, RoleOperator},
-		"patchHostByID":           {RoleAdmin, RoleOperator},
-		"validateHost":            {RoleOperator, RoleAnalyst},
-		"createScans":             {RoleOperator},
-	}
-
-	v, ok := funcRoles[funcName]
-
-	if !ok {
-		return nil, fmt.Errorf("`%s` has no defined roles", funcName)
-	}
-
-	return v, nil
-
-}
-
 // ContainsRole finds the intersection of two arrays
 // of type Role, returns an array with the intersection
 func ContainsRole(roles []Role, rolesToCheck []Role) []Role {
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,9 +9,15 @@ import (
 	events "github.com/kptm-tools/common/common/events"
 )
 
+const (
+	DefaultScanPageSize = 20
+	MaxScanPageSize     = 100
+)
+
 type Metadata struct {
 	Progress string            `json:"progress"`
 	Service  enums.ServiceName `json:"service"`
+	Error    string            `json:"error,omitempty"`
 }
 
 type StatusHost struct {
@@ -19,20 +26,123 @@ type StatusHost struct {
 }
 
 type ResultHost struct {
-	Host string `json:"id,omitempty"`
+	Host    string                                `json:"id,omitempty"`
+	Results map[enums.ServiceName]json.RawMessage `json:"results,omitempty"`
+}
+
+// ServiceProgressEvent is published by the scanning services while they work
+// on a scan. An empty Target means the progress applies to every target.
+type ServiceProgressEvent struct {
+	events.BaseEvent
+	Service  enums.ServiceName `json:"service"`
+	Target   string            `json:"target,omitempty"`
+	Progress string            `json:"progress"`
 }
+
+// Progress subjects aren't part of kptm-tools/common, this is their contract.
+// A scanning service publishes a [ServiceProgressEvent] as JSON on the subject
+// of its results followed by `.progress`, and keeps publishing its results on
+// the common subject. Progress is optional, services that don't publish it only
+// move from pending to completed.
+const (
+	WhoIsProgressSubject     = "event.whois.progress"
+	DNSLookupProgressSubject = "event.dnslookup.progress"
+	HarvesterProgressSubject = "event.harvester.progress"
+	NmapProgressSubject      = "event.nmap.progress"
+)
+
 type Scan struct {
 	ID           string          `json:"id,omitempty"`
+	TenantID     string          `json:"tenant_id,omitempty"`
+	OperatorID   string          `json:"user_id,omitempty"`
+	Status       ScanStatus      `json:"status"`
 	HostsStatus  []StatusHost    `json:"hosts_status,omitempty"`
 	HostsResults []ResultHost    `json:"hosts_results,omitempty"`
 	Targets      []events.Target `json:"targets,omitempty"`
+	Tools        []ScanTool      `json:"tools,omitempty"`
 	CreatedAt    time.Time       `json:"created_at"`
 	UpdatedAt    time.Time       `json:"updated_at"`
 }
 
+// ScanFilter holds the criteria used to list the scans of a tenant
+type ScanFilter struct {
+	TenantID  string
+	HostID    int
+	HostAlias string
+	Status    ScanStatus
+	From      *time.Time
+	To        *time.Time
+	Cursor    string
+	Limit     int
+}
+
+// ScanPage is a single page of scans, NextCursor is empty on the last page
+type ScanPage struct {
+	Scans      []*Scan `json:"scans"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// ScanUpdatedEventSubject is published by core-service every time the status of a scan changes
+const ScanUpdatedEventSubject = "event.scanupdated"
+
+// ScanUpdatedEvent carries the full status of a scan after a change,
+// so listeners never need to replay intermediate events
+type ScanUpdatedEvent struct {
+	ScanID      string       `json:"scan_id"`
+	TenantID    string       `json:"tenant_id"`
+	Status      ScanStatus   `json:"status"`
+	HostsStatus []StatusHost `json:"hosts_status,omitempty"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// EventID identifies the state carried by the event, later states have greater IDs
+func (e *ScanUpdatedEvent) EventID() int64 {
+	return e.UpdatedAt.UnixMicro()
+}
+
+const (
+	ProgressPending   = "0%"
+	ProgressCompleted = "100%"
+)
+
+// IsFinished reports whether every service finished on every host
+func (s *Scan) IsFinished() bool {
+	for _, hs := range s.HostsStatus {
+		for _, m := range hs.Metadata {
+			if m.Progress != ProgressCompleted {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// HasFailures reports whether any service reported an error
+func (s *Scan) HasFailures() bool {
+	for _, hs := range s.HostsStatus {
+		for _, m := range hs.Metadata {
+			if m.Error != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HostAlias returns the alias of the target with the given value
+func (s *Scan) HostAlias(targetValue string) (string, bool) {
+	for _, t := range s.Targets {
+		if t.Value == targetValue || t.Alias == targetValue {
+			return t.Alias, true
+		}
+	}
+	return "", false
+}
+
 func NewScan() *Scan {
 	return &Scan{
 		ID:        uuid.NewString(),
+		Status:    ScanStatusPending,
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
 	}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"github.com/kptm-tools/common/common/results"
+)
+
+// ScanDiff is the change in attack surface between two scans, per host
+type ScanDiff struct {
+	BaseScanID        string     `json:"base_scan_id"`
+	TargetScanID      string     `json:"target_scan_id"`
+	Hosts             []HostDiff `json:"hosts"`
+	HostsOnlyInBase   []string   `json:"hosts_only_in_base"`
+	HostsOnlyInTarget []string   `json:"hosts_only_in_target"`
+}
+
+// HostDiff lists what changed on a host between two scans. A nil section
+// means the tool didn't produce results in one of the scans.
+type HostDiff struct {
+	Host          string        `json:"host"`
+	NewPorts      []PortDiff    `json:"new_ports,omitempty"`
+	ClosedPorts   []PortDiff    `json:"closed_ports,omitempty"`
+	DNS           *DNSDiff      `json:"dns,omitempty"`
+	NewEmails     []string      `json:"new_emails,omitempty"`
+	NewSubdomains []string      `json:"new_subdomains,omitempty"`
+	WhoIs         []FieldChange `json:"whois,omitempty"`
+}
+
+type PortDiff struct {
+	ID       uint16 `json:"id"`
+	Protocol string `json:"protocol"`
+	Service  string `json:"service,omitempty"`
+}
+
+type DNSDiff struct {
+	Added   []results.DNSRecord `json:"added,omitempty"`
+	Removed []results.DNSRecord `json:"removed,omitempty"`
+}
+
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// HasChanges reports whether anything changed on the host
+func (d *HostDiff) HasChanges() bool {
+	return len(d.NewPorts) > 0 || len(d.ClosedPorts) > 0 ||
+		(d.DNS != nil && (len(d.DNS.Added) > 0 || len(d.DNS.Removed) > 0)) ||
+		len(d.NewEmails) > 0 || len(d.NewSubdomains) > 0 || len(d.WhoIs) > 0
+}
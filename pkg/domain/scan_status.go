@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidScanTransition = errors.New("invalid scan status transition")
+
+// ScanStatus is the overall lifecycle status of a scan
+type ScanStatus string
+
+const (
+	ScanStatusPending         ScanStatus = "pending"
+	ScanStatusRunning         ScanStatus = "running"
+	ScanStatusPartiallyFailed ScanStatus = "partially_failed"
+	ScanStatusCompleted       ScanStatus = "completed"
+	ScanStatusCancelled       ScanStatus = "cancelled"
+	ScanStatusTimedOut        ScanStatus = "timed_out"
+)
+
+// scanTransitions lists the statuses each status can move to.
+// Terminal statuses have no outgoing transitions.
+var scanTransitions = map[ScanStatus][]ScanStatus{
+	ScanStatusPending: {ScanStatusRunning, ScanStatusCancelled, ScanStatusTimedOut},
+	ScanStatusRunning: {ScanStatusCompleted, ScanStatusPartiallyFailed, ScanStatusCancelled, ScanStatusTimedOut},
+}
+
+func (s ScanStatus) String() string {
+	return string(s)
+}
+
+func ParseScanStatus(s string) (ScanStatus, error) {
+	var stringToScanStatus = map[string]ScanStatus{
+		"pending":          ScanStatusPending,
+		"running":          ScanStatusRunning,
+		"partially_failed": ScanStatusPartiallyFailed,
+		"completed":        ScanStatusCompleted,
+		"cancelled":        ScanStatusCancelled,
+		"timed_out":        ScanStatusTimedOut,
+	}
+
+	v, ok := stringToScanStatus[s]
+	if !ok {
+		return "", fmt.Errorf("invalid scan status: `%s`", s)
+	}
+
+	return v, nil
+}
+
+// IsTerminal reports whether the scan has finished and can no longer change
+func (s ScanStatus) IsTerminal() bool {
+	return len(scanTransitions[s]) == 0
+}
+
+// CanTransitionTo reports whether a scan in status s may move to next
+func (s ScanStatus) CanTransitionTo(next ScanStatus) bool {
+	for _, allowed := range scanTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionTo moves the scan to the next status,
+// returns [ErrInvalidScanTransition] if the lifecycle doesn't allow it
+func (sc *Scan) TransitionTo(next ScanStatus) error {
+	if !sc.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%q: %w", fmt.Sprintf("%s -> %s", sc.Status, next), ErrInvalidScanTransition)
+	}
+	sc.Status = next
+	return nil
+}
+
+// ActiveScanStatuses are the statuses of scans that haven't finished yet
+func ActiveScanStatuses() []ScanStatus {
+	return []ScanStatus{ScanStatusPending, ScanStatusRunning}
+}
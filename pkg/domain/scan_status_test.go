@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_ScanTransitionTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    ScanStatus
+		to      ScanStatus
+		wantErr error
+	}{
+		{
+			name:    "Pending scan starts running",
+			from:    ScanStatusPending,
+			to:      ScanStatusRunning,
+			wantErr: nil,
+		},
+		{
+			name:    "Pending scan is cancelled",
+			from:    ScanStatusPending,
+			to:      ScanStatusCancelled,
+			wantErr: nil,
+		},
+		{
+			name:    "Running scan completes",
+			from:    ScanStatusRunning,
+			to:      ScanStatusCompleted,
+			wantErr: nil,
+		},
+		{
+			name:    "Running scan partially fails",
+			from:    ScanStatusRunning,
+			to:      ScanStatusPartiallyFailed,
+			wantErr: nil,
+		},
+		{
+			name:    "Running scan times out",
+			from:    ScanStatusRunning,
+			to:      ScanStatusTimedOut,
+			wantErr: nil,
+		},
+		{
+			name:    "Pending scan can't complete without running",
+			from:    ScanStatusPending,
+			to:      ScanStatusCompleted,
+			wantErr: ErrInvalidScanTransition,
+		},
+		{
+			name:    "Completed scan can't be cancelled",
+			from:    ScanStatusCompleted,
+			to:      ScanStatusCancelled,
+			wantErr: ErrInvalidScanTransition,
+		},
+		{
+			name:    "Cancelled scan can't be cancelled again",
+			from:    ScanStatusCancelled,
+			to:      ScanStatusCancelled,
+			wantErr: ErrInvalidScanTransition,
+		},
+		{
+			name:    "Timed out scan can't start running",
+			from:    ScanStatusTimedOut,
+			to:      ScanStatusRunning,
+			wantErr: ErrInvalidScanTransition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scan := &Scan{Status: tt.from}
+
+			err := scan.TransitionTo(tt.to)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error `%v`, got `%v`", tt.wantErr, err)
+			}
+			if err == nil && scan.Status != tt.to {
+				t.Errorf("Expected status `%s`, got `%s`", tt.to, scan.Status)
+			}
+			if err != nil && scan.Status != tt.from {
+				t.Errorf("Expected status to remain `%s`, got `%s`", tt.from, scan.Status)
+			}
+		})
+	}
+}
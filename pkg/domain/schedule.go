@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ScanSchedule runs a scan on a set of hosts either on a cron
+// expression or every IntervalMinutes, only one of them is set.
+// Its scans run Tools, or every tool when none are set.
+type ScanSchedule struct {
+	ID              string     `json:"id"`
+	TenantID        string     `json:"tenant_id"`
+	OperatorID      string     `json:"user_id"`
+	Name            string     `json:"name"`
+	HostIDs         []int      `json:"host_ids"`
+	CronExpression  string     `json:"cron_expression,omitempty"`
+	IntervalMinutes int        `json:"interval_minutes,omitempty"`
+	Tools           []ScanTool `json:"tools,omitempty"`
+	Enabled         bool       `json:"enabled"`
+	NextRunAt       *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt       *time.Time `json:"last_run_at,omitempty"`
+	LastScanID      string     `json:"last_scan_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// ScanSchedulePatch holds the fields of a schedule to update, nil fields are
+// kept. Setting a cron expression drops the interval and the other way around.
+type ScanSchedulePatch struct {
+	Name            *string
+	HostIDs         *[]int
+	CronExpression  *string
+	IntervalMinutes *int
+	Tools           *[]ScanTool
+	Enabled         *bool
+}
+
+// Apply returns a copy of the schedule with the fields of the patch
+func (p ScanSchedulePatch) Apply(sc ScanSchedule) *ScanSchedule {
+	if p.Name != nil {
+		sc.Name = *p.Name
+	}
+	if p.HostIDs != nil {
+		sc.HostIDs = *p.HostIDs
+	}
+	if p.CronExpression != nil {
+		sc.CronExpression = *p.CronExpression
+		if p.IntervalMinutes == nil && sc.CronExpression != "" {
+			sc.IntervalMinutes = 0
+		}
+	}
+	if p.IntervalMinutes != nil {
+		sc.IntervalMinutes = *p.IntervalMinutes
+		if p.CronExpression == nil && sc.IntervalMinutes != 0 {
+			sc.CronExpression = ""
+		}
+	}
+	if p.Tools != nil {
+		sc.Tools = *p.Tools
+	}
+	if p.Enabled != nil {
+		sc.Enabled = *p.Enabled
+	}
+	return &sc
+}
+
+func NewScanSchedule(tenantID, operatorID, name string, hostIDs []int, cronExpression string, intervalMinutes int, tools []ScanTool, enabled bool) *ScanSchedule {
+	return &ScanSchedule{
+		ID:              uuid.NewString(),
+		TenantID:        tenantID,
+		OperatorID:      operatorID,
+		Name:            name,
+		HostIDs:         hostIDs,
+		CronExpression:  cronExpression,
+		IntervalMinutes: intervalMinutes,
+		Tools:           tools,
+		Enabled:         enabled,
+		CreatedAt:       time.Now().UTC(),
+		UpdatedAt:       time.Now().UTC(),
+	}
+}
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"github.com/kptm-tools/common/common/enums"
+	events "github.com/kptm-tools/common/common/events"
+)
+
+// ScanTool is a scanning service selected for a scan along with
+// the options it should run with
+type ScanTool struct {
+	Service enums.ServiceName      `json:"service"`
+	Options map[string]interface{} `json:"options,omitempty"`
+}
+
+// ScanStartedEvent extends the common started event with the tools the
+// scanning services should run. Services that don't know about tools
+// can still decode it as a [events.ScanStartedEvent].
+type ScanStartedEvent struct {
+	events.ScanStartedEvent
+	Tools []ScanTool `json:"tools"`
+}
@@ -10,6 +10,7 @@ type User struct {
 	Password      string           `json:"password"`
 	ApplicationID string           `json:"application_id"`
 	Roles         []string         `json:"roles"`
+	Active        bool             `json:"active"`
 	User          UserPersonalInfo `json:"user"`
 }
 
@@ -20,6 +21,7 @@ func NewUser(id, email, password, tenantID, appID string, roles []string, name s
 		Password:      password,
 		ApplicationID: appID,
 		Roles:         roles,
+		Active:        true,
 		User: UserPersonalInfo{
 			Name:     name,
 			Lastname: lastname,
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/kptm-tools/core-service/pkg/api"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+type APIKeyHandlers struct {
+	apiKeyService interfaces.IAPIKeyService
+}
+
+var _ interfaces.IAPIKeyHandlers = (*APIKeyHandlers)(nil)
+
+func NewAPIKeyHandlers(apiKeyService interfaces.IAPIKeyService) *APIKeyHandlers {
+	return &APIKeyHandlers{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKey responds with the new key, it's the only time the key is shown
+func (h *APIKeyHandlers) CreateAPIKey(w http.ResponseWriter, req *http.Request) error {
+	keyRequest := new(APIKeyRequest)
+
+	if err := decodeJSONBody(w, req, keyRequest); err != nil {
+		var mr *malformedRequest
+
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	key, secret, err := h.apiKeyService.CreateAPIKey(getActor(req), tenantID, keyRequest.Name, keyRequest.Scopes, keyRequest.ExpiresAt)
+	if err != nil {
+		return writeAPIKeyError(w, err)
+	}
+
+	return api.WriteJSON(w, http.StatusCreated, CreateAPIKeyResponse{APIKey: key, Key: secret})
+}
+
+func (h *APIKeyHandlers) GetAPIKeys(w http.ResponseWriter, req *http.Request) error {
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	keys, err := h.apiKeyService.GetAPIKeysByTenantID(tenantID)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandlers) GetAPIKeyByID(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	key, err := h.apiKeyService.GetAPIKeyByID(id, tenantID)
+	if err != nil {
+		return writeAPIKeyError(w, err)
+	}
+
+	return api.WriteJSON(w, http.StatusOK, key)
+}
+
+func (h *APIKeyHandlers) PatchAPIKeyByID(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	keyRequest := new(PatchAPIKeyRequest)
+
+	if err := decodeJSONBody(w, req, keyRequest); err != nil {
+		var mr *malformedRequest
+
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+	patch := domain.APIKeyPatch{
+		Name:         keyRequest.Name,
+		Scopes:       keyRequest.Scopes,
+		SetExpiresAt: keyRequest.ExpiresAt.Set,
+		ExpiresAt:    keyRequest.ExpiresAt.Time,
+	}
+
+	key, err := h.apiKeyService.PatchAPIKeyByID(getActor(req), id, tenantID, patch)
+	if err != nil {
+		return writeAPIKeyError(w, err)
+	}
+
+	return api.WriteJSON(w, http.StatusOK, key)
+}
+
+func (h *APIKeyHandlers) DeleteAPIKeyByID(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	isDeleted, err := h.apiKeyService.DeleteAPIKeyByID(getActor(req), id, tenantID)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+	if !isDeleted {
+		statusCode := http.StatusNotFound
+		return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, map[string]string{"deleted": "true"})
+}
+
+// writeAPIKeyError maps the errors of the api key service to a response
+func writeAPIKeyError(w http.ResponseWriter, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidAPIKey):
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	case errors.Is(err, sql.ErrNoRows):
+		statusCode := http.StatusNotFound
+		return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+	default:
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_PatchAPIKeyRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantSet       bool
+		wantExpiresAt bool
+	}{
+		{name: "Missing expires_at", body: `{"name":"ci"}`},
+		{name: "Null expires_at", body: `{"expires_at":null}`, wantSet: true},
+		{name: "New expires_at", body: `{"expires_at":"2030-01-01T00:00:00Z"}`, wantSet: true, wantExpiresAt: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := new(PatchAPIKeyRequest)
+			if err := json.Unmarshal([]byte(tt.body), r); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if r.ExpiresAt.Set != tt.wantSet || (r.ExpiresAt.Time != nil) != tt.wantExpiresAt {
+				t.Errorf("Expected set `%v` with time `%v`, got %+v", tt.wantSet, tt.wantExpiresAt, r.ExpiresAt)
+			}
+		})
+	}
+}
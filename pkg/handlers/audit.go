@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/api"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+var auditCSVHeader = []string{"created_at", "tenant_id", "user_id", "action", "resource_type", "resource_id", "request_id", "changes"}
+
+type AuditHandlers struct {
+	auditService interfaces.IAuditService
+}
+
+var _ interfaces.IAuditHandlers = (*AuditHandlers)(nil)
+
+func NewAuditHandlers(auditService interfaces.IAuditService) *AuditHandlers {
+	return &AuditHandlers{
+		auditService: auditService,
+	}
+}
+
+// GetAuditEvents lists the audit events of the tenant as JSON pages, or
+// exports all of them as CSV with `format=csv`
+func (h *AuditHandlers) GetAuditEvents(w http.ResponseWriter, req *http.Request) error {
+	filter, err := getAuditFilter(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	filter.TenantID = req.Context().Value(middleware.ContextTenantID).(string)
+
+	switch format := req.URL.Query().Get("format"); format {
+	case "", "json":
+	case "csv":
+		return h.exportAuditEvents(w, *filter)
+	default:
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: fmt.Sprintf("invalid format: `%s`", format)})
+	}
+
+	page, err := h.auditService.GetAuditEvents(*filter)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) || errors.Is(err, services.ErrInvalidDateRange) {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, page)
+}
+
+// exportAuditEvents streams the events page by page. Errors are only reported
+// as JSON while nothing has been written yet.
+func (h *AuditHandlers) exportAuditEvents(w http.ResponseWriter, filter domain.AuditFilter) error {
+	cw := csv.NewWriter(w)
+	started := false
+
+	err := h.auditService.ExportAuditEvents(filter, func(events []*domain.AuditEvent) error {
+		if !started {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", "attachment; filename=\"audit.csv\"")
+			w.WriteHeader(http.StatusOK)
+			if err := cw.Write(auditCSVHeader); err != nil {
+				return err
+			}
+			started = true
+		}
+
+		for _, e := range events {
+			record, err := auditCSVRecord(e)
+			if err != nil {
+				return err
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+	if err != nil {
+		if started {
+			log.Printf("Error exporting audit events of tenant `%s`: `%+v`", filter.TenantID, err)
+			return nil
+		}
+		if errors.Is(err, domain.ErrInvalidCursor) || errors.Is(err, services.ErrInvalidDateRange) {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return nil
+}
+
+// auditCSVRecord flattens an event into a CSV row, changes stay JSON encoded
+func auditCSVRecord(e *domain.AuditEvent) ([]string, error) {
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{
+		e.CreatedAt.Format(time.RFC3339Nano),
+		e.TenantID,
+		e.UserID,
+		string(e.Action),
+		e.ResourceType,
+		e.ResourceID,
+		e.RequestID,
+		string(changes),
+	}, nil
+}
+
+// getAuditFilter builds an AuditFilter from the `user_id`, `action`,
+// `resource_type`, `resource_id`, `from`, `to`, `cursor` and `limit` query
+// parameters. Dates must be RFC3339.
+func getAuditFilter(req *http.Request) (*domain.AuditFilter, error) {
+	query := req.URL.Query()
+	filter := &domain.AuditFilter{
+		UserID:       query.Get("user_id"),
+		Action:       domain.AuditAction(query.Get("action")),
+		ResourceType: query.Get("resource_type"),
+		ResourceID:   query.Get("resource_id"),
+		Cursor:       query.Get("cursor"),
+	}
+
+	if from := query.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from date: `%s`", from)
+		}
+		filter.From = &t
+	}
+	if to := query.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to date: `%s`", to)
+		}
+		filter.To = &t
+	}
+	if limit := query.Get("limit"); limit != "" {
+		intLimit, err := strconv.Atoi(limit)
+		if err != nil || intLimit <= 0 {
+			return nil, fmt.Errorf("invalid limit: `%s`", limit)
+		}
+		filter.Limit = intLimit
+	}
+
+	return filter, nil
+}
@@ -6,18 +6,21 @@ import (
 
 	"github.com/kptm-tools/core-service/pkg/api"
 	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/middleware"
 	"github.com/kptm-tools/core-service/pkg/services"
 )
 
 type AuthHandlers struct {
 	authService interfaces.IAuthService
+	cookies     AuthCookies
 }
 
 var _ interfaces.IAuthHandlers = (*AuthHandlers)(nil)
 
-func NewAuthHandlers(authService interfaces.IAuthService) *AuthHandlers {
+func NewAuthHandlers(authService interfaces.IAuthService, cookies AuthCookies) *AuthHandlers {
 	return &AuthHandlers{
 		authService: authService,
+		cookies:     cookies,
 	}
 }
 
@@ -48,8 +51,81 @@ func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
-	return api.WriteJSON(w, http.StatusOK, &resp)
+	// Logins waiting on a password change or two factor have no token yet
+	if resp.Token != "" {
+		h.cookies.setTokens(w, resp.Token, resp.RefreshToken)
+	}
+
+	return api.WriteJSON(w, http.StatusOK, LoginResponse{
+		User:                       resp.User,
+		TokenExpirationInstant:     resp.TokenExpirationInstant,
+		ChangePasswordId:           resp.ChangePasswordId,
+		ChangePasswordReason:       resp.ChangePasswordReason,
+		EmailVerificationId:        resp.EmailVerificationId,
+		RegistrationVerificationId: resp.RegistrationVerificationId,
+		TwoFactorId:                resp.TwoFactorId,
+		Methods:                    resp.Methods,
+	})
+
+}
+
+// RefreshToken rotates the access token cookie with the refresh token cookie.
+// Rejected refresh tokens clear both cookies, the user has to log in again.
+func (h *AuthHandlers) RefreshToken(w http.ResponseWriter, r *http.Request) error {
+	refreshCookie, err := r.Cookie(middleware.RefreshTokenCookie)
+	if err != nil || refreshCookie.Value == "" {
+		statusCode := http.StatusUnauthorized
+		return api.WriteJSON(w, statusCode, api.APIError{Error: "refresh token not found"})
+	}
+	token := ""
+	if tokenCookie, err := r.Cookie(middleware.AccessTokenCookie); err == nil {
+		token = tokenCookie.Value
+	}
+
+	resp, err := h.authService.RefreshToken(refreshCookie.Value, token)
+	if err != nil {
+		var fae *services.FaError
+
+		if errors.As(err, &fae) {
+			if fae.Status() == http.StatusBadRequest || fae.Status() == http.StatusUnauthorized || fae.Status() == http.StatusNotFound {
+				h.cookies.clearTokens(w)
+				return api.WriteJSON(w, http.StatusUnauthorized, api.APIError{Error: fae.Error()})
+			}
+			return api.WriteJSON(w, fae.Status(), api.APIError{Error: fae.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+
+	// FusionAuth only returns a refresh token when it rotates them
+	h.cookies.setTokens(w, resp.Token, resp.RefreshToken)
+
+	refreshResponse := TokenRefreshResponse{}
+	if exp := tokenExpiry(resp.Token); !exp.IsZero() {
+		refreshResponse.TokenExpirationInstant = exp.UnixMilli()
+	}
+	return api.WriteJSON(w, http.StatusOK, refreshResponse)
+}
+
+// Logout clears both cookies and revokes the refresh token cookie. Requests
+// without a refresh token are already logged out.
+func (h *AuthHandlers) Logout(w http.ResponseWriter, r *http.Request) error {
+	// The cookies are cleared even when revoking fails, the browser is logged out either way
+	h.cookies.clearTokens(w)
+
+	if refreshCookie, err := r.Cookie(middleware.RefreshTokenCookie); err == nil && refreshCookie.Value != "" {
+		if err := h.authService.Logout(refreshCookie.Value); err != nil {
+			var fae *services.FaError
+
+			if errors.As(err, &fae) {
+				return api.WriteJSON(w, fae.Status(), api.APIError{Error: fae.Error()})
+			} else {
+				return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+			}
+		}
+	}
 
+	return api.WriteJSON(w, http.StatusOK, map[string]string{"logged_out": "true"})
 }
 
 func (h *AuthHandlers) RegisterTenant(w http.ResponseWriter, r *http.Request) error {
@@ -66,7 +142,7 @@ func (h *AuthHandlers) RegisterTenant(w http.ResponseWriter, r *http.Request) er
 		}
 	}
 
-	t, u, err := h.authService.RegisterTenant(registerTenantRequest.Name)
+	t, u, err := h.authService.RegisterTenant(getActor(r), registerTenantRequest.Name)
 
 	if err != nil {
 		var fae *services.FaError
@@ -143,6 +219,7 @@ func (h *AuthHandlers) RegisterUser(w http.ResponseWriter, r *http.Request) erro
 		}
 	}
 	user, err := h.authService.RegisterUser(
+		getActor(r),
 		registerUserRequest.FirstName,
 		registerUserRequest.LastName,
 		registerUserRequest.Email,
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FusionAuth/go-client/pkg/fusionauth"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+// sessions knows a single refresh token and rotates it on refresh
+type sessions struct {
+	interfaces.IAuthService
+	refreshToken string
+	token        string
+	revoked      []string
+	revokeErr    error
+}
+
+func (s *sessions) Login(email, password, applicationID string) (*fusionauth.LoginResponse, error) {
+	if password != "s3cret" {
+		return nil, services.NewFaError(http.StatusNotFound, "invalid login")
+	}
+	return &fusionauth.LoginResponse{
+		Token:          s.token,
+		RefreshToken:   s.refreshToken,
+		RefreshTokenId: "rt-id",
+		User:           fusionauth.User{Email: email},
+	}, nil
+}
+
+func (s *sessions) RefreshToken(refreshToken, token string) (*fusionauth.JWTRefreshResponse, error) {
+	if refreshToken != s.refreshToken {
+		return nil, services.NewFaError(http.StatusBadRequest, "invalid refresh token")
+	}
+	return &fusionauth.JWTRefreshResponse{Token: s.token, RefreshToken: "rotated"}, nil
+}
+
+func (s *sessions) Logout(refreshToken string) error {
+	s.revoked = append(s.revoked, refreshToken)
+	return s.revokeErr
+}
+
+func testToken(t *testing.T, exp time.Time) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": exp.Unix()}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return token
+}
+
+func responseCookies(rr *httptest.ResponseRecorder) map[string]*http.Cookie {
+	cookies := map[string]*http.Cookie{}
+	for _, c := range rr.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+	return cookies
+}
+
+// assertNoTokens fails when the body holds any of the tokens
+func assertNoTokens(t *testing.T, body string, tokens ...string) {
+	t.Helper()
+	for _, token := range tokens {
+		if strings.Contains(body, token) {
+			t.Errorf("Expected the body to not contain `%s`, got %s", token, body)
+		}
+	}
+	for _, field := range []string{`"token"`, `"refreshToken"`, `"refreshTokenId"`} {
+		if strings.Contains(body, field) {
+			t.Errorf("Expected the body to not contain %s, got %s", field, body)
+		}
+	}
+}
+
+func Test_Login(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	token := testToken(t, exp)
+
+	tests := []struct {
+		name           string
+		password       string
+		expectedStatus int
+		expectCookies  bool
+	}{
+		{
+			name:           "Valid login",
+			password:       "s3cret",
+			expectedStatus: http.StatusOK,
+			expectCookies:  true,
+		},
+		{
+			name:           "Invalid login",
+			password:       "wrong",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewAuthHandlers(&sessions{refreshToken: "rt-1", token: token}, AuthCookies{Secure: true, RefreshTokenTTL: time.Hour})
+
+			body := `{"loginId":"ana@example.com","password":"` + tt.password + `","application_id":"app-1"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			h.Login(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status `%d`, got `%d`", tt.expectedStatus, rr.Code)
+			}
+			assertNoTokens(t, rr.Body.String(), token, "rt-1", "rt-id")
+
+			cookies := responseCookies(rr)
+			if !tt.expectCookies {
+				if len(cookies) != 0 {
+					t.Errorf("Expected no cookies, got %v", cookies)
+				}
+				return
+			}
+			if !strings.Contains(rr.Body.String(), "ana@example.com") {
+				t.Errorf("Expected the user in the body, got %s", rr.Body.String())
+			}
+			at := cookies[middleware.AccessTokenCookie]
+			if at == nil || at.Value != token || !at.HttpOnly || !at.Secure || !at.Expires.Equal(exp) {
+				t.Errorf("Expected an HttpOnly access token cookie expiring at `%v`, got %+v", exp, at)
+			}
+			rt := cookies[middleware.RefreshTokenCookie]
+			if rt == nil || rt.Value != "rt-1" || !rt.HttpOnly || rt.Path != refreshTokenCookiePath {
+				t.Errorf("Expected an HttpOnly refresh token cookie `rt-1`, got %+v", rt)
+			}
+		})
+	}
+}
+
+func Test_RefreshToken(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	token := testToken(t, exp)
+
+	tests := []struct {
+		name           string
+		refreshToken   string
+		expectedStatus int
+		expectedToken  string
+		expectedRT     string
+		clearsCookies  bool
+	}{
+		{
+			name:           "Valid refresh token",
+			refreshToken:   "rt-1",
+			expectedStatus: http.StatusOK,
+			expectedToken:  token,
+			expectedRT:     "rotated",
+		},
+		{
+			name:           "Rejected refresh token",
+			refreshToken:   "rt-2",
+			expectedStatus: http.StatusUnauthorized,
+			clearsCookies:  true,
+		},
+		{
+			name:           "No refresh token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewAuthHandlers(&sessions{refreshToken: "rt-1", token: token}, AuthCookies{Secure: true, RefreshTokenTTL: time.Hour})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/token/refresh", nil)
+			if tt.refreshToken != "" {
+				req.AddCookie(&http.Cookie{Name: middleware.RefreshTokenCookie, Value: tt.refreshToken})
+			}
+			rr := httptest.NewRecorder()
+			h.RefreshToken(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status `%d`, got `%d`", tt.expectedStatus, rr.Code)
+			}
+			assertNoTokens(t, rr.Body.String(), token, "rotated")
+
+			cookies := responseCookies(rr)
+			if tt.clearsCookies {
+				if cookies[middleware.AccessTokenCookie].MaxAge >= 0 || cookies[middleware.RefreshTokenCookie].MaxAge >= 0 {
+					t.Errorf("Expected cookies to be cleared, got %v", cookies)
+				}
+				return
+			}
+			if tt.expectedToken == "" {
+				if len(cookies) != 0 {
+					t.Errorf("Expected no cookies, got %v", cookies)
+				}
+				return
+			}
+
+			at := cookies[middleware.AccessTokenCookie]
+			if at == nil || at.Value != tt.expectedToken || !at.HttpOnly || !at.Secure || !at.Expires.Equal(exp) {
+				t.Errorf("Expected an HttpOnly access token cookie expiring at `%v`, got %+v", exp, at)
+			}
+			rt := cookies[middleware.RefreshTokenCookie]
+			if rt == nil || rt.Value != tt.expectedRT || !rt.HttpOnly || rt.Path != refreshTokenCookiePath {
+				t.Errorf("Expected an HttpOnly refresh token cookie `%s`, got %+v", tt.expectedRT, rt)
+			}
+		})
+	}
+}
+
+func Test_Logout(t *testing.T) {
+	tests := []struct {
+		name            string
+		refreshToken    string
+		revokeErr       error
+		expectedStatus  int
+		expectedRevoked int
+	}{
+		{
+			name:            "Refresh token is revoked",
+			refreshToken:    "rt-1",
+			expectedStatus:  http.StatusOK,
+			expectedRevoked: 1,
+		},
+		{
+			name:            "No refresh token",
+			expectedStatus:  http.StatusOK,
+			expectedRevoked: 0,
+		},
+		{
+			name:            "FusionAuth fails to revoke",
+			refreshToken:    "rt-1",
+			revokeErr:       services.NewFaError(http.StatusServiceUnavailable, "unavailable"),
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedRevoked: 1,
+		},
+		{
+			name:            "FusionAuth is down",
+			refreshToken:    "rt-1",
+			revokeErr:       errors.New("connection refused"),
+			expectedStatus:  http.StatusInternalServerError,
+			expectedRevoked: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &sessions{revokeErr: tt.revokeErr}
+			h := NewAuthHandlers(s, AuthCookies{})
+
+			req := httptest.NewRequest(http.MethodPost, "/api/token/logout", nil)
+			if tt.refreshToken != "" {
+				req.AddCookie(&http.Cookie{Name: middleware.RefreshTokenCookie, Value: tt.refreshToken})
+			}
+			rr := httptest.NewRecorder()
+			h.Logout(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status `%d`, got `%d`", tt.expectedStatus, rr.Code)
+			}
+			if len(s.revoked) != tt.expectedRevoked {
+				t.Errorf("Expected `%d` revoked tokens, got %v", tt.expectedRevoked, s.revoked)
+			}
+			cookies := responseCookies(rr)
+			if cookies[middleware.AccessTokenCookie].MaxAge >= 0 || cookies[middleware.RefreshTokenCookie].MaxAge >= 0 {
+				t.Errorf("Expected cookies to be cleared, got %v", cookies)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+)
+
+// refreshTokenCookiePath limits the refresh token to the refresh and logout
+// endpoints under /api/token, it isn't sent with the other API requests
+const refreshTokenCookiePath = "/api/token"
+
+// AuthCookies sets the HttpOnly cookies holding the tokens of browser sessions,
+// so the frontend never handles them. Secure should only be off for local development.
+type AuthCookies struct {
+	Domain          string
+	Secure          bool
+	RefreshTokenTTL time.Duration
+}
+
+// setTokens sets the access token cookie, and the refresh token one unless
+// refreshToken is empty. The access token cookie expires with the token.
+func (c AuthCookies) setTokens(w http.ResponseWriter, token string, refreshToken string) {
+	http.SetCookie(w, c.cookie(middleware.AccessTokenCookie, token, "/", tokenExpiry(token)))
+	if refreshToken != "" {
+		http.SetCookie(w, c.cookie(middleware.RefreshTokenCookie, refreshToken, refreshTokenCookiePath, time.Now().Add(c.RefreshTokenTTL)))
+	}
+}
+
+// clearTokens removes both token cookies
+func (c AuthCookies) clearTokens(w http.ResponseWriter) {
+	at := c.cookie(middleware.AccessTokenCookie, "", "/", time.Time{})
+	at.MaxAge = -1
+	http.SetCookie(w, at)
+
+	rt := c.cookie(middleware.RefreshTokenCookie, "", refreshTokenCookiePath, time.Time{})
+	rt.MaxAge = -1
+	http.SetCookie(w, rt)
+}
+
+func (c AuthCookies) cookie(name string, value string, path string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.Domain,
+		Expires:  expires,
+		Secure:   c.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// tokenExpiry reads the exp claim of a token without verifying it, the token
+// comes straight from FusionAuth. A zero time makes a session cookie.
+func tokenExpiry(token string) time.Time {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return time.Time{}
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(exp), 0)
+}
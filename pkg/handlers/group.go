@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/kptm-tools/core-service/pkg/api"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+type GroupHandlers struct {
+	groupService interfaces.IGroupService
+}
+
+var _ interfaces.IGroupHandlers = (*GroupHandlers)(nil)
+
+func NewGroupHandlers(groupService interfaces.IGroupService) *GroupHandlers {
+	return &GroupHandlers{
+		groupService: groupService,
+	}
+}
+
+func (h *GroupHandlers) CreateTag(w http.ResponseWriter, req *http.Request) error {
+	tagRequest := new(TagRequest)
+
+	if err := decodeJSONBody(w, req, tagRequest); err != nil {
+		var mr *malformedRequest
+
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	tag, err := h.groupService.CreateTag(getActor(req), domain.NewTag(tenantID, tagRequest.Name))
+	if err != nil {
+		return writeGroupError(w, err)
+	}
+
+	return api.WriteJSON(w, http.StatusCreated, tag)
+}
+
+func (h *GroupHandlers) GetTags(w http.ResponseWriter, req *http.Request) error {
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	tags, err := h.groupService.GetTagsByTenantID(tenantID)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, tags)
+}
+
+func (h *GroupHandlers) PatchTagByID(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	tagRequest := new(TagRequest)
+
+	if err := decodeJSONBody(w, req, tagRequest); err != nil {
+		var mr *malformedRequest
+
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+	tagToDB := &domain.Tag{ID: id, TenantID: tenantID, Name: tagRequest.Name}
+
+	tag, err := h.groupService.PatchTagByID(getActor(req), tagToDB)
+	if err != nil {
+		return writeGroupError(w, err)
+	}
+
+	return api.WriteJSON(w, http.StatusOK, tag)
+}
+
+func (h *GroupHandlers) DeleteTagByID(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	isDeleted, err := h.groupService.DeleteTagByID(getActor(req), id, tenantID)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+	if !isDeleted {
+		statusCode := http.StatusNotFound
+		return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, map[string]string{"deleted": "true"})
+}
+
+// SetHostTags replaces the tags of a host
+func (h *GroupHandlers) SetHostTags(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	tagsRequest := new(HostTagsRequest)
+
+	if err := decodeJSONBody(w, req, tagsRequest); err != nil {
+		var mr *malformedRequest
+
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	tags, err := h.groupService.SetHostTags(getActor(req), id, tenantID, tagsRequest.Tags)
+	if err != nil {
+		return writeGroupError(w, err)
+	}
+
+	return api.WriteJSON(w, http.StatusOK, HostTagsRequest{Tags: tags})
+}
+
+func (h *GroupHandlers) CreateHostGroup(w http.ResponseWriter, req *http.Request) error {
+	groupRequest := new(HostGroupRequest)
+
+	if err := decodeJSONBody(w, req, groupRequest); err != nil {
+		var mr *malformedRequest
+
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+
+	group, err := constructHostGroupForDB(groupRequest, req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	group, err = h.groupService.CreateHostGroup(getActor(req), group)
+	if err != nil {
+		return writeGroupError(w, err)
+	}
+
+	return api.WriteJSON(w, http.StatusCreated, group)
+}
+
+func (h *GroupHandlers) GetHostGroups(w http.ResponseWriter, req *http.Request) error {
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	groups, err := h.groupService.GetHostGroupsByTenantID(tenantID)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, groups)
+}
+
+func (h *GroupHandlers) GetHostGroupByID(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	group, err := h.groupService.GetHostGroupByID(id, tenantID)
+	if err != nil {
+		return writeGroupError(w, err)
+	}
+
+	return api.WriteJSON(w, http.StatusOK, group)
+}
+
+func (h *GroupHandlers) PatchHostGroupByID(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	groupRequest := new(HostGroupRequest)
+
+	if err := decodeJSONBody(w, req, groupRequest); err != nil {
+		var mr *malformedRequest
+
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+
+	groupToDB, err := constructHostGroupForDB(groupRequest, req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	groupToDB.ID = id
+
+	group, err := h.groupService.PatchHostGroupByID(getActor(req), groupToDB)
+	if err != nil {
+		return writeGroupError(w, err)
+	}
+
+	return api.WriteJSON(w, http.StatusOK, group)
+}
+
+func (h *GroupHandlers) DeleteHostGroupByID(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	isDeleted, err := h.groupService.DeleteHostGroupByID(getActor(req), id, tenantID)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+	if !isDeleted {
+		statusCode := http.StatusNotFound
+		return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, map[string]string{"deleted": "true"})
+}
+
+func constructHostGroupForDB(groupRequest *HostGroupRequest, req *http.Request) (*domain.HostGroup, error) {
+	hostIDs, err := parseHostIDs(groupRequest.HostIds)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	return domain.NewHostGroup(tenantID, groupRequest.Name, groupRequest.Description, hostIDs), nil
+}
+
+// writeGroupError maps the errors of the group service to a response
+func writeGroupError(w http.ResponseWriter, err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidTag), errors.Is(err, services.ErrInvalidGroup):
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	case errors.Is(err, services.ErrTagExists), errors.Is(err, services.ErrGroupExists):
+		return api.WriteJSON(w, http.StatusConflict, api.APIError{Error: err.Error()})
+	case errors.Is(err, sql.ErrNoRows):
+		statusCode := http.StatusNotFound
+		return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+	default:
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/middleware"
 )
 
 type malformedRequest struct {
@@ -108,3 +110,28 @@ func GetTenantIDFromHeader(req *http.Request) (string, error) {
 	}
 	return tenantID, nil
 }
+
+// getActor returns who makes the request, for the audit log. Requests
+// without a token have no tenant nor user.
+func getActor(req *http.Request) domain.Actor {
+	tenantID, _ := req.Context().Value(middleware.ContextTenantID).(string)
+	userID, _ := req.Context().Value(middleware.ContextUserID).(string)
+	apiKeyID, _ := req.Context().Value(middleware.ContextAPIKeyID).(string)
+	roles, _ := req.Context().Value(middleware.ContextRoles).([]domain.Role)
+	requestID, _ := req.Context().Value(middleware.ContextRequestID).(string)
+
+	return domain.Actor{TenantID: tenantID, UserID: userID, APIKeyID: apiKeyID, Roles: roles, RequestID: requestID}
+}
+
+// parseHostIDs converts the host IDs of a request body
+func parseHostIDs(strIDs []string) ([]int, error) {
+	var hostIDs []int
+	for _, strID := range strIDs {
+		intID, err := strconv.Atoi(strID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id: %s", strID)
+		}
+		hostIDs = append(hostIDs, intID)
+	}
+	return hostIDs, nil
+}
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"strconv"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/kptm-tools/common/common/enums"
 	cmmn "github.com/kptm-tools/common/common/events"
 	"github.com/kptm-tools/core-service/pkg/middleware"
@@ -50,7 +52,7 @@ func (h *HostHandlers) CreateHost(w http.ResponseWriter, req *http.Request) erro
 		return api.WriteJSON(w, http.StatusBadRequest, err.Error())
 	}
 
-	host, err = h.hostService.CreateHost(host)
+	host, err = h.hostService.CreateHost(getActor(req), host)
 	if err != nil {
 		return api.WriteJSON(w, http.StatusInternalServerError, err.Error())
 	}
@@ -60,21 +62,94 @@ func (h *HostHandlers) CreateHost(w http.ResponseWriter, req *http.Request) erro
 
 func (h *HostHandlers) GetHostsByTenantIDAndUserID(w http.ResponseWriter, req *http.Request) error {
 
-	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+	filter, err := getHostFilter(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	filter.TenantID = req.Context().Value(middleware.ContextTenantID).(string)
 	userID := req.Context().Value(middleware.ContextUserID).(string)
+	roles, _ := req.Context().Value(middleware.ContextRoles).([]domain.Role)
 
-	hosts, err := h.hostService.GetHostsByTenantIDAndUserID(tenantID, userID)
+	page, err := h.hostService.GetHosts(*filter, userID, roles)
 
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
+		if errors.Is(err, services.ErrForbiddenOwner) || errors.Is(err, services.ErrForbiddenDeleted) {
+			return api.WriteJSON(w, http.StatusForbidden, api.APIError{Error: err.Error()})
+		}
 		return api.WriteJSON(w, http.StatusInternalServerError, err.Error())
 	}
 
-	hostsResponse := []*domain.HostResponse{}
-	for _, host := range hosts {
-		hostsResponse = append(hostsResponse, constructResponse(host))
+	pageResponse := &domain.HostPageResponse{
+		Hosts:      []*domain.HostResponse{},
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+	}
+	for _, host := range page.Hosts {
+		pageResponse.Hosts = append(pageResponse.Hosts, constructResponse(host))
 	}
 
-	return api.WriteJSON(w, http.StatusOK, hostsResponse)
+	return api.WriteJSON(w, http.StatusOK, pageResponse)
+}
+
+// getHostFilter builds a HostFilter from the `owner`, `tag`, `domain`, `ip`, `rapporteur`,
+// `deleted`, `sort`, `order`, `cursor` and `limit` query parameters. The ip can be a CIDR block.
+func getHostFilter(req *http.Request) (*domain.HostFilter, error) {
+	query := req.URL.Query()
+	filter := &domain.HostFilter{
+		Tags:            query["tag"],
+		Domain:          strings.TrimSpace(query.Get("domain")),
+		RapporteurEmail: strings.TrimSpace(query.Get("rapporteur")),
+		Cursor:          query.Get("cursor"),
+	}
+
+	if owner := query.Get("owner"); owner != "" {
+		if err := uuid.Validate(owner); err != nil {
+			return nil, fmt.Errorf("invalid owner: `%s`", owner)
+		}
+		filter.Owner = owner
+	}
+	if ip := strings.TrimSpace(query.Get("ip")); ip != "" {
+		if prefix, err := netip.ParsePrefix(ip); err == nil {
+			filter.IP = prefix.Masked().String()
+		} else if addr, ok := services.ParseIPHost(ip); ok {
+			filter.IP = addr.String()
+		} else {
+			return nil, fmt.Errorf("invalid ip: `%s`", ip)
+		}
+	}
+	if deleted := query.Get("deleted"); deleted != "" {
+		isDeleted, err := strconv.ParseBool(deleted)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deleted: `%s`", deleted)
+		}
+		filter.Deleted = isDeleted
+	}
+	if sort := query.Get("sort"); sort != "" {
+		hostSort, err := domain.ParseHostSort(sort)
+		if err != nil {
+			return nil, err
+		}
+		filter.Sort = hostSort
+	}
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return nil, fmt.Errorf("invalid order: `%s`", order)
+	}
+	if limit := query.Get("limit"); limit != "" {
+		intLimit, err := strconv.Atoi(limit)
+		if err != nil || intLimit <= 0 {
+			return nil, fmt.Errorf("invalid limit: `%s`", limit)
+		}
+		filter.Limit = intLimit
+	}
+
+	return filter, nil
 }
 
 func (h *HostHandlers) GetHostByID(w http.ResponseWriter, req *http.Request) error {
@@ -83,7 +158,11 @@ func (h *HostHandlers) GetHostByID(w http.ResponseWriter, req *http.Request) err
 		return api.WriteJSON(w, http.StatusBadRequest, err.Error())
 	}
 
-	host, err := h.hostService.GetHostByID(id)
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+	userID := req.Context().Value(middleware.ContextUserID).(string)
+	roles, _ := req.Context().Value(middleware.ContextRoles).([]domain.Role)
+
+	host, err := h.hostService.GetHostByID(id, tenantID, userID, roles)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			statusCode := http.StatusNotFound
@@ -114,39 +193,103 @@ func (h *HostHandlers) PatchHostByID(w http.ResponseWriter, req *http.Request) e
 	}
 	hostToDB, err := constructHostForDB(createHostRequest, req, h)
 	if err != nil {
-		return err
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
 	}
 	hostToDB.ID = id
-	host, err := h.hostService.PatchHostByID(hostToDB)
+	host, err := h.hostService.PatchHostByID(getActor(req), hostToDB)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			statusCode := http.StatusNotFound
 			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
 		}
+		if errors.Is(err, domain.ErrMissingPassword) {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
 		return api.WriteJSON(w, http.StatusInternalServerError, err.Error())
 	}
 
 	return api.WriteJSON(w, http.StatusCreated, constructResponse(host))
 }
 
+// TransferHost gives the host to another operator of the tenant
+func (h *HostHandlers) TransferHost(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	transferRequest := new(TransferHostRequest)
+
+	if err := decodeJSONBody(w, req, transferRequest); err != nil {
+		var mr *malformedRequest
+
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+	if err := uuid.Validate(transferRequest.OperatorID); err != nil {
+		msg := fmt.Sprintf("invalid operator_id: `%s`", transferRequest.OperatorID)
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: msg})
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	host, err := h.hostService.TransferHost(getActor(req), id, tenantID, transferRequest.OperatorID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			statusCode := http.StatusNotFound
+			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+		}
+		if errors.Is(err, services.ErrInvalidOwner) {
+			return api.WriteJSON(w, http.StatusUnprocessableEntity, api.APIError{Error: err.Error()})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, constructResponse(host))
+}
+
 func (h *HostHandlers) DeleteHostByID(w http.ResponseWriter, req *http.Request) error {
 	id, err := GetID(req)
 	if err != nil {
 		return api.WriteJSON(w, http.StatusBadRequest, err.Error())
 	}
 
-	isDeleted, err := h.hostService.DeleteHostByID(id)
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	isDeleted, err := h.hostService.DeleteHostByID(getActor(req), id, tenantID)
 	if err != nil {
 		return api.WriteJSON(w, http.StatusInternalServerError, err.Error())
 	}
+	if !isDeleted {
+		statusCode := http.StatusNotFound
+		return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, map[string]string{"deleted": "true"})
+}
+
+// RestoreHost undoes the deletion of a host that hasn't been purged
+func (h *HostHandlers) RestoreHost(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
 
-	result := make(map[string]string)
-	if isDeleted {
-		result["deleted"] = "true"
-	} else {
-		result["deleted"] = "false"
+	host, err := h.hostService.RestoreHost(getActor(req), id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			statusCode := http.StatusNotFound
+			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
 	}
-	return api.WriteJSON(w, http.StatusOK, result)
+
+	return api.WriteJSON(w, http.StatusOK, constructResponse(host))
 }
 
 func (h *HostHandlers) ValidateHost(w http.ResponseWriter, req *http.Request) error {
@@ -204,6 +347,8 @@ func constructResponse(host *domain.Host) *domain.HostResponse {
 	hostResponse.IP = host.IP
 	hostResponse.Rapporteurs = host.Rapporteurs
 	hostResponse.Credentials = host.Credentials
+	hostResponse.Tags = host.Tags
+	hostResponse.DeletedAt = host.DeletedAt
 	return hostResponse
 }
 
@@ -224,20 +369,27 @@ func getDomainIPValues(createHostRequest *CreateHostRequest, h *HostHandlers) (s
 		if err != nil {
 			return "", "", fmt.Errorf("error looking up IP of domain: %w", err)
 		}
+		// Prefer IPv4, IPv6-only domains get their first IPv6 address
 		for _, ip := range ips {
 			if ipv4 := ip.To4(); ipv4 != nil {
 				ipValue = ipv4.String()
 				break
 			}
+			if ipValue == "" {
+				ipValue = ip.String()
+			}
 		}
 		return domain, ipValue, nil
 	}
 
 	if createHostRequest.ValueType == string(enums.IP) {
-		normalizedURL := cmmn.NormalizeURL(createHostRequest.Value)
+		addr, ok := services.ParseIPHost(createHostRequest.Value)
+		if !ok {
+			return "", "", fmt.Errorf("invalid ip: %s", createHostRequest.Value)
+		}
 
-		ipValue = strings.Split(normalizedURL, "//")[1]
-		domainValue = h.hostService.GetHostname(ipValue + ":443")
+		ipValue = addr.String()
+		domainValue = h.hostService.GetHostname(net.JoinHostPort(ipValue, "443"))
 		return domainValue, ipValue, nil
 	}
 
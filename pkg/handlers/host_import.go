@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/kptm-tools/core-service/pkg/api"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+// maxImportBodySize bounds host imports, 300 assets are around 30KB of CSV
+const maxImportBodySize = 5 << 20
+
+// ImportHosts creates hosts from a `text/csv` or `application/json` body
+func (h *HostHandlers) ImportHosts(w http.ResponseWriter, req *http.Request) error {
+	var (
+		rows []domain.HostImportRow
+		err  error
+	)
+
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(req.Header.Get("Content-Type"), ";")[0]))
+	switch mediaType {
+	case "text/csv":
+		rows, err = parseImportCSV(http.MaxBytesReader(w, req.Body, maxImportBodySize))
+	case "application/json", "":
+		importRequest := new(ImportHostsRequest)
+		if err = decodeJSONBody(w, req, importRequest); err == nil {
+			rows = importRequest.Hosts
+			for i := range rows {
+				rows[i].Row = i + 1
+			}
+		}
+	default:
+		statusCode := http.StatusUnsupportedMediaType
+		return api.WriteJSON(w, statusCode, api.APIError{Error: "Content-Type header must be text/csv or application/json"})
+	}
+
+	if err != nil {
+		var mr *malformedRequest
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+	operatorID := req.Context().Value(middleware.ContextUserID).(string)
+
+	report, err := h.hostService.ImportHosts(getActor(req), tenantID, operatorID, rows)
+	if err != nil {
+		if errors.Is(err, services.ErrEmptyImport) {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, report)
+}
+
+// parseImportCSV reads a CSV with a header row. The value column is required, name
+// and rapporteurs are optional. Rapporteurs are `;` separated addresses such as
+// `Ana <ana@example.com>`. Credentials can only be imported with JSON.
+func parseImportCSV(r io.Reader) ([]domain.HostImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, &malformedRequest{status: http.StatusBadRequest, msg: "Request body must not be empty"}
+		}
+		return nil, csvError(err)
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		switch column {
+		case "value", "name", "rapporteurs":
+			columns[column] = i
+		default:
+			msg := fmt.Sprintf("Unknown CSV column `%s`, expected value, name and rapporteurs", column)
+			return nil, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+		}
+	}
+	if _, ok := columns["value"]; !ok {
+		return nil, &malformedRequest{status: http.StatusBadRequest, msg: "CSV header must have a value column"}
+	}
+
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rows := []domain.HostImportRow{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+
+		row := domain.HostImportRow{
+			Row:         len(rows) + 1,
+			Value:       field(record, "value"),
+			Name:        field(record, "name"),
+			Rapporteurs: []domain.Rapporteur{},
+		}
+		for _, address := range strings.Split(field(record, "rapporteurs"), ";") {
+			if strings.TrimSpace(address) == "" {
+				continue
+			}
+			parsed, err := mail.ParseAddress(address)
+			if err != nil {
+				msg := fmt.Sprintf("Invalid rapporteur `%s` on row %d", strings.TrimSpace(address), row.Row)
+				return nil, &malformedRequest{status: http.StatusBadRequest, msg: msg}
+			}
+			row.Rapporteurs = append(row.Rapporteurs, domain.Rapporteur{Name: parsed.Name, Email: parsed.Address})
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func csvError(err error) error {
+	var (
+		parseError    *csv.ParseError
+		maxBytesError *http.MaxBytesError
+	)
+	switch {
+	case errors.As(err, &parseError):
+		msg := fmt.Sprintf("Request body contains badly-formed CSV (line %d): %s", parseError.Line, parseError.Err)
+		return &malformedRequest{status: http.StatusBadRequest, msg: msg}
+	case errors.As(err, &maxBytesError):
+		msg := "Request body must not be larger than 5MB"
+		return &malformedRequest{status: http.StatusRequestEntityTooLarge, msg: msg}
+	default:
+		return err
+	}
+}
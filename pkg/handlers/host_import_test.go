@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+// CreateHosts skips hosts whose alias is taken by any tenant, like the unique alias index
+func (s *hostStorage) CreateHosts(tenantID string, hosts []*domain.Host) ([]*domain.Host, error) {
+	created := make([]*domain.Host, len(hosts))
+	for i, h := range hosts {
+		taken := false
+		for _, existing := range s.hosts {
+			taken = taken || existing.Name == h.Name
+		}
+		if taken {
+			continue
+		}
+		h.ID = len(s.hosts) + 1
+		s.hosts[h.ID] = h
+		created[i] = h
+	}
+	return created, nil
+}
+
+func Test_ImportHosts(t *testing.T) {
+	csvBody := strings.Join([]string{
+		"value,name,rapporteurs",
+		"10.0.0.0/30,lab,Ana <ana@example.com>;bob@example.com",
+		"10.0.0.9,host-a,",
+		"not a host,broken,",
+		"[2001:db8::1]:8443,v6,",
+		"2001:db8::2,v6,",
+		"10.0.0.0/8,too-big,",
+		"https://www.example.com/login,,",
+	}, "\n")
+
+	tests := []struct {
+		name            string
+		contentType     string
+		body            string
+		expectedStatus  int
+		expectedReport  *domain.HostImportReport
+		expectedResults []domain.HostImportStatus
+	}{
+		{
+			name:            "CSV",
+			contentType:     "text/csv",
+			body:            csvBody,
+			expectedStatus:  http.StatusOK,
+			expectedReport:  &domain.HostImportReport{Created: 4, Skipped: 2, Invalid: 2},
+			expectedResults: []domain.HostImportStatus{"created", "created", "skipped", "invalid", "created", "skipped", "invalid", "created"},
+		},
+		{
+			name:            "JSON",
+			contentType:     "application/json",
+			body:            `{"hosts": [{"value": "192.168.1.10", "name": "db"}, {"value": "192.168.1.0/33"}]}`,
+			expectedStatus:  http.StatusOK,
+			expectedReport:  &domain.HostImportReport{Created: 1, Invalid: 1},
+			expectedResults: []domain.HostImportStatus{"created", "invalid"},
+		},
+		{
+			name:           "Empty import",
+			contentType:    "text/csv",
+			body:           "value,name\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown CSV column",
+			contentType:    "text/csv",
+			body:           "value,password\n10.0.0.1,secret\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid rapporteur",
+			contentType:    "text/csv",
+			body:           "value,rapporteurs\n10.0.0.1,not an email\n",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unsupported content type",
+			contentType:    "application/xml",
+			body:           "<hosts/>",
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHostHandlers(services.NewHostService(newHostStorage(), nil, nil, 16))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/hosts/import", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			ctx := context.WithValue(req.Context(), middleware.ContextTenantID, tenantA)
+			ctx = context.WithValue(ctx, middleware.ContextUserID, "00000000-0000-0000-0000-000000000000")
+			req = req.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			if err := h.ImportHosts(w, req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected `%d`, got `%d`: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedReport == nil {
+				return
+			}
+
+			report := new(domain.HostImportReport)
+			if err := json.Unmarshal(w.Body.Bytes(), report); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if report.Created != tt.expectedReport.Created || report.Skipped != tt.expectedReport.Skipped || report.Invalid != tt.expectedReport.Invalid {
+				t.Errorf("Expected `%d/%d/%d`, got `%d/%d/%d`", tt.expectedReport.Created, tt.expectedReport.Skipped, tt.expectedReport.Invalid,
+					report.Created, report.Skipped, report.Invalid)
+			}
+			if len(report.Results) != len(tt.expectedResults) {
+				t.Fatalf("Expected `%d` results, got `%d`: %+v", len(tt.expectedResults), len(report.Results), report.Results)
+			}
+			for i, status := range tt.expectedResults {
+				if report.Results[i].Status != status {
+					t.Errorf("Expected result `%d` to be `%s`, got `%+v`", i, status, report.Results[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+const (
+	tenantA = "aaaaaaaa-0000-0000-0000-000000000000"
+	tenantB = "bbbbbbbb-0000-0000-0000-000000000000"
+
+	operatorA = "00000000-0000-0000-0000-00000000000a"
+	operatorB = "00000000-0000-0000-0000-00000000000b"
+)
+
+// hostStorage keeps hosts in memory and scopes them by tenant like PostgreSQLStore
+type hostStorage struct {
+	interfaces.IStorage
+	hosts map[int]*domain.Host
+}
+
+func newHostStorage() *hostStorage {
+	return &hostStorage{hosts: map[int]*domain.Host{
+		1: {ID: 1, TenantID: tenantA, OperatorID: operatorA, Name: "host-a", IP: "10.0.0.1"},
+		2: {ID: 2, TenantID: tenantB, OperatorID: operatorB, Name: "host-b", IP: "10.0.0.2"},
+		3: {ID: 3, TenantID: tenantA, OperatorID: operatorB, Name: "host-c", IP: "10.0.0.3"},
+	}}
+}
+
+func (s *hostStorage) CreateAuditEvent(e *domain.AuditEvent) error {
+	return nil
+}
+
+func (s *hostStorage) GetHostByID(ID int, tenantID string) (*domain.Host, error) {
+	host, ok := s.hosts[ID]
+	if !ok || host.TenantID != tenantID {
+		return nil, fmt.Errorf("failed to fetch host: %w", sql.ErrNoRows)
+	}
+	return host, nil
+}
+
+func (s *hostStorage) PatchHostByID(h *domain.Host) (*domain.Host, error) {
+	if _, err := s.GetHostByID(h.ID, h.TenantID); err != nil {
+		return nil, err
+	}
+	// No credential is stored, so every one needs a password
+	for _, c := range h.Credentials {
+		if c.Password == "" {
+			return nil, fmt.Errorf("failed to update credentials: %w", domain.ErrMissingPassword)
+		}
+	}
+	s.hosts[h.ID] = h
+	return h, nil
+}
+
+func (s *hostStorage) DeleteHostByID(ID int, tenantID string) (bool, error) {
+	if _, err := s.GetHostByID(ID, tenantID); err != nil {
+		return false, nil
+	}
+	delete(s.hosts, ID)
+	return true, nil
+}
+
+func Test_HostHandlersTenantIsolation(t *testing.T) {
+	operator := []domain.Role{domain.RoleOperator}
+
+	tests := []struct {
+		name           string
+		method         string
+		hostID         int
+		tenantID       string
+		userID         string
+		roles          []domain.Role
+		expectedStatus int
+	}{
+		{name: "Get own host", method: http.MethodGet, hostID: 1, tenantID: tenantA, userID: operatorA, roles: operator, expectedStatus: http.StatusOK},
+		{name: "Get host of another tenant", method: http.MethodGet, hostID: 2, tenantID: tenantA, userID: operatorA, roles: operator, expectedStatus: http.StatusNotFound},
+		{name: "Get missing host", method: http.MethodGet, hostID: 4, tenantID: tenantA, userID: operatorA, roles: operator, expectedStatus: http.StatusNotFound},
+		{name: "Get host of another operator", method: http.MethodGet, hostID: 3, tenantID: tenantA, userID: operatorA, roles: operator, expectedStatus: http.StatusNotFound},
+		{name: "Analyst gets host of an operator", method: http.MethodGet, hostID: 3, tenantID: tenantA, userID: operatorA, roles: []domain.Role{domain.RoleAnalyst}, expectedStatus: http.StatusOK},
+		{name: "Admin gets host of an operator", method: http.MethodGet, hostID: 3, tenantID: tenantA, userID: operatorA, roles: []domain.Role{domain.RoleAdmin}, expectedStatus: http.StatusOK},
+		{name: "Patch own host", method: http.MethodPatch, hostID: 1, tenantID: tenantA, userID: operatorA, roles: operator, expectedStatus: http.StatusCreated},
+		{name: "Patch host of another tenant", method: http.MethodPatch, hostID: 1, tenantID: tenantB, userID: operatorB, roles: operator, expectedStatus: http.StatusNotFound},
+		{name: "Patch host of another operator", method: http.MethodPatch, hostID: 3, tenantID: tenantA, userID: operatorA, roles: operator, expectedStatus: http.StatusNotFound},
+		{name: "Delete host of another tenant", method: http.MethodDelete, hostID: 2, tenantID: tenantA, userID: operatorA, roles: operator, expectedStatus: http.StatusNotFound},
+		{name: "Delete host of another operator", method: http.MethodDelete, hostID: 3, tenantID: tenantA, userID: operatorA, roles: operator, expectedStatus: http.StatusNotFound},
+		{name: "Delete own host", method: http.MethodDelete, hostID: 2, tenantID: tenantB, userID: operatorB, roles: operator, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newHostStorage()
+			h := NewHostHandlers(services.NewHostService(storage, nil, nil, 0))
+
+			body := `{"value": "127.0.0.1", "name": "renamed", "value_type": "IP"}`
+			req := httptest.NewRequest(tt.method, "/api/hosts/"+strconv.Itoa(tt.hostID), strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.SetPathValue("id", strconv.Itoa(tt.hostID))
+			ctx := context.WithValue(req.Context(), middleware.ContextTenantID, tt.tenantID)
+			ctx = context.WithValue(ctx, middleware.ContextUserID, tt.userID)
+			ctx = context.WithValue(ctx, middleware.ContextRoles, tt.roles)
+			req = req.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			handlers := map[string]func(http.ResponseWriter, *http.Request) error{
+				http.MethodGet:    h.GetHostByID,
+				http.MethodPatch:  h.PatchHostByID,
+				http.MethodDelete: h.DeleteHostByID,
+			}
+			if err := handlers[tt.method](w, req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected `%d`, got `%d`: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+
+			// Hosts the user can't see must be left untouched
+			for id, host := range newHostStorage().hosts {
+				if host.TenantID == tt.tenantID && (host.OperatorID == tt.userID || !slices.Equal(tt.roles, operator)) {
+					continue
+				}
+				if got, ok := storage.hosts[id]; !ok || got.Name != host.Name {
+					t.Errorf("Expected host `%d` the user can't see to be untouched", id)
+				}
+			}
+		})
+	}
+}
+
+func Test_PatchHostByIDMissingPassword(t *testing.T) {
+	h := NewHostHandlers(services.NewHostService(newHostStorage(), nil, nil, 0))
+
+	body := `{"value": "127.0.0.1", "name": "renamed", "value_type": "IP", "credentials": [{"username": "root"}]}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/hosts/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.SetPathValue("id", "1")
+	ctx := context.WithValue(req.Context(), middleware.ContextTenantID, tenantA)
+	ctx = context.WithValue(ctx, middleware.ContextUserID, operatorA)
+	ctx = context.WithValue(ctx, middleware.ContextRoles, []domain.Role{domain.RoleOperator})
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	if err := h.PatchHostByID(w, req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected `%d`, got `%d`: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+}
+
+func Test_getHostFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected domain.HostFilter
+		wantErr  bool
+	}{
+		{
+			name:     "No parameters",
+			query:    "",
+			expected: domain.HostFilter{},
+		},
+		{
+			name:  "Every parameter",
+			query: "tag=prod&tag=web&domain=example&rapporteur=ana@example.com&sort=created_at&order=desc&cursor=abc&limit=10",
+			expected: domain.HostFilter{
+				Tags:            []string{"prod", "web"},
+				Domain:          "example",
+				RapporteurEmail: "ana@example.com",
+				Sort:            domain.HostSortCreatedAt,
+				Descending:      true,
+				Cursor:          "abc",
+				Limit:           10,
+			},
+		},
+		{
+			name:     "IPv4 address",
+			query:    "ip=10.0.0.1",
+			expected: domain.HostFilter{IP: "10.0.0.1"},
+		},
+		{
+			name:     "CIDR block is masked",
+			query:    "ip=10.0.0.7/24",
+			expected: domain.HostFilter{IP: "10.0.0.0/24"},
+		},
+		{
+			name:     "IPv6 address",
+			query:    "ip=2001:DB8::1",
+			expected: domain.HostFilter{IP: "2001:db8::1"},
+		},
+		{
+			name:    "Invalid ip",
+			query:   "ip=example.com",
+			wantErr: true,
+		},
+		{
+			name:     "Deleted hosts",
+			query:    "deleted=true",
+			expected: domain.HostFilter{Deleted: true},
+		},
+		{
+			name:    "Invalid deleted",
+			query:   "deleted=maybe",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid sort",
+			query:   "sort=domain",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid order",
+			query:   "order=up",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid limit",
+			query:   "limit=0",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/hosts?"+tt.query, nil)
+
+			filter, err := getHostFilter(req)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected an error, got filter %+v", filter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(*filter, tt.expected) {
+				t.Errorf("Expected filter %+v, got %+v", tt.expected, *filter)
+			}
+		})
+	}
+}
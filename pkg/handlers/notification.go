@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/kptm-tools/core-service/pkg/api"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+type NotificationHandlers struct {
+	notificationService interfaces.INotificationService
+}
+
+var _ interfaces.INotificationHandlers = (*NotificationHandlers)(nil)
+
+func NewNotificationHandlers(notificationService interfaces.INotificationService) *NotificationHandlers {
+	return &NotificationHandlers{
+		notificationService: notificationService,
+	}
+}
+
+func (h *NotificationHandlers) GetScanNotifications(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	notifications, err := h.notificationService.GetNotificationsByScanID(id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			statusCode := http.StatusNotFound
+			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, notifications)
+}
+
+func (h *NotificationHandlers) GetNotificationTemplate(w http.ResponseWriter, req *http.Request) error {
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	template, err := h.notificationService.GetNotificationTemplate(tenantID)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, template)
+}
+
+func (h *NotificationHandlers) UpdateNotificationTemplate(w http.ResponseWriter, req *http.Request) error {
+	templateRequest := new(NotificationTemplateRequest)
+
+	if err := decodeJSONBody(w, req, templateRequest); err != nil {
+		var mr *malformedRequest
+
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+
+	template := &domain.NotificationTemplate{
+		TenantID: req.Context().Value(middleware.ContextTenantID).(string),
+		Subject:  templateRequest.Subject,
+		Body:     templateRequest.Body,
+	}
+
+	template, err := h.notificationService.UpdateNotificationTemplate(getActor(req), template)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTemplate) {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, template)
+}
+
+func (h *NotificationHandlers) ResetNotificationTemplate(w http.ResponseWriter, req *http.Request) error {
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	template, err := h.notificationService.ResetNotificationTemplate(getActor(req), tenantID)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, template)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/kptm-tools/core-service/pkg/api"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+type PermissionHandlers struct {
+	permissionService interfaces.IPermissionService
+}
+
+var _ interfaces.IPermissionHandlers = (*PermissionHandlers)(nil)
+
+func NewPermissionHandlers(permissionService interfaces.IPermissionService) *PermissionHandlers {
+	return &PermissionHandlers{
+		permissionService: permissionService,
+	}
+}
+
+func (h *PermissionHandlers) GetRolePermissions(w http.ResponseWriter, req *http.Request) error {
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	bindings, err := h.permissionService.GetRolePermissions(tenantID)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, bindings)
+}
+
+func (h *PermissionHandlers) SetRolePermissions(w http.ResponseWriter, req *http.Request) error {
+	role, err := domain.ParseRole(req.PathValue("role"))
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	permissionsRequest := new(RolePermissionsRequest)
+
+	if err := decodeJSONBody(w, req, permissionsRequest); err != nil {
+		var mr *malformedRequest
+
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	binding, err := h.permissionService.SetRolePermissions(getActor(req), tenantID, role, permissionsRequest.Permissions)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPermissions) {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, binding)
+}
+
+func (h *PermissionHandlers) ResetRolePermissions(w http.ResponseWriter, req *http.Request) error {
+	role, err := domain.ParseRole(req.PathValue("role"))
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	binding, err := h.permissionService.ResetRolePermissions(getActor(req), tenantID, role)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, binding)
+}
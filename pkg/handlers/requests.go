@@ -1,6 +1,13 @@
 package handlers
 
-import "github.com/kptm-tools/core-service/pkg/domain"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/FusionAuth/go-client/pkg/fusionauth"
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
 
 type CreateHostRequest struct {
 	Value       string              `json:"value"`
@@ -10,6 +17,14 @@ type CreateHostRequest struct {
 	Rapporteurs []domain.Rapporteur `json:"rapporteurs"`
 }
 
+type ImportHostsRequest struct {
+	Hosts []domain.HostImportRow `json:"hosts"`
+}
+
+type TransferHostRequest struct {
+	OperatorID string `json:"operator_id"`
+}
+
 type ValidateHostRequest struct {
 	Value    string `json:"value"`
 	Hostname string `json:"hostname"`
@@ -56,10 +71,91 @@ type RegisterUserRequest struct {
 	ApplicationID string `json:"application_id"`
 }
 
+// APIKeyRequest creates an API key, scopes are role names and a nil
+// ExpiresAt never expires
+type APIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// PatchAPIKeyRequest updates the fields of an API key that are sent, a null
+// expires_at removes the expiry
+type PatchAPIKeyRequest struct {
+	Name      *string      `json:"name"`
+	Scopes    *[]string    `json:"scopes"`
+	ExpiresAt optionalTime `json:"expires_at"`
+}
+
+// optionalTime tells a missing time apart from a null one
+type optionalTime struct {
+	Set  bool
+	Time *time.Time
+}
+
+func (o *optionalTime) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	return json.Unmarshal(data, &o.Time)
+}
+
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// FusionAuthEventRequest is the body of FusionAuth webhooks, user events have the user
+type FusionAuthEventRequest struct {
+	Event fusionauth.BaseUserEvent `json:"event"`
+}
+
 type ServiceHost struct {
 	Names []string `json:"names"`
 	Host  string   `json:"host"`
 }
 type ScanRequest struct {
-	HostIds []string `json:"host_ids"`
+	HostIds     []string                                     `json:"host_ids"`
+	GroupIds    []string                                     `json:"group_ids"`
+	Tags        []string                                     `json:"tags"`
+	Tools       []enums.ServiceName                          `json:"tools"`
+	ToolOptions map[enums.ServiceName]map[string]interface{} `json:"tool_options"`
+}
+
+type ScheduleRequest struct {
+	Name            string                                       `json:"name"`
+	HostIds         []string                                     `json:"host_ids"`
+	CronExpression  string                                       `json:"cron_expression"`
+	IntervalMinutes int                                          `json:"interval_minutes"`
+	Tools           []enums.ServiceName                          `json:"tools"`
+	ToolOptions     map[enums.ServiceName]map[string]interface{} `json:"tool_options"`
+	Enabled         *bool                                        `json:"enabled"`
+}
+
+// PatchScheduleRequest updates the fields of a schedule that are sent,
+// tool_options replace the options of every tool so they need tools
+type PatchScheduleRequest struct {
+	Name            *string                                      `json:"name"`
+	HostIds         *[]string                                    `json:"host_ids"`
+	CronExpression  *string                                      `json:"cron_expression"`
+	IntervalMinutes *int                                         `json:"interval_minutes"`
+	Tools           *[]enums.ServiceName                         `json:"tools"`
+	ToolOptions     map[enums.ServiceName]map[string]interface{} `json:"tool_options"`
+	Enabled         *bool                                        `json:"enabled"`
+}
+
+type NotificationTemplateRequest struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type TagRequest struct {
+	Name string `json:"name"`
+}
+
+type HostTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type HostGroupRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	HostIds     []string `json:"host_ids"`
 }
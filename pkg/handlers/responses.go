@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"time"
+
+	"github.com/FusionAuth/go-client/pkg/fusionauth"
 	"github.com/kptm-tools/core-service/pkg/domain"
 )
 
@@ -8,3 +11,32 @@ type RegisterTenantResponse struct {
 	ApplicationID string      `json:"application_id"`
 	User          domain.User `json:"user"`
 }
+
+// CreateAPIKeyResponse holds the new key, it can't be retrieved again
+type CreateAPIKeyResponse struct {
+	*domain.APIKey
+	Key string `json:"key"`
+}
+
+// LoginResponse is the session of a login, the tokens are only sent in the
+// HttpOnly cookies. The other fields tell the frontend what a login still needs.
+type LoginResponse struct {
+	User                       fusionauth.User                 `json:"user"`
+	TokenExpirationInstant     int64                           `json:"tokenExpirationInstant,omitempty"`
+	ChangePasswordId           string                          `json:"changePasswordId,omitempty"`
+	ChangePasswordReason       fusionauth.ChangePasswordReason `json:"changePasswordReason,omitempty"`
+	EmailVerificationId        string                          `json:"emailVerificationId,omitempty"`
+	RegistrationVerificationId string                          `json:"registrationVerificationId,omitempty"`
+	TwoFactorId                string                          `json:"twoFactorId,omitempty"`
+	Methods                    []fusionauth.TwoFactorMethod    `json:"methods,omitempty"`
+}
+
+// TokenRefreshResponse tells when the new access token expires, the token
+// itself is only sent in the HttpOnly cookie
+type TokenRefreshResponse struct {
+	TokenExpirationInstant int64 `json:"tokenExpirationInstant,omitempty"`
+}
+
+type SchedulePreviewResponse struct {
+	NextRuns []time.Time `json:"next_runs"`
+}
@@ -1,30 +1,39 @@
 package handlers
 
 import (
+	"bytes"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/kptm-tools/common/common/enums"
 	cmmn "github.com/kptm-tools/common/common/events"
 	"github.com/kptm-tools/core-service/pkg/api"
+	"github.com/kptm-tools/core-service/pkg/domain"
 	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/services"
 )
 
 type ScanHandlers struct {
-	scanService interfaces.IScanService
-	eventBus    cmmn.EventBus
+	scanService   interfaces.IScanService
+	eventBus      cmmn.EventBus
+	updatesBroker interfaces.IScanUpdatesBroker
 }
 
 var _ interfaces.IScanHandlers = (*ScanHandlers)(nil)
 
-func NewScanHandlers(scanService interfaces.IScanService, bus cmmn.EventBus) *ScanHandlers {
+func NewScanHandlers(scanService interfaces.IScanService, bus cmmn.EventBus, broker interfaces.IScanUpdatesBroker) *ScanHandlers {
 	return &ScanHandlers{
-		scanService: scanService,
-		eventBus:    bus,
+		scanService:   scanService,
+		eventBus:      bus,
+		updatesBroker: broker,
 	}
 }
 
@@ -42,18 +51,28 @@ func (s ScanHandlers) CreateScans(w http.ResponseWriter, req *http.Request) erro
 		}
 	}
 
-	var hostIDs []int
-	for _, strID := range scanRequest.HostIds {
-		intID, err := strconv.Atoi(strID)
-		if err != nil {
-			msg := fmt.Sprintf("invalid id: %s", strID)
+	hostIDs, err := parseHostIDs(scanRequest.HostIds)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	for _, groupID := range scanRequest.GroupIds {
+		if err := uuid.Validate(groupID); err != nil {
+			msg := fmt.Sprintf("invalid group id: %s", groupID)
 			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: msg})
 		}
-		hostIDs = append(hostIDs, intID)
+	}
+	selector := domain.HostSelector{HostIDs: hostIDs, GroupIDs: scanRequest.GroupIds, Tags: scanRequest.Tags}
+
+	tools, err := getScanTools(scanRequest.Tools, scanRequest.ToolOptions)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
 	}
 
-	scan, err := s.scanService.CreateScans(hostIDs)
+	scan, err := s.scanService.CreateScans(getActor(req), selector, tools)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidTool) || errors.Is(err, services.ErrNoHostsSelected) {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
 		if errors.Is(err, sql.ErrNoRows) {
 			statusCode := http.StatusNotFound
 			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
@@ -62,17 +81,218 @@ func (s ScanHandlers) CreateScans(w http.ResponseWriter, req *http.Request) erro
 		return api.WriteJSON(w, http.StatusInternalServerError, err.Error())
 	}
 
-	scanStartedPayload := &cmmn.ScanStartedEvent{
+	scanStartedBytes, err := json.Marshal(services.NewScanStartedEvent(scan))
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, err.Error())
+	}
+	if err := s.eventBus.Publish(string(enums.ScanStartedEventSubject), scanStartedBytes); err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, err.Error())
+	}
+
+	return api.WriteJSON(w, http.StatusCreated, scan)
+}
+
+func (s ScanHandlers) GetScanByID(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	scan, err := s.scanService.GetScanByID(id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			statusCode := http.StatusNotFound
+			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, scan)
+}
+
+func (s ScanHandlers) GetScans(w http.ResponseWriter, req *http.Request) error {
+	filter, err := getScanFilter(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	filter.TenantID = req.Context().Value(middleware.ContextTenantID).(string)
+
+	page, err := s.scanService.GetScans(*filter)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) || errors.Is(err, services.ErrInvalidDateRange) {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			statusCode := http.StatusNotFound
+			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, page)
+}
+
+func (s ScanHandlers) CancelScan(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	scan, err := s.scanService.CancelScan(getActor(req), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			statusCode := http.StatusNotFound
+			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+		}
+		if errors.Is(err, domain.ErrInvalidScanTransition) {
+			return api.WriteJSON(w, http.StatusConflict, api.APIError{Error: err.Error()})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	scanCancelledPayload := &cmmn.ScanCancelledEvent{
 		ScanID:    scan.ID,
-		Targets:   scan.Targets,
-		Timestamp: scan.CreatedAt.Unix(),
+		Timestamp: scan.UpdatedAt.Unix(),
+	}
+	scanCancelledBytes, err := json.Marshal(scanCancelledPayload)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+	if err := s.eventBus.Publish(string(enums.ScanCancelledEventSubject), scanCancelledBytes); err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+	if err := services.PublishScanUpdated(s.eventBus, scan); err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
 	}
-	scanStartedBytes, err := json.Marshal(scanStartedPayload)
-	s.eventBus.Publish(string(enums.ScanStartedEventSubject), scanStartedBytes)
 
+	return api.WriteJSON(w, http.StatusOK, scan)
+}
+
+func (s ScanHandlers) DiffScans(w http.ResponseWriter, req *http.Request) error {
+	query := req.URL.Query()
+	baseID, targetID := query.Get("base"), query.Get("target")
+
+	for _, id := range []string{baseID, targetID} {
+		if err := uuid.Validate(id); err != nil {
+			msg := fmt.Sprintf("invalid UUID: `%s`", id)
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: msg})
+		}
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	diff, err := s.scanService.DiffScans(baseID, targetID, tenantID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			statusCode := http.StatusNotFound
+			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
 
-		return api.WriteJSON(w, http.StatusInternalServerError, err.Error())
+	return api.WriteJSON(w, http.StatusOK, diff)
+}
+
+func (s ScanHandlers) GetScanReport(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
 	}
-	return api.WriteJSON(w, http.StatusCreated, scan)
+
+	format, err := services.ParseReportFormat(req.URL.Query().Get("format"))
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	report, err := s.scanService.GetScanReport(id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			statusCode := http.StatusNotFound
+			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	// Render before writing headers so failures can still be reported as JSON
+	var buf bytes.Buffer
+	if err := services.RenderScanReport(&buf, format, report); err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"scan-%s.%s\"", report.ScanID, format))
+	w.WriteHeader(http.StatusOK)
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// getScanTools pairs the selected tools with their options,
+// options are only accepted for selected tools
+func getScanTools(selected []enums.ServiceName, options map[enums.ServiceName]map[string]interface{}) ([]domain.ScanTool, error) {
+	tools := []domain.ScanTool{}
+	for _, service := range selected {
+		tools = append(tools, domain.ScanTool{
+			Service: service,
+			Options: options[service],
+		})
+	}
+
+	for service := range options {
+		if !slices.Contains(selected, service) {
+			return nil, fmt.Errorf("options given for tool `%s` which is not selected", service)
+		}
+	}
+
+	return tools, nil
+}
+
+// getScanFilter builds a ScanFilter from the `from`, `to`, `host_id`,
+// `status`, `cursor` and `limit` query parameters. Dates must be RFC3339.
+func getScanFilter(req *http.Request) (*domain.ScanFilter, error) {
+	query := req.URL.Query()
+	filter := &domain.ScanFilter{
+		Cursor: query.Get("cursor"),
+	}
+
+	if from := query.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from date: `%s`", from)
+		}
+		filter.From = &t
+	}
+	if to := query.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to date: `%s`", to)
+		}
+		filter.To = &t
+	}
+	if status := query.Get("status"); status != "" {
+		scanStatus, err := domain.ParseScanStatus(status)
+		if err != nil {
+			return nil, err
+		}
+		filter.Status = scanStatus
+	}
+	if hostID := query.Get("host_id"); hostID != "" {
+		intID, err := strconv.Atoi(hostID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid host_id: `%s`", hostID)
+		}
+		filter.HostID = intID
+	}
+	if limit := query.Get("limit"); limit != "" {
+		intLimit, err := strconv.Atoi(limit)
+		if err != nil || intLimit <= 0 {
+			return nil, fmt.Errorf("invalid limit: `%s`", limit)
+		}
+		filter.Limit = intLimit
+	}
+
+	return filter, nil
 }
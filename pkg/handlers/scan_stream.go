@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/api"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+const sseHeartbeatInterval = 15 * time.Second
+
+// StreamScanEvents streams the status of a scan as Server-Sent Events until
+// the scan finishes or the client disconnects. Every event carries the full
+// status of the scan and its id grows with each change, so a client that
+// reconnects with `Last-Event-ID` only receives the state if it changed.
+func (s ScanHandlers) StreamScanEvents(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	var lastEventID int64
+	if header := req.Header.Get("Last-Event-ID"); header != "" {
+		lastEventID, err = strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			msg := fmt.Sprintf("invalid Last-Event-ID: `%s`", header)
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: msg})
+		}
+	}
+
+	// Subscribe before reading the snapshot so no update falls in between
+	updates, unsubscribe := s.updatesBroker.Subscribe(id)
+	defer unsubscribe()
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	scan, err := s.scanService.GetScanByID(id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			statusCode := http.StatusNotFound
+			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event *domain.ScanUpdatedEvent) error {
+		if event.EventID() <= lastEventID {
+			return nil
+		}
+		if err := writeSSE(w, event); err != nil {
+			return err
+		}
+		lastEventID = event.EventID()
+		return rc.Flush()
+	}
+
+	if err := send(services.NewScanUpdatedEvent(scan)); err != nil {
+		log.Printf("Error streaming scan `%s`: `%s`", id, err.Error())
+		return nil
+	}
+	if scan.Status.IsTerminal() {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return nil
+		case event := <-updates:
+			if err := send(event); err != nil {
+				log.Printf("Error streaming scan `%s`: `%s`", id, err.Error())
+				return nil
+			}
+			if event.Status.IsTerminal() {
+				return nil
+			}
+		case <-heartbeat.C:
+			// Comments keep proxies from closing an idle connection
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			if err := rc.Flush(); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, event *domain.ScanUpdatedEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.EventID(), event.Status, data)
+	return err
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/kptm-tools/core-service/pkg/api"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/middleware"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+const defaultSchedulePreviewRuns = 5
+
+type ScheduleHandlers struct {
+	scheduleService interfaces.IScheduleService
+}
+
+var _ interfaces.IScheduleHandlers = (*ScheduleHandlers)(nil)
+
+func NewScheduleHandlers(scheduleService interfaces.IScheduleService) *ScheduleHandlers {
+	return &ScheduleHandlers{
+		scheduleService: scheduleService,
+	}
+}
+
+func (h *ScheduleHandlers) CreateSchedule(w http.ResponseWriter, req *http.Request) error {
+	scheduleRequest := new(ScheduleRequest)
+
+	if err := decodeJSONBody(w, req, scheduleRequest); err != nil {
+		var mr *malformedRequest
+
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+
+	schedule, err := constructScheduleForDB(scheduleRequest, req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	schedule, err = h.scheduleService.CreateSchedule(getActor(req), schedule)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSchedule) || errors.Is(err, services.ErrInvalidTool) {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusCreated, schedule)
+}
+
+func (h *ScheduleHandlers) GetSchedules(w http.ResponseWriter, req *http.Request) error {
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	schedules, err := h.scheduleService.GetSchedulesByTenantID(tenantID)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, schedules)
+}
+
+func (h *ScheduleHandlers) GetScheduleByID(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	schedule, err := h.scheduleService.GetScheduleByID(id, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			statusCode := http.StatusNotFound
+			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, schedule)
+}
+
+func (h *ScheduleHandlers) PatchScheduleByID(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+
+	scheduleRequest := new(PatchScheduleRequest)
+
+	if err := decodeJSONBody(w, req, scheduleRequest); err != nil {
+		var mr *malformedRequest
+
+		if errors.As(err, &mr) {
+			return api.WriteJSON(w, mr.status, api.APIError{Error: mr.Error()})
+		} else {
+			return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+		}
+	}
+
+	patch := domain.ScanSchedulePatch{
+		Name:            scheduleRequest.Name,
+		CronExpression:  scheduleRequest.CronExpression,
+		IntervalMinutes: scheduleRequest.IntervalMinutes,
+		Enabled:         scheduleRequest.Enabled,
+	}
+	if scheduleRequest.HostIds != nil {
+		hostIDs, err := parseHostIDs(*scheduleRequest.HostIds)
+		if err != nil {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
+		patch.HostIDs = &hostIDs
+	}
+	if scheduleRequest.Tools != nil {
+		tools, err := getScanTools(*scheduleRequest.Tools, scheduleRequest.ToolOptions)
+		if err != nil {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
+		patch.Tools = &tools
+	} else if scheduleRequest.ToolOptions != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: "tool_options can only be given with tools"})
+	}
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	schedule, err := h.scheduleService.PatchScheduleByID(getActor(req), id, tenantID, patch)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSchedule) || errors.Is(err, services.ErrInvalidTool) {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			statusCode := http.StatusNotFound
+			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, schedule)
+}
+
+func (h *ScheduleHandlers) DeleteScheduleByID(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	isDeleted, err := h.scheduleService.DeleteScheduleByID(getActor(req), id, tenantID)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	result := make(map[string]string)
+	if isDeleted {
+		result["deleted"] = "true"
+	} else {
+		result["deleted"] = "false"
+	}
+	return api.WriteJSON(w, http.StatusOK, result)
+}
+
+func (h *ScheduleHandlers) PreviewSchedule(w http.ResponseWriter, req *http.Request) error {
+	id, err := GetUUID(req)
+	if err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+	}
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+
+	count := defaultSchedulePreviewRuns
+	if strCount := req.URL.Query().Get("count"); strCount != "" {
+		count, err = strconv.Atoi(strCount)
+		if err != nil {
+			msg := fmt.Sprintf("invalid count: `%s`", strCount)
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: msg})
+		}
+	}
+
+	runs, err := h.scheduleService.PreviewSchedule(id, tenantID, count)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidSchedule) {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: err.Error()})
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			statusCode := http.StatusNotFound
+			return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+		}
+		return api.WriteJSON(w, http.StatusInternalServerError, api.APIError{Error: err.Error()})
+	}
+
+	return api.WriteJSON(w, http.StatusOK, SchedulePreviewResponse{NextRuns: runs})
+}
+
+func constructScheduleForDB(scheduleRequest *ScheduleRequest, req *http.Request) (*domain.ScanSchedule, error) {
+	hostIDs, err := parseHostIDs(scheduleRequest.HostIds)
+	if err != nil {
+		return nil, err
+	}
+
+	tools, err := getScanTools(scheduleRequest.Tools, scheduleRequest.ToolOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Schedules are enabled unless stated otherwise
+	enabled := true
+	if scheduleRequest.Enabled != nil {
+		enabled = *scheduleRequest.Enabled
+	}
+
+	tenantID := req.Context().Value(middleware.ContextTenantID).(string)
+	operatorID := req.Context().Value(middleware.ContextUserID).(string)
+
+	return domain.NewScanSchedule(tenantID, operatorID, scheduleRequest.Name, hostIDs, scheduleRequest.CronExpression, scheduleRequest.IntervalMinutes, tools, enabled), nil
+}
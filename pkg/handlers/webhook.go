@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/FusionAuth/go-client/pkg/fusionauth"
+	"github.com/kptm-tools/core-service/pkg/api"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+type WebhookHandlers struct {
+	userCache interfaces.IUserCache
+	secret    string
+}
+
+var _ interfaces.IWebhookHandlers = (*WebhookHandlers)(nil)
+
+// NewWebhookHandlers creates the webhook handlers, FusionAuth must send secret
+// in the Authorization header. Every webhook is rejected when secret is empty.
+func NewWebhookHandlers(userCache interfaces.IUserCache, secret string) *WebhookHandlers {
+	return &WebhookHandlers{
+		userCache: userCache,
+		secret:    secret,
+	}
+}
+
+// FusionAuthEvent drops deleted and deactivated users, and users whose
+// registration changed, from the user cache. Their tokens stop working and
+// their API keys lose revoked roles before the cache expires. Other events are
+// ignored.
+func (h *WebhookHandlers) FusionAuthEvent(w http.ResponseWriter, req *http.Request) error {
+	authorization := req.Header.Get("Authorization")
+	if h.secret == "" || subtle.ConstantTimeCompare([]byte(authorization), []byte(h.secret)) != 1 {
+		statusCode := http.StatusUnauthorized
+		return api.WriteJSON(w, statusCode, api.APIError{Error: http.StatusText(statusCode)})
+	}
+
+	// FusionAuth events have many more fields than the ones used here, so
+	// unknown fields are allowed unlike decodeJSONBody
+	eventRequest := new(FusionAuthEventRequest)
+	req.Body = http.MaxBytesReader(w, req.Body, 1048576)
+	if err := json.NewDecoder(req.Body).Decode(eventRequest); err != nil {
+		return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: "Request body contains badly-formed JSON"})
+	}
+
+	event := eventRequest.Event
+	switch event.Type {
+	case fusionauth.EventType_UserDelete, fusionauth.EventType_UserDeactivate,
+		fusionauth.EventType_UserRegistrationUpdate, fusionauth.EventType_UserRegistrationDelete:
+		if event.User.Id == "" {
+			return api.WriteJSON(w, http.StatusBadRequest, api.APIError{Error: "event has no user"})
+		}
+		h.userCache.InvalidateUser(event.User.Id)
+		log.Printf("Invalidated user `%s` on `%s` event", event.User.Id, event.Type)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
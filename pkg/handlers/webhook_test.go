@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// invalidatedUsers records the users dropped from the cache
+type invalidatedUsers struct {
+	userIDs []string
+}
+
+func (c *invalidatedUsers) UserExists(userID string, tenantID string) (bool, error) {
+	return true, nil
+}
+
+func (c *invalidatedUsers) InvalidateUser(userID string) {
+	c.userIDs = append(c.userIDs, userID)
+}
+
+func Test_FusionAuthEvent(t *testing.T) {
+	tests := []struct {
+		name            string
+		secret          string
+		authorization   string
+		body            string
+		expectedStatus  int
+		expectedUserIDs []string
+	}{
+		{
+			name:            "User deleted",
+			secret:          "s3cret",
+			authorization:   "s3cret",
+			body:            `{"event":{"type":"user.delete","tenantId":"t-1","user":{"id":"u-1","active":true}}}`,
+			expectedStatus:  http.StatusOK,
+			expectedUserIDs: []string{"u-1"},
+		},
+		{
+			name:            "User deactivated",
+			secret:          "s3cret",
+			authorization:   "s3cret",
+			body:            `{"event":{"type":"user.deactivate","user":{"id":"u-2"}}}`,
+			expectedStatus:  http.StatusOK,
+			expectedUserIDs: []string{"u-2"},
+		},
+		{
+			name:            "Registration updated",
+			secret:          "s3cret",
+			authorization:   "s3cret",
+			body:            `{"event":{"type":"user.registration.update","user":{"id":"u-4"}}}`,
+			expectedStatus:  http.StatusOK,
+			expectedUserIDs: []string{"u-4"},
+		},
+		{
+			name:           "Other events are ignored",
+			secret:         "s3cret",
+			authorization:  "s3cret",
+			body:           `{"event":{"type":"user.update","user":{"id":"u-3"}}}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Wrong secret",
+			secret:         "s3cret",
+			authorization:  "guess",
+			body:           `{"event":{"type":"user.delete","user":{"id":"u-1"}}}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Webhooks are rejected without a secret",
+			secret:         "",
+			authorization:  "",
+			body:           `{"event":{"type":"user.delete","user":{"id":"u-1"}}}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Event without user",
+			secret:         "s3cret",
+			authorization:  "s3cret",
+			body:           `{"event":{"type":"user.delete"}}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &invalidatedUsers{}
+			h := NewWebhookHandlers(cache, tt.secret)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/webhooks/fusionauth", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", tt.authorization)
+			w := httptest.NewRecorder()
+
+			if err := h.FusionAuthEvent(w, req); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if !reflect.DeepEqual(cache.userIDs, tt.expectedUserIDs) {
+				t.Errorf("expected invalidated users %v, got %v", tt.expectedUserIDs, cache.userIDs)
+			}
+		})
+	}
+}
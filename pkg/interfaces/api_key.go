@@ -0,0 +1,25 @@
+package interfaces
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+type IAPIKeyService interface {
+	CreateAPIKey(actor domain.Actor, tenantID string, name string, scopes []string, expiresAt *time.Time) (*domain.APIKey, string, error)
+	GetAPIKeysByTenantID(tenantID string) ([]*domain.APIKey, error)
+	GetAPIKeyByID(ID string, tenantID string) (*domain.APIKey, error)
+	PatchAPIKeyByID(actor domain.Actor, ID string, tenantID string, patch domain.APIKeyPatch) (*domain.APIKey, error)
+	DeleteAPIKeyByID(actor domain.Actor, ID string, tenantID string) (bool, error)
+	Authenticate(key string) (*domain.APIKey, error)
+}
+
+type IAPIKeyHandlers interface {
+	CreateAPIKey(w http.ResponseWriter, req *http.Request) error
+	GetAPIKeys(w http.ResponseWriter, req *http.Request) error
+	GetAPIKeyByID(w http.ResponseWriter, req *http.Request) error
+	PatchAPIKeyByID(w http.ResponseWriter, req *http.Request) error
+	DeleteAPIKeyByID(w http.ResponseWriter, req *http.Request) error
+}
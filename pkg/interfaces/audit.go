@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+type IAuditService interface {
+	GetAuditEvents(filter domain.AuditFilter) (*domain.AuditPage, error)
+	ExportAuditEvents(filter domain.AuditFilter, write func([]*domain.AuditEvent) error) error
+}
+
+type IAuditHandlers interface {
+	GetAuditEvents(w http.ResponseWriter, req *http.Request) error
+}
@@ -9,16 +9,26 @@ import (
 
 type IAuthService interface {
 	Login(email, password, applicationID string) (*fusionauth.LoginResponse, error)
-	RegisterTenant(tenantName string) (*domain.Tenant, *domain.User, error)
+	RefreshToken(refreshToken, token string) (*fusionauth.JWTRefreshResponse, error)
+	Logout(refreshToken string) error
+	RegisterTenant(actor domain.Actor, tenantName string) (*domain.Tenant, *domain.User, error)
 	GetUserByID(userID string, tenantID *string) (*domain.User, error)
 	ForgotPassword(email, applicationID string) (*fusionauth.ForgotPasswordResponse, error)
-	RegisterUser(firstname, lastname, email, password, applicationID string, roles []string) (*fusionauth.RegistrationResponse, error)
+	RegisterUser(actor domain.Actor, firstname, lastname, email, password, applicationID string, roles []string) (*fusionauth.RegistrationResponse, error)
 	ChangePassword(changePasswordID, password, email, applicationID string) (*fusionauth.ChangePasswordResponse, error)
 	VerifyEmail(verificationID, userID, tenantID string) (*fusionauth.BaseHTTPResponse, error)
 }
 
+// IUserCache answers if the user of a token still exists without asking FusionAuth every time
+type IUserCache interface {
+	UserExists(userID string, tenantID string) (bool, error)
+	InvalidateUser(userID string)
+}
+
 type IAuthHandlers interface {
 	Login(w http.ResponseWriter, req *http.Request) error
+	RefreshToken(w http.ResponseWriter, req *http.Request) error
+	Logout(w http.ResponseWriter, req *http.Request) error
 	RegisterTenant(w http.ResponseWriter, req *http.Request) error
 	GetUser(w http.ResponseWriter, req *http.Request) error
 	ForgotPassword(w http.ResponseWriter, req *http.Request) error
@@ -0,0 +1,33 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+type IGroupService interface {
+	CreateTag(actor domain.Actor, tag *domain.Tag) (*domain.Tag, error)
+	GetTagsByTenantID(tenantID string) ([]*domain.Tag, error)
+	PatchTagByID(actor domain.Actor, tag *domain.Tag) (*domain.Tag, error)
+	DeleteTagByID(actor domain.Actor, ID string, tenantID string) (bool, error)
+	SetHostTags(actor domain.Actor, hostID int, tenantID string, tags []string) ([]string, error)
+	CreateHostGroup(actor domain.Actor, group *domain.HostGroup) (*domain.HostGroup, error)
+	GetHostGroupsByTenantID(tenantID string) ([]*domain.HostGroup, error)
+	GetHostGroupByID(ID string, tenantID string) (*domain.HostGroup, error)
+	PatchHostGroupByID(actor domain.Actor, group *domain.HostGroup) (*domain.HostGroup, error)
+	DeleteHostGroupByID(actor domain.Actor, ID string, tenantID string) (bool, error)
+}
+
+type IGroupHandlers interface {
+	CreateTag(w http.ResponseWriter, req *http.Request) error
+	GetTags(w http.ResponseWriter, req *http.Request) error
+	PatchTagByID(w http.ResponseWriter, req *http.Request) error
+	DeleteTagByID(w http.ResponseWriter, req *http.Request) error
+	SetHostTags(w http.ResponseWriter, req *http.Request) error
+	CreateHostGroup(w http.ResponseWriter, req *http.Request) error
+	GetHostGroups(w http.ResponseWriter, req *http.Request) error
+	GetHostGroupByID(w http.ResponseWriter, req *http.Request) error
+	PatchHostGroupByID(w http.ResponseWriter, req *http.Request) error
+	DeleteHostGroupByID(w http.ResponseWriter, req *http.Request) error
+}
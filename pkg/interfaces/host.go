@@ -2,26 +2,35 @@ package interfaces
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/kptm-tools/core-service/pkg/domain"
 )
 
 type IHostService interface {
-	CreateHost(*domain.Host) (*domain.Host, error)
-	GetHostsByTenantIDAndUserID(tenantID string, userID string) ([]*domain.Host, error)
-	GetHostByID(ID int) (*domain.Host, error)
+	CreateHost(actor domain.Actor, host *domain.Host) (*domain.Host, error)
+	ImportHosts(actor domain.Actor, tenantID string, operatorID string, rows []domain.HostImportRow) (*domain.HostImportReport, error)
+	GetHosts(filter domain.HostFilter, userID string, roles []domain.Role) (*domain.HostPage, error)
+	TransferHost(actor domain.Actor, ID int, tenantID string, operatorID string) (*domain.Host, error)
+	GetHostByID(ID int, tenantID string, userID string, roles []domain.Role) (*domain.Host, error)
 	GetHostname(string) string
-	DeleteHostByID(ID int) (bool, error)
-	PatchHostByID(*domain.Host) (*domain.Host, error)
+	DeleteHostByID(actor domain.Actor, ID int, tenantID string) (bool, error)
+	RestoreHost(actor domain.Actor, ID int, tenantID string) (*domain.Host, error)
+	PurgeDeletedHosts(retention time.Duration) (int64, error)
+	PatchHostByID(actor domain.Actor, host *domain.Host) (*domain.Host, error)
 	ValidateHost(string) error
 	ValidateAlias(string) error
+	RotateCredentials(legacyPassphrase string) (int, error)
 }
 
 type IHostHandlers interface {
 	CreateHost(w http.ResponseWriter, req *http.Request) error
+	ImportHosts(w http.ResponseWriter, req *http.Request) error
 	GetHostsByTenantIDAndUserID(w http.ResponseWriter, req *http.Request) error
 	GetHostByID(w http.ResponseWriter, req *http.Request) error
 	DeleteHostByID(w http.ResponseWriter, req *http.Request) error
+	RestoreHost(w http.ResponseWriter, req *http.Request) error
 	PatchHostByID(w http.ResponseWriter, req *http.Request) error
+	TransferHost(w http.ResponseWriter, req *http.Request) error
 	ValidateHost(w http.ResponseWriter, req *http.Request) error
 }
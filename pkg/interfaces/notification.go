@@ -0,0 +1,32 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+// INotifier delivers a single message to its recipient
+type INotifier interface {
+	Send(*domain.NotificationMessage) error
+}
+
+type INotificationService interface {
+	NotifyScanFinished(scanID string, tenantID string) ([]*domain.Notification, error)
+	RetryFailedNotifications() ([]*domain.Notification, error)
+	GetNotificationsByScanID(scanID string, tenantID string) ([]*domain.Notification, error)
+	GetNotificationTemplate(tenantID string) (*domain.NotificationTemplate, error)
+	UpdateNotificationTemplate(actor domain.Actor, template *domain.NotificationTemplate) (*domain.NotificationTemplate, error)
+	ResetNotificationTemplate(actor domain.Actor, tenantID string) (*domain.NotificationTemplate, error)
+}
+
+type INotificationHandlers interface {
+	GetScanNotifications(w http.ResponseWriter, req *http.Request) error
+	GetNotificationTemplate(w http.ResponseWriter, req *http.Request) error
+	UpdateNotificationTemplate(w http.ResponseWriter, req *http.Request) error
+	ResetNotificationTemplate(w http.ResponseWriter, req *http.Request) error
+}
+
+type INotificationSubscribers interface {
+	Init() error
+}
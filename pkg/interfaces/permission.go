@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"net/http"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+type IPermissionService interface {
+	Authorize(tenantID string, roles []domain.Role, required []domain.Permission) error
+	GetRolePermissions(tenantID string) ([]*domain.RolePermissions, error)
+	SetRolePermissions(actor domain.Actor, tenantID string, role domain.Role, permissions []string) (*domain.RolePermissions, error)
+	ResetRolePermissions(actor domain.Actor, tenantID string, role domain.Role) (*domain.RolePermissions, error)
+}
+
+type IPermissionHandlers interface {
+	GetRolePermissions(w http.ResponseWriter, req *http.Request) error
+	SetRolePermissions(w http.ResponseWriter, req *http.Request) error
+	ResetRolePermissions(w http.ResponseWriter, req *http.Request) error
+}
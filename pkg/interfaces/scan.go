@@ -1,14 +1,45 @@
 package interfaces
 
 import (
-	"github.com/kptm-tools/core-service/pkg/domain"
 	"net/http"
+	"time"
+
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/common/common/results"
+	"github.com/kptm-tools/core-service/pkg/domain"
 )
 
 type IScanService interface {
-	CreateScans(hostIDs []int) (*domain.Scan, error)
+	CreateScans(actor domain.Actor, selector domain.HostSelector, tools []domain.ScanTool) (*domain.Scan, error)
+	GetScanByID(ID string, tenantID string) (*domain.Scan, error)
+	GetScans(filter domain.ScanFilter) (*domain.ScanPage, error)
+	UpdateServiceProgress(scanID string, service enums.ServiceName, target string, progress string) (*domain.Scan, error)
+	SaveServiceResults(scanID string, service enums.ServiceName, targetResults []results.TargetResult, eventErr *events.EventError) (*domain.Scan, error)
+	CancelScan(actor domain.Actor, ID string) (*domain.Scan, error)
+	TimeoutScans(timeout time.Duration) ([]*domain.Scan, error)
+	HasActiveScan(hostIDs []int, tenantID string) (bool, error)
+	DiffScans(baseID string, targetID string, tenantID string) (*domain.ScanDiff, error)
+	GetScanReport(ID string, tenantID string) (*domain.ScanReport, error)
 }
 
 type IScanHandlers interface {
 	CreateScans(writer http.ResponseWriter, request *http.Request) error
+	GetScanByID(writer http.ResponseWriter, request *http.Request) error
+	GetScans(writer http.ResponseWriter, request *http.Request) error
+	CancelScan(writer http.ResponseWriter, request *http.Request) error
+	StreamScanEvents(writer http.ResponseWriter, request *http.Request) error
+	DiffScans(writer http.ResponseWriter, request *http.Request) error
+	GetScanReport(writer http.ResponseWriter, request *http.Request) error
+}
+
+type IScanSubscribers interface {
+	Init() error
+}
+
+// IScanUpdatesBroker fans out scan updates received from the event bus
+// to the listeners of each scan
+type IScanUpdatesBroker interface {
+	Init() error
+	Subscribe(scanID string) (<-chan *domain.ScanUpdatedEvent, func())
 }
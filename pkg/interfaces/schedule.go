@@ -0,0 +1,28 @@
+package interfaces
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+type IScheduleService interface {
+	CreateSchedule(actor domain.Actor, schedule *domain.ScanSchedule) (*domain.ScanSchedule, error)
+	GetSchedulesByTenantID(tenantID string) ([]*domain.ScanSchedule, error)
+	GetScheduleByID(ID string, tenantID string) (*domain.ScanSchedule, error)
+	PatchScheduleByID(actor domain.Actor, ID string, tenantID string, patch domain.ScanSchedulePatch) (*domain.ScanSchedule, error)
+	DeleteScheduleByID(actor domain.Actor, ID string, tenantID string) (bool, error)
+	PreviewSchedule(ID string, tenantID string, count int) ([]time.Time, error)
+	GetDueSchedules(now time.Time) ([]*domain.ScanSchedule, error)
+	MarkScheduleRun(schedule *domain.ScanSchedule, runAt time.Time, scanID string) error
+}
+
+type IScheduleHandlers interface {
+	CreateSchedule(w http.ResponseWriter, req *http.Request) error
+	GetSchedules(w http.ResponseWriter, req *http.Request) error
+	GetScheduleByID(w http.ResponseWriter, req *http.Request) error
+	PatchScheduleByID(w http.ResponseWriter, req *http.Request) error
+	DeleteScheduleByID(w http.ResponseWriter, req *http.Request) error
+	PreviewSchedule(w http.ResponseWriter, req *http.Request) error
+}
@@ -1,18 +1,71 @@
 package interfaces
 
 import (
+	"time"
+
 	"github.com/kptm-tools/core-service/pkg/domain"
 )
 
 type IStorage interface {
 	CreateHost(*domain.Host) (*domain.Host, error)
-	GetHostsByTenantIDAndUserID(string, string) ([]*domain.Host, error)
-	GetHostByID(int) (*domain.Host, error)
-	DeleteHostByID(int) (bool, error)
+	CreateHosts(tenantID string, hosts []*domain.Host) ([]*domain.Host, error)
+	GetHosts(filter domain.HostFilter) (*domain.HostPage, error)
+	GetHostByID(ID int, tenantID string) (*domain.Host, error)
+	RotateCredentials(legacyPassphrase string, reseal func(*domain.Credential) error) (int, error)
+	GetHostsByAliases(tenantID string, aliases []string) ([]*domain.Host, error)
+	DeleteHostByID(ID int, tenantID string) (bool, error)
+	RestoreHostByID(ID int, tenantID string) (*domain.Host, error)
+	PurgeDeletedHosts(deletedBefore time.Time) (int64, error)
+	TransferHostByID(ID int, tenantID string, operatorID string) (*domain.Host, error)
 	PatchHostByID(*domain.Host) (*domain.Host, error)
+	SetHostTags(hostID int, tenantID string, tags []string) ([]string, error)
+	CreateTag(*domain.Tag) (*domain.Tag, error)
+	GetTagsByTenantID(tenantID string) ([]*domain.Tag, error)
+	GetTagByID(ID string, tenantID string) (*domain.Tag, error)
+	PatchTagByID(*domain.Tag) (*domain.Tag, error)
+	DeleteTagByID(ID string, tenantID string) (bool, error)
+	ExistsTagName(tenantID string, name string, exceptID string) (bool, error)
+	CreateHostGroup(*domain.HostGroup) (*domain.HostGroup, error)
+	GetHostGroupsByTenantID(tenantID string) ([]*domain.HostGroup, error)
+	GetHostGroupByID(ID string, tenantID string) (*domain.HostGroup, error)
+	PatchHostGroupByID(*domain.HostGroup) (*domain.HostGroup, error)
+	DeleteHostGroupByID(ID string, tenantID string) (bool, error)
+	ExistsHostGroupName(tenantID string, name string, exceptID string) (bool, error)
+	GetHostIDsBySelector(tenantID string, groupIDs []string, tags []string) ([]int, error)
 	CreateTenant(*domain.Tenant) (*domain.Tenant, error)
 	GetTenants() ([]*domain.Tenant, error)
 	Ping() error
 	CreateScan(*domain.Scan) (*domain.Scan, error)
+	GetScanByID(ID string, tenantID string) (*domain.Scan, error)
+	GetScans(domain.ScanFilter) (*domain.ScanPage, error)
+	UpdateScanByID(string, func(*domain.Scan) error) (*domain.Scan, error)
+	GetScansByStatus([]domain.ScanStatus, time.Time) ([]*domain.Scan, error)
 	ExistAlias(string) (bool, error)
+	ExistsActiveScanForHosts(tenantID string, aliases []string) (bool, error)
+	CreateSchedule(*domain.ScanSchedule) (*domain.ScanSchedule, error)
+	GetSchedulesByTenantID(string) ([]*domain.ScanSchedule, error)
+	GetScheduleByID(ID string, tenantID string) (*domain.ScanSchedule, error)
+	PatchScheduleByID(*domain.ScanSchedule) (*domain.ScanSchedule, error)
+	DeleteScheduleByID(ID string, tenantID string) (bool, error)
+	GetDueSchedules(time.Time) ([]*domain.ScanSchedule, error)
+	MarkScheduleRun(ID string, runAt time.Time, nextRunAt *time.Time, scanID string) error
+	ClaimNotification(n *domain.Notification, maxAttempts int) (bool, error)
+	UpdateNotificationStatus(*domain.Notification) error
+	GetRetryableNotifications(maxAttempts int) ([]*domain.Notification, error)
+	GetNotificationsByScanID(scanID string, tenantID string) ([]*domain.Notification, error)
+	GetNotificationTemplate(tenantID string) (*domain.NotificationTemplate, error)
+	UpsertNotificationTemplate(*domain.NotificationTemplate) (*domain.NotificationTemplate, error)
+	DeleteNotificationTemplate(tenantID string) (bool, error)
+	CreateAuditEvent(*domain.AuditEvent) error
+	GetAuditEvents(domain.AuditFilter) (*domain.AuditPage, error)
+	GetRolePermissions(tenantID string) ([]*domain.RolePermissions, error)
+	UpsertRolePermissions(*domain.RolePermissions) (*domain.RolePermissions, error)
+	DeleteRolePermissions(tenantID string, role domain.Role) (bool, error)
+	CreateAPIKey(*domain.APIKey) (*domain.APIKey, error)
+	GetAPIKeysByTenantID(tenantID string) ([]*domain.APIKey, error)
+	GetAPIKeyByID(ID string, tenantID string) (*domain.APIKey, error)
+	GetAPIKeyByHash(hash string) (*domain.APIKey, error)
+	PatchAPIKeyByID(*domain.APIKey) (*domain.APIKey, error)
+	DeleteAPIKeyByID(ID string, tenantID string) (bool, error)
+	TouchAPIKey(ID string, usedAt time.Time) error
 }
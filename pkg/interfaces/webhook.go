@@ -0,0 +1,7 @@
+package interfaces
+
+import "net/http"
+
+type IWebhookHandlers interface {
+	FusionAuthEvent(w http.ResponseWriter, req *http.Request) error
+}
@@ -0,0 +1,127 @@
+// Package keyring encrypts secrets with AES-256-GCM keys identified by an ID,
+// so ciphertexts can be stored next to the ID of the key that sealed them
+// and keys can be rotated without losing access to older ciphertexts.
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const keySize = 32
+
+var (
+	ErrInvalidKey = errors.New("invalid key")
+	ErrUnknownKey = errors.New("unknown key")
+	ErrDecrypt    = errors.New("failed to decrypt")
+)
+
+type Keyring struct {
+	keys     map[string][]byte
+	activeID string
+}
+
+// New returns a keyring that encrypts with the key activeID
+func New(keys map[string][]byte, activeID string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%q: %w", "no keys configured", ErrInvalidKey)
+	}
+	for id, key := range keys {
+		if id == "" || strings.ContainsAny(id, ":,") {
+			return nil, fmt.Errorf("%q: %w", "invalid key ID", ErrInvalidKey)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key `%s` must be %d bytes: %w", id, keySize, ErrInvalidKey)
+		}
+	}
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("active key `%s`: %w", activeID, ErrUnknownKey)
+	}
+
+	return &Keyring{keys: keys, activeID: activeID}, nil
+}
+
+// Parse builds a keyring from a `id:base64key,id:base64key` list
+func Parse(spec string, activeID string) (*Keyring, error) {
+	keys := map[string][]byte{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, found := strings.Cut(entry, ":")
+		if !found {
+			return nil, fmt.Errorf("%q: %w", "expected id:base64key", ErrInvalidKey)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key `%s` is not base64: %w", id, ErrInvalidKey)
+		}
+		if _, ok := keys[id]; ok {
+			return nil, fmt.Errorf("duplicated key `%s`: %w", id, ErrInvalidKey)
+		}
+		keys[id] = key
+	}
+
+	return New(keys, activeID)
+}
+
+// ActiveKeyID returns the ID of the key new secrets are encrypted with
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeID
+}
+
+// Encrypt seals plaintext with the active key, it returns the key ID and
+// the base64 encoded nonce and ciphertext
+func (k *Keyring) Encrypt(plaintext string) (string, string, error) {
+	gcm, err := k.cipher(k.activeID)
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), []byte(k.activeID))
+
+	return k.activeID, base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a ciphertext produced by Encrypt with the key keyID
+func (k *Keyring) Decrypt(keyID string, ciphertext string) (string, error) {
+	gcm, err := k.cipher(keyID)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", ErrDecrypt
+	}
+	nonce, sealed := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, sealed, []byte(keyID))
+	if err != nil {
+		return "", ErrDecrypt
+	}
+
+	return string(plaintext), nil
+}
+
+func (k *Keyring) cipher(keyID string) (cipher.AEAD, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key `%s`: %w", keyID, ErrUnknownKey)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
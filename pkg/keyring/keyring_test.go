@@ -0,0 +1,91 @@
+package keyring
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, keySize))
+}
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		activeID    string
+		expectedErr error
+	}{
+		{name: "Single key", spec: "k1:" + testKey(1), activeID: "k1"},
+		{name: "Several keys", spec: "k1:" + testKey(1) + ", k2:" + testKey(2), activeID: "k2"},
+		{name: "No keys", spec: "", activeID: "k1", expectedErr: ErrInvalidKey},
+		{name: "Missing ID", spec: testKey(1), activeID: "k1", expectedErr: ErrInvalidKey},
+		{name: "Not base64", spec: "k1:not-base64!", activeID: "k1", expectedErr: ErrInvalidKey},
+		{name: "Short key", spec: "k1:" + base64.StdEncoding.EncodeToString([]byte("short")), activeID: "k1", expectedErr: ErrInvalidKey},
+		{name: "Duplicated key", spec: "k1:" + testKey(1) + ",k1:" + testKey(2), activeID: "k1", expectedErr: ErrInvalidKey},
+		{name: "Unknown active key", spec: "k1:" + testKey(1), activeID: "k2", expectedErr: ErrUnknownKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.spec, tt.activeID)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Expected `%v`, got `%v`", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func Test_EncryptDecrypt(t *testing.T) {
+	old, err := Parse("k1:"+testKey(1), "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := Parse("k1:"+testKey(1)+",k2:"+testKey(2), "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldKeyID, oldCiphertext, err := old.Encrypt("s3cr3t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKeyID, newCiphertext, err := rotated.Encrypt("s3cr3t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, _ := base64.StdEncoding.DecodeString(oldCiphertext)
+	sealed[len(sealed)-1] ^= 0xff
+	tampered := base64.StdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name        string
+		keyring     *Keyring
+		keyID       string
+		ciphertext  string
+		expected    string
+		expectedErr error
+	}{
+		{name: "Same keyring", keyring: old, keyID: oldKeyID, ciphertext: oldCiphertext, expected: "s3cr3t"},
+		{name: "Old key after rotation", keyring: rotated, keyID: oldKeyID, ciphertext: oldCiphertext, expected: "s3cr3t"},
+		{name: "New key", keyring: rotated, keyID: newKeyID, ciphertext: newCiphertext, expected: "s3cr3t"},
+		{name: "Key unknown to old keyring", keyring: old, keyID: newKeyID, ciphertext: newCiphertext, expectedErr: ErrUnknownKey},
+		{name: "Wrong key ID", keyring: rotated, keyID: "k1", ciphertext: newCiphertext, expectedErr: ErrDecrypt},
+		{name: "Tampered ciphertext", keyring: old, keyID: oldKeyID, ciphertext: tampered, expectedErr: ErrDecrypt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.keyring.Decrypt(tt.keyID, tt.ciphertext)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Expected error `%v`, got `%v`", tt.expectedErr, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected `%v`, got `%v`", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+// ContextAPIKeyID holds the ID of the API key of the request, requests made
+// with a token don't have it
+const ContextAPIKeyID ContextKey = "apiKeyID"
+
+// apiKeys authenticates the requests made with an API key, main sets it
+// through SetAPIKeys
+var (
+	apiKeys   interfaces.IAPIKeyService
+	apiKeysMu sync.Mutex
+)
+
+// SetAPIKeys replaces the service that authenticates API keys
+func SetAPIKeys(k interfaces.IAPIKeyService) {
+	apiKeysMu.Lock()
+	defer apiKeysMu.Unlock()
+	apiKeys = k
+}
+
+func getAPIKeys() (interfaces.IAPIKeyService, error) {
+	apiKeysMu.Lock()
+	defer apiKeysMu.Unlock()
+	if apiKeys == nil {
+		return nil, errors.New("api keys are not set")
+	}
+	return apiKeys, nil
+}
+
+// getRequestAPIKey gets the API key of the request from the X-API-Key header,
+// or from the Authorization header when it holds a key instead of a JWT
+func getRequestAPIKey(r *http.Request) (string, bool) {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key, true
+	}
+
+	key, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if ok && domain.IsAPIKey(key) {
+		return key, true
+	}
+	return "", false
+}
+
+// withAPIKey serves the request made with secret when the user who created the
+// key is still active and the scopes of the key that the user still holds have
+// every permission in permissions. The request acts as that user, with those
+// scopes as its roles.
+func withAPIKey(endpoint http.HandlerFunc, w http.ResponseWriter, r *http.Request, secret string, permissions []domain.Permission) {
+	keys, err := getAPIKeys()
+	if err != nil {
+		log.Println("General error: ", err.Error())
+		WriteInternalServerError(w)
+		return
+	}
+
+	key, err := keys.Authenticate(secret)
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyRejected) {
+			log.Println(err.Error())
+			WriteUnauthorized(w)
+			return
+		}
+		log.Println("General error: ", err.Error())
+		WriteInternalServerError(w)
+		return
+	}
+
+	// Keys stop working along with the user who created them, and lose
+	// the scopes the user no longer has
+	scopes, err := getCreatorScopes(key)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrInvalidToken) {
+			log.Println(err.Error())
+			WriteUnauthorized(w)
+			return
+		}
+		log.Println("General error: ", err.Error())
+		WriteInternalServerError(w)
+		return
+	}
+
+	if err := checkRolePermissions(key.TenantID, scopes, permissions); err != nil {
+		if errors.Is(err, services.ErrPermissionDenied) {
+			log.Println(err.Error())
+			WriteForbidden(w)
+			return
+		}
+		log.Println("General error: ", err.Error())
+		WriteInternalServerError(w)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), ContextTenantID, key.TenantID)
+	ctx = context.WithValue(ctx, ContextUserID, key.CreatedBy)
+	ctx = context.WithValue(ctx, ContextRoles, scopes)
+	ctx = context.WithValue(ctx, ContextAPIKeyID, key.ID)
+	endpoint(w, r.WithContext(ctx))
+}
+
+// getCreatorScopes returns the scopes of the key that its creator still has.
+// It returns ErrUserNotFound when the creator is gone and ErrInvalidToken when
+// none of the scopes are left.
+func getCreatorScopes(key *domain.APIKey) ([]domain.Role, error) {
+	users, err := getUserCache()
+	if err != nil {
+		return nil, err
+	}
+	roles, exists, err := users.UserRoles(key.CreatedBy, key.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		msg := fmt.Sprintf("User `%s` is not registered", key.CreatedBy)
+		return nil, fmt.Errorf("%q: %w", msg, ErrUserNotFound)
+	}
+
+	scopes := []domain.Role{}
+	for _, scope := range key.Scopes {
+		if slices.Contains(roles, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		msg := fmt.Sprintf("Api key `%s` has no scopes its creator holds", key.ID)
+		return nil, fmt.Errorf("%q: %w", msg, ErrInvalidToken)
+	}
+	return scopes, nil
+}
@@ -2,20 +2,15 @@ package middleware
 
 import (
 	"context"
-	"crypto/rsa"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v4"
-	"github.com/kptm-tools/core-service/pkg/config"
 	"github.com/kptm-tools/core-service/pkg/domain"
 	"github.com/kptm-tools/core-service/pkg/services"
-	"github.com/kptm-tools/core-service/pkg/storage"
 )
 
 var ErrInvalidToken = errors.New("invalid token")
@@ -24,25 +19,45 @@ var ErrNoToken = errors.New("token not found")
 
 var ErrUserNotFound = errors.New("user not found")
 
-var verifyKey *rsa.PublicKey
-
 type ContextKey string
 
 const ContextTenantID ContextKey = "tenantID"
 const ContextUserID ContextKey = "userID"
 
+// ContextRoles holds the []domain.Role of the token
+const ContextRoles ContextKey = "roles"
+
+// AccessTokenCookie and RefreshTokenCookie hold the tokens of browser
+// sessions, login sets them
+const (
+	AccessTokenCookie  = "app.at"
+	RefreshTokenCookie = "app.rt"
+)
+
 func WriteUnauthorized(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusUnauthorized)
 	w.Write([]byte(http.StatusText(http.StatusUnauthorized)))
 }
 
+func WriteForbidden(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte(http.StatusText(http.StatusForbidden)))
+}
+
 func WriteInternalServerError(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusInternalServerError)
 	w.Write([]byte(http.StatusText(http.StatusInternalServerError)))
 }
 
-func WithAuth(endpoint http.HandlerFunc, functionName string) http.HandlerFunc {
+// WithAuth lets through requests with a valid token or API key whose roles
+// have every permission in permissions
+func WithAuth(endpoint http.HandlerFunc, permissions ...domain.Permission) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, ok := getRequestAPIKey(r); ok {
+			withAPIKey(endpoint, w, r, key, permissions)
+			return
+		}
+
 		token, err := parseToken(r)
 		if err != nil {
 			if errors.Is(err, ErrInvalidToken) {
@@ -74,8 +89,7 @@ func WithAuth(endpoint http.HandlerFunc, functionName string) http.HandlerFunc {
 		var tenantID = token.Claims.(jwt.MapClaims)["tid"]
 		var userID = token.Claims.(jwt.MapClaims)["sub"]
 
-		exists, err := validateUserWithFusionAuth(userID.(string), tenantID.(string))
-		if err != nil {
+		if err := checkUserExists(userID.(string), tenantID.(string)); err != nil {
 			if errors.Is(err, ErrUserNotFound) {
 				log.Println(err.Error())
 				WriteUnauthorized(w)
@@ -86,19 +100,19 @@ func WithAuth(endpoint http.HandlerFunc, functionName string) http.HandlerFunc {
 				return
 			}
 		}
-		if !exists {
-			log.Printf("User is not registered\n")
-			WriteUnauthorized(w)
-			return
-		}
 
-		// Verify user roles
-		if err := checkTokenRoles(token, functionName); err != nil {
+		// Verify user permissions
+		if err := checkTokenPermissions(token, tenantID.(string), permissions); err != nil {
 			if errors.Is(err, ErrInvalidToken) {
 				log.Println(err.Error())
 				WriteUnauthorized(w)
 				return
 			}
+			if errors.Is(err, services.ErrPermissionDenied) {
+				log.Println(err.Error())
+				WriteForbidden(w)
+				return
+			}
 			log.Println("General error: ", err.Error())
 			WriteInternalServerError(w)
 			return
@@ -106,11 +120,29 @@ func WithAuth(endpoint http.HandlerFunc, functionName string) http.HandlerFunc {
 
 		ctx := context.WithValue(r.Context(), ContextTenantID, tenantID)
 		ctx = context.WithValue(ctx, ContextUserID, userID)
+		ctx = context.WithValue(ctx, ContextRoles, getTokenRoles(token))
 		endpoint(w, r.WithContext(ctx))
 
 	})
 }
 
+// checkUserExists returns an ErrUserNotFound unless userID is an active user of tenantID
+func checkUserExists(userID string, tenantID string) error {
+	users, err := getUserCache()
+	if err != nil {
+		return err
+	}
+	exists, err := users.UserExists(userID, tenantID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		msg := fmt.Sprintf("User `%s` is not registered", userID)
+		return fmt.Errorf("%q: %w", msg, ErrUserNotFound)
+	}
+	return nil
+}
+
 func parseToken(r *http.Request) (*jwt.Token, error) {
 	reqToken, err := getRequestToken(r)
 	if err != nil {
@@ -135,8 +167,9 @@ func verifyTokenSignature(token *jwt.Token) (interface{}, error) {
 
 	// At this point we already validated we have a KID
 	kid := token.Header["kid"].(string)
-	if err := setPublicKey(kid); err != nil {
-		return nil, fmt.Errorf("error setting public key: %w", err)
+	verifyKey, err := getKeySet().Key(kid)
+	if err != nil {
+		return nil, fmt.Errorf("error getting public key: %w", err)
 	}
 	return verifyKey, nil
 }
@@ -208,7 +241,7 @@ func validateUserAndTenant(claims jwt.MapClaims) error {
 // the cookie or the header. Returns a [ErrNoToken] on failure
 func getRequestToken(r *http.Request) (string, error) {
 	reqToken := ""
-	tokenCookie, err := r.Cookie("app.at")
+	tokenCookie, err := r.Cookie(AccessTokenCookie)
 
 	// If token was not in cookie
 	if err != nil {
@@ -239,89 +272,38 @@ func getRequestToken(r *http.Request) (string, error) {
 	return reqToken, nil
 }
 
-func checkTokenRoles(token *jwt.Token, functionName string) error {
-	var roles = token.Claims.(jwt.MapClaims)["roles"]
-	// Check if we have any roles in our claims
-	if len(roles.([]interface{})) == 0 {
-		msg := "Token has no roles"
+// checkTokenPermissions makes sure the roles of the token, taken together,
+// have every required permission in the tenant
+func checkTokenPermissions(token *jwt.Token, tenantID string, required []domain.Permission) error {
+	roles := getTokenRoles(token)
+	if len(roles) == 0 {
+		msg := "Token has no known roles"
 		return fmt.Errorf("%q: %w", msg, ErrInvalidToken)
 	}
 
-	parsedRoles, err := domain.GetRolesFromStringSlice([]string{roles.([]interface{})[0].(string)})
-	if err != nil {
-		msg := fmt.Sprintf("Invalid Role: `%s`", err.Error())
-		return fmt.Errorf("%q: %w", msg, ErrInvalidToken)
-	}
-
-	// Check out what page we're calling, so we can check relevant roles
-	validRoles, err := domain.GetValidRoles(functionName)
-	if err != nil {
-		msg := fmt.Sprintf("Invalid Role: `%v`, must be one of `%v`", parsedRoles, validRoles)
-		return fmt.Errorf("%q: %w", msg, ErrInvalidToken)
-	}
-
-	result := domain.ContainsRole(parsedRoles, validRoles)
-	// If the length of the intersection is >= 1 , we have the proper role
-	// log.Printf("Intersection result: `%v`\n", result)
-	if len(result) == 0 {
-		msg := fmt.Sprintf("Roles missing: Have `%v`, want one of `%v`", parsedRoles, validRoles)
-		return fmt.Errorf("%q: %w", msg, ErrInvalidToken)
-	}
-
-	return nil
+	return checkRolePermissions(tenantID, roles, required)
 }
 
-func setPublicKey(kid string) error {
-	c := config.LoadConfig()
-	// Retrieves the public key for JWT from FusionAuth
-	if verifyKey == nil {
-		url := fmt.Sprintf("http://%s:%s/api/jwt/public-key?kid=%s", c.FusionAuthHost, c.FusionAuthPort, kid)
-		response, err := http.Get(url)
-		if err != nil {
-			return fmt.Errorf("problem connecting to FusionAuth: `%s`", err.Error())
-		}
-
-		responseData, err := io.ReadAll(response.Body)
-		if err != nil {
-			return fmt.Errorf("problem reading FusionAuth response: `%s`", err.Error())
-		}
-
-		var publicKey map[string]interface{}
-
-		if err = json.Unmarshal(responseData, &publicKey); err != nil {
-			return fmt.Errorf("problem unmarshaling response: `%s`", err.Error())
-		}
-
-		var publicKeyPEM = publicKey["publicKey"].(string)
-
-		var verifyBytes = []byte(publicKeyPEM)
-		verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(verifyBytes)
-
-		if err != nil {
-			return fmt.Errorf("problem retreiving public key: `%s`", err.Error())
-		}
+// checkRolePermissions makes sure the roles have every required permission in the tenant
+func checkRolePermissions(tenantID string, roles []domain.Role, required []domain.Permission) error {
+	// Routes are closed unless they declare their permissions
+	if len(required) == 0 {
+		msg := "Route requires no permission"
+		return fmt.Errorf("%q: %w", msg, services.ErrPermissionDenied)
 	}
-	return nil
-}
 
-func validateUserWithFusionAuth(userID, tenantID string) (bool, error) {
-	c := config.LoadConfig()
-	storage, _ := storage.NewPostgreSQLStore(c.PostgreSQLCoreConnStr())
-	authService := services.NewAuthService(storage)
-
-	_, err := authService.GetUserByID(userID, &tenantID)
-	if err != nil {
-		var faErr *services.FaError
-		if errors.As(err, &faErr) {
-			msg := faErr.Error()
-			log.Printf("FusionAuth error fetching user: `%s`", msg)
-			return false, fmt.Errorf("%q: %w", msg, ErrUserNotFound)
+	return authorize(tenantID, roles, required)
+}
 
-		} else {
-			log.Printf("Error fetching user: `%s`\n", err.Error())
-			return false, err
+// getTokenRoles returns every known role of the token, unknown roles are skipped
+func getTokenRoles(token *jwt.Token) []domain.Role {
+	roles := []domain.Role{}
+	claimRoles, _ := token.Claims.(jwt.MapClaims)["roles"].([]interface{})
+	for _, claimRole := range claimRoles {
+		s, _ := claimRole.(string)
+		if role, err := domain.ParseRole(s); err == nil {
+			roles = append(roles, role)
 		}
 	}
-
-	return true, nil
+	return roles
 }
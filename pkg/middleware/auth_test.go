@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/services"
 )
 
 func Test_getRequestToken(t *testing.T) {
@@ -62,36 +64,68 @@ func Test_getRequestToken(t *testing.T) {
 	}
 }
 
-func Test_checkTokenRoles(t *testing.T) {
+func Test_checkTokenPermissions(t *testing.T) {
 	tests := []struct {
-		name         string
-		tokenClaims  jwt.MapClaims
-		functionName string
-		wantErr      error
+		name        string
+		tokenClaims jwt.MapClaims
+		permissions []domain.Permission
+		wantErr     error
 	}{
 		{
 			name: "Valid roles",
 			tokenClaims: jwt.MapClaims{
 				"roles": []interface{}{"admin"},
 			},
-			functionName: "tenants",
-			wantErr:      nil,
+			permissions: []domain.Permission{domain.PermTenantsRead},
+			wantErr:     nil,
 		},
 		{
 			name: "Invalid roles",
 			tokenClaims: jwt.MapClaims{
 				"roles": []interface{}{"user"},
 			},
-			functionName: "tenants",
-			wantErr:      ErrInvalidToken,
+			permissions: []domain.Permission{domain.PermTenantsRead},
+			wantErr:     ErrInvalidToken,
 		},
 		{
 			name: "Empty roles",
 			tokenClaims: jwt.MapClaims{
 				"roles": []interface{}{},
 			},
-			functionName: "admin_function",
-			wantErr:      ErrInvalidToken,
+			permissions: []domain.Permission{domain.PermTenantsRead},
+			wantErr:     ErrInvalidToken,
+		},
+		{
+			name: "Missing permission",
+			tokenClaims: jwt.MapClaims{
+				"roles": []interface{}{"analyst"},
+			},
+			permissions: []domain.Permission{domain.PermHostsWrite},
+			wantErr:     services.ErrPermissionDenied,
+		},
+		{
+			name: "Permission of a later role",
+			tokenClaims: jwt.MapClaims{
+				"roles": []interface{}{"analyst", "operator"},
+			},
+			permissions: []domain.Permission{domain.PermScansCreate},
+			wantErr:     nil,
+		},
+		{
+			name: "Permissions of every role are combined",
+			tokenClaims: jwt.MapClaims{
+				"roles": []interface{}{"operator", "user", "analyst"},
+			},
+			permissions: []domain.Permission{domain.PermTenantsRead, domain.PermScansCreate},
+			wantErr:     nil,
+		},
+		{
+			name: "Route without permissions",
+			tokenClaims: jwt.MapClaims{
+				"roles": []interface{}{"admin"},
+			},
+			permissions: nil,
+			wantErr:     services.ErrPermissionDenied,
 		},
 	}
 
@@ -100,7 +134,7 @@ func Test_checkTokenRoles(t *testing.T) {
 			token := &jwt.Token{
 				Claims: tt.tokenClaims,
 			}
-			err := checkTokenRoles(token, tt.functionName)
+			err := checkTokenPermissions(token, "tenant-1", tt.permissions)
 
 			if !errors.Is(err, tt.wantErr) {
 				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
@@ -21,6 +21,7 @@ func CheckCORS(next http.Handler) http.Handler {
 			if slices.Contains(originAllowlist, origin) && slices.Contains(methodAllowlist, method) {
 				// Preflight request (OPTIONS)
 				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
 				w.Header().Set("Access-Control-Allow-Methods", strings.Join(methodAllowlist, ", "))
 				w.Header().Set("Access-Control-Allow-Headers", strings.Join(allowedHeaders, ", "))
 				w.Header().Set("Allow", strings.Join(methodAllowlist, ", "))
@@ -32,6 +33,8 @@ func CheckCORS(next http.Handler) http.Handler {
 			origin := r.Header.Get("Origin")
 			if slices.Contains(originAllowlist, origin) {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
+				// The token cookies are only sent along with credentials
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
 			}
 		}
 		w.Header().Add("Vary", "Origin")
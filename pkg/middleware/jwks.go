@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/config"
+)
+
+var ErrUnknownKID = errors.New("unknown kid")
+
+// jwksFetchTimeout bounds a single request to the JWKS endpoint
+const jwksFetchTimeout = 10 * time.Second
+
+// keySet verifies the token signatures, it's built from the config the first
+// time it's needed unless SetKeySet was called before
+var (
+	keySet   *KeySet
+	keySetMu sync.Mutex
+)
+
+// jwk is an entry of a JSON Web Key Set, only RSA signing keys are used
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// KeySet caches the public keys of a JWKS endpoint by kid. Keys are fetched
+// again once they're older than ttl, or when a token has a kid that isn't
+// cached, at most once every cooldown so unknown kids can't flood the endpoint.
+type KeySet struct {
+	url      string
+	ttl      time.Duration
+	cooldown time.Duration
+	client   *http.Client
+	now      func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	// fetching is closed when the fetch in progress is done, nil when there's none
+	fetching chan struct{}
+}
+
+func NewKeySet(url string, ttl time.Duration, cooldown time.Duration) *KeySet {
+	return &KeySet{
+		url:      url,
+		ttl:      ttl,
+		cooldown: cooldown,
+		client:   &http.Client{Timeout: jwksFetchTimeout},
+		now:      time.Now,
+		keys:     map[string]*rsa.PublicKey{},
+	}
+}
+
+// SetKeySet replaces the key set used to verify tokens, nil goes back to the
+// one built from the config
+func SetKeySet(ks *KeySet) {
+	keySetMu.Lock()
+	defer keySetMu.Unlock()
+	keySet = ks
+}
+
+func getKeySet() *KeySet {
+	keySetMu.Lock()
+	defer keySetMu.Unlock()
+	if keySet == nil {
+		c := config.LoadConfig()
+		keySet = NewKeySet(c.GetJWKSURL(), c.GetJWKSCacheTTL(), c.GetJWKSRefetchCooldown())
+	}
+	return keySet
+}
+
+// Key returns the public key of kid. Stale keys are kept when the JWKS
+// endpoint can't be reached, so an outage doesn't reject every token. The
+// endpoint is fetched without holding the lock, cached keys are served in
+// the meantime and only a kid that isn't cached waits for the fetch.
+func (ks *KeySet) Key(kid string) (*rsa.PublicKey, error) {
+	ks.mu.Lock()
+	now := ks.now()
+	key, ok := ks.keys[kid]
+	expired := now.Sub(ks.fetchedAt) >= ks.ttl
+
+	switch {
+	case ok && !expired:
+		ks.mu.Unlock()
+	case ks.fetching != nil:
+		fetching := ks.fetching
+		ks.mu.Unlock()
+		if !ok {
+			<-fetching
+			ks.mu.Lock()
+			key, ok = ks.keys[kid]
+			ks.mu.Unlock()
+		}
+	case now.Sub(ks.attemptedAt) >= ks.cooldown:
+		if err := ks.refresh(now); err != nil {
+			if !ok {
+				return nil, err
+			}
+			log.Printf("Error refreshing JWKS, using cached keys: `%s`", err.Error())
+		}
+		ks.mu.Lock()
+		key, ok = ks.keys[kid]
+		ks.mu.Unlock()
+	default:
+		ks.mu.Unlock()
+	}
+
+	if !ok {
+		msg := fmt.Sprintf("No public key for kid `%s`", kid)
+		return nil, fmt.Errorf("%q: %w: %w", msg, ErrUnknownKID, ErrInvalidToken)
+	}
+	return key, nil
+}
+
+// refresh replaces the cached keys with those of the endpoint, keys that
+// were rotated out are dropped. It's called with ks.mu held and releases it
+// for the request, concurrent calls of Key see ks.fetching in the meantime.
+func (ks *KeySet) refresh(now time.Time) error {
+	ks.attemptedAt = now
+	fetching := make(chan struct{})
+	ks.fetching = fetching
+	ks.mu.Unlock()
+
+	keys, err := ks.fetch()
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if err == nil {
+		ks.keys = keys
+		ks.fetchedAt = now
+	}
+	ks.fetching = nil
+	close(fetching)
+	return err
+}
+
+// fetch returns the RSA signing keys of the endpoint by kid
+func (ks *KeySet) fetch() (map[string]*rsa.PublicKey, error) {
+	response, err := ks.client.Get(ks.url)
+	if err != nil {
+		return nil, fmt.Errorf("problem connecting to the JWKS endpoint: `%s`", err.Error())
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected JWKS response status: `%d`", response.StatusCode)
+	}
+
+	set := new(jwks)
+	if err := json.NewDecoder(response.Body).Decode(set); err != nil {
+		return nil, fmt.Errorf("problem unmarshaling JWKS response: `%s`", err.Error())
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || k.Kid == "" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := parseRSAPublicKey(k)
+		if err != nil {
+			log.Printf("Skipping JWKS key `%s`: `%s`", k.Kid, err.Error())
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// parseRSAPublicKey decodes the base64url modulus and exponent of a JWK
+func parseRSAPublicKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(k.N, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(k.E, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid modulus or exponent size")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
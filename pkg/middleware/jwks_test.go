@@ -0,0 +1,308 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// fakeJWKS serves the public keys it holds and counts the requests it gets.
+// When release is set, a request signals waiting and blocks until release is closed.
+type fakeJWKS struct {
+	mu       sync.Mutex
+	keys     map[string]*rsa.PublicKey
+	requests int
+	down     bool
+
+	waiting chan struct{}
+	release chan struct{}
+}
+
+func (f *fakeJWKS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.release != nil {
+		f.waiting <- struct{}{}
+		<-f.release
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests++
+	if f.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	set := jwks{Keys: []jwk{}}
+	for kid, key := range f.keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+func (f *fakeJWKS) set(keys map[string]*rsa.PublicKey, down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys, f.down = keys, down
+}
+
+func (f *fakeJWKS) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return key
+}
+
+// newTestKeySet returns a key set on a fake JWKS server and a clock to move it
+func newTestKeySet(t *testing.T, keys map[string]*rsa.PublicKey) (*KeySet, *fakeJWKS, *time.Time) {
+	t.Helper()
+	fake := &fakeJWKS{keys: keys}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ks := NewKeySet(server.URL, time.Hour, 30*time.Second)
+	ks.now = func() time.Time { return now }
+	return ks, fake, &now
+}
+
+func Test_KeySet(t *testing.T) {
+	first := newRSAKey(t)
+	second := newRSAKey(t)
+
+	tests := []struct {
+		name string
+		// run makes the calls of the scenario, the error is that of the last Key call
+		run          func(ks *KeySet, fake *fakeJWKS, now *time.Time) (*rsa.PublicKey, error)
+		wantKey      *rsa.PublicKey
+		wantErr      error
+		wantRequests int
+	}{
+		{
+			name: "Keys are cached",
+			run: func(ks *KeySet, fake *fakeJWKS, now *time.Time) (*rsa.PublicKey, error) {
+				ks.Key("first")
+				*now = now.Add(59 * time.Minute)
+				return ks.Key("first")
+			},
+			wantKey:      &first.PublicKey,
+			wantRequests: 1,
+		},
+		{
+			name: "Keys are fetched again after the TTL",
+			run: func(ks *KeySet, fake *fakeJWKS, now *time.Time) (*rsa.PublicKey, error) {
+				ks.Key("first")
+				*now = now.Add(time.Hour)
+				return ks.Key("first")
+			},
+			wantKey:      &first.PublicKey,
+			wantRequests: 2,
+		},
+		{
+			name: "Unknown kid fetches the rotated keys",
+			run: func(ks *KeySet, fake *fakeJWKS, now *time.Time) (*rsa.PublicKey, error) {
+				ks.Key("first")
+				*now = now.Add(time.Minute)
+				fake.set(map[string]*rsa.PublicKey{"first": &first.PublicKey, "second": &second.PublicKey}, false)
+				return ks.Key("second")
+			},
+			wantKey:      &second.PublicKey,
+			wantRequests: 2,
+		},
+		{
+			name: "Unknown kids wait for the cooldown",
+			run: func(ks *KeySet, fake *fakeJWKS, now *time.Time) (*rsa.PublicKey, error) {
+				ks.Key("first")
+				ks.Key("unknown")
+				*now = now.Add(29 * time.Second)
+				return ks.Key("unknown")
+			},
+			wantErr:      ErrUnknownKID,
+			wantRequests: 1,
+		},
+		{
+			name: "Unknown kid after the cooldown fetches again",
+			run: func(ks *KeySet, fake *fakeJWKS, now *time.Time) (*rsa.PublicKey, error) {
+				ks.Key("unknown")
+				*now = now.Add(30 * time.Second)
+				return ks.Key("unknown")
+			},
+			wantErr:      ErrInvalidToken,
+			wantRequests: 2,
+		},
+		{
+			name: "Rotated out keys are dropped",
+			run: func(ks *KeySet, fake *fakeJWKS, now *time.Time) (*rsa.PublicKey, error) {
+				ks.Key("first")
+				fake.set(map[string]*rsa.PublicKey{"second": &second.PublicKey}, false)
+				*now = now.Add(time.Hour)
+				return ks.Key("first")
+			},
+			wantErr:      ErrUnknownKID,
+			wantRequests: 2,
+		},
+		{
+			name: "Cached keys are used while the endpoint is down",
+			run: func(ks *KeySet, fake *fakeJWKS, now *time.Time) (*rsa.PublicKey, error) {
+				ks.Key("first")
+				fake.set(nil, true)
+				*now = now.Add(2 * time.Hour)
+				return ks.Key("first")
+			},
+			wantKey:      &first.PublicKey,
+			wantRequests: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ks, fake, now := newTestKeySet(t, map[string]*rsa.PublicKey{"first": &first.PublicKey})
+
+			key, err := tt.run(ks, fake, now)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected error `%v`, got `%v`", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: `%v`", err)
+			}
+			if tt.wantKey != nil && !tt.wantKey.Equal(key) {
+				t.Errorf("Expected key of `%v`, got another one", tt.wantKey.N)
+			}
+			if got := fake.count(); got != tt.wantRequests {
+				t.Errorf("Expected %d JWKS requests, got %d", tt.wantRequests, got)
+			}
+		})
+	}
+}
+
+func Test_KeySetEndpointDown(t *testing.T) {
+	ks, fake, _ := newTestKeySet(t, nil)
+	fake.set(nil, true)
+
+	_, err := ks.Key("first")
+	if err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected a general error, got `%v`", err)
+	}
+}
+
+func Test_KeySetSlowEndpoint(t *testing.T) {
+	first := newRSAKey(t)
+	ks, fake, now := newTestKeySet(t, map[string]*rsa.PublicKey{"first": &first.PublicKey})
+	if _, err := ks.Key("first"); err != nil {
+		t.Fatalf("Unexpected error: `%v`", err)
+	}
+
+	*now = now.Add(time.Hour)
+	fake.waiting, fake.release = make(chan struct{}), make(chan struct{})
+	refreshed := make(chan error)
+	go func() {
+		_, err := ks.Key("first")
+		refreshed <- err
+	}()
+	<-fake.waiting
+
+	// The stale key is served while the refresh is in progress
+	served := make(chan error, 1)
+	go func() {
+		_, err := ks.Key("first")
+		served <- err
+	}()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Unexpected error: `%v`", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Expected the cached key while the JWKS endpoint is slow")
+	}
+
+	close(fake.release)
+	if err := <-refreshed; err != nil {
+		t.Errorf("Unexpected error: `%v`", err)
+	}
+	if got := fake.count(); got != 2 {
+		t.Errorf("Expected 2 JWKS requests, got %d", got)
+	}
+}
+
+func Test_verifyTokenSignature(t *testing.T) {
+	signing := newRSAKey(t)
+	other := newRSAKey(t)
+
+	ks, _, _ := newTestKeySet(t, map[string]*rsa.PublicKey{"tenant-key": &signing.PublicKey})
+	SetKeySet(ks)
+	t.Cleanup(func() { SetKeySet(nil) })
+
+	tests := []struct {
+		name    string
+		kid     string
+		key     *rsa.PrivateKey
+		wantErr error
+	}{
+		{
+			name: "Token signed with the key of its kid",
+			kid:  "tenant-key",
+			key:  signing,
+		},
+		{
+			name:    "Token signed with another key",
+			kid:     "tenant-key",
+			key:     other,
+			wantErr: rsa.ErrVerification,
+		},
+		{
+			name:    "Token with an unknown kid",
+			kid:     "other-key",
+			key:     other,
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+				"iss": "https://app.kriptome.com",
+				"tid": "b2131c96-bc4d-4dab-86c8-e5ff3e70b3f9",
+				"sub": "b2131c96-bc4d-4dab-86c8-e5ff3e70b3f9",
+			})
+			token.Header["kid"] = tt.kid
+			signed, err := token.SignedString(tt.key)
+			if err != nil {
+				t.Fatalf("failed to sign token: %v", err)
+			}
+
+			_, err = jwt.Parse(signed, verifyTokenSignature)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Unexpected error: `%v`", err)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error `%v`, got `%v`", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"sync"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+// permissionService authorizes the requests, without it every tenant has the
+// default permissions of its roles
+var (
+	permissionService   interfaces.IPermissionService
+	permissionServiceMu sync.Mutex
+)
+
+// SetPermissions replaces the service that authorizes the requests
+func SetPermissions(p interfaces.IPermissionService) {
+	permissionServiceMu.Lock()
+	defer permissionServiceMu.Unlock()
+	permissionService = p
+}
+
+func authorize(tenantID string, roles []domain.Role, required []domain.Permission) error {
+	permissionServiceMu.Lock()
+	p := permissionService
+	permissionServiceMu.Unlock()
+
+	if p == nil {
+		return services.CheckPermissions(roles, nil, required)
+	}
+	return p.Authorize(tenantID, roles, required)
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// ContextRequestID holds the ID of the request, it's sent back in the X-Request-ID header
+const ContextRequestID ContextKey = "requestID"
+
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs accepted from clients and proxies
+const maxRequestIDLength = 128
+
+// RequestID tags every request with an ID, reusing the X-Request-ID header of
+// the client when it's valid, so audit events can be traced to their request
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		w.Header().Set(requestIDHeader, requestID)
+		ctx := context.WithValue(r.Context(), ContextRequestID, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// isValidRequestID accepts printable ASCII IDs, so they're safe to log and store
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range requestID {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
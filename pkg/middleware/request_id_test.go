@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_RequestID(t *testing.T) {
+	tests := []struct {
+		name          string
+		header        string
+		wantRequestID string
+	}{
+		{
+			name:          "Client request ID is kept",
+			header:        "req-1234",
+			wantRequestID: "req-1234",
+		},
+		{
+			name:   "Missing request ID is generated",
+			header: "",
+		},
+		{
+			name:   "Request ID with spaces is replaced",
+			header: "req 1234",
+		},
+		{
+			name:   "Request ID with control characters is replaced",
+			header: "req\x1b[31m",
+		},
+		{
+			name:   "Too long request ID is replaced",
+			header: strings.Repeat("a", maxRequestIDLength+1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var contextRequestID string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contextRequestID, _ = r.Context().Value(ContextRequestID).(string)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set(requestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if contextRequestID == "" {
+				t.Fatal("expected a request ID in the context")
+			}
+			if got := w.Header().Get(requestIDHeader); got != contextRequestID {
+				t.Errorf("expected header %q, got %q", contextRequestID, got)
+			}
+			if tt.wantRequestID != "" && contextRequestID != tt.wantRequestID {
+				t.Errorf("expected request ID %q, got %q", tt.wantRequestID, contextRequestID)
+			}
+			if tt.wantRequestID == "" && contextRequestID == tt.header {
+				t.Errorf("expected invalid request ID %q to be replaced", tt.header)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+// userCache checks the users of the tokens, main sets it through SetUserCache
+var (
+	userCache   *UserCache
+	userCacheMu sync.Mutex
+)
+
+// UserCache remembers which users exist, and their roles, so authenticated
+// requests don't call FusionAuth every time. Users that exist are cached for ttl, missing or
+// inactive ones for negativeTTL. Once size users are cached the least recently
+// used one is dropped.
+type UserCache struct {
+	users       interfaces.IAuthService
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type userCacheEntry struct {
+	userID    string
+	tenantID  string
+	exists    bool
+	roles     []domain.Role
+	expiresAt time.Time
+}
+
+var _ interfaces.IUserCache = (*UserCache)(nil)
+
+func NewUserCache(users interfaces.IAuthService, size int, ttl time.Duration, negativeTTL time.Duration) *UserCache {
+	return &UserCache{
+		users:       users,
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     map[string]*list.Element{},
+		order:       list.New(),
+	}
+}
+
+// SetUserCache replaces the cache used to check the users of the tokens
+func SetUserCache(c *UserCache) {
+	userCacheMu.Lock()
+	defer userCacheMu.Unlock()
+	userCache = c
+}
+
+func getUserCache() (*UserCache, error) {
+	userCacheMu.Lock()
+	defer userCacheMu.Unlock()
+	if userCache == nil {
+		return nil, errors.New("user cache is not set")
+	}
+	return userCache, nil
+}
+
+// UserExists tells if userID is an active user of tenantID, asking FusionAuth
+// only when the answer isn't cached
+func (c *UserCache) UserExists(userID string, tenantID string) (bool, error) {
+	_, exists, err := c.UserRoles(userID, tenantID)
+	return exists, err
+}
+
+// UserRoles returns the current roles of userID and tells if it's an active
+// user of tenantID, asking FusionAuth only when the answer isn't cached
+func (c *UserCache) UserRoles(userID string, tenantID string) ([]domain.Role, bool, error) {
+	if entry, ok := c.get(userID, tenantID); ok {
+		return entry.roles, entry.exists, nil
+	}
+
+	exists := true
+	roles := []domain.Role{}
+	user, err := c.users.GetUserByID(userID, &tenantID)
+	if err != nil {
+		var faErr *services.FaError
+		if !errors.As(err, &faErr) {
+			return nil, false, err
+		}
+		if faErr.Status() != http.StatusNotFound {
+			msg := faErr.Error()
+			log.Printf("FusionAuth error fetching user: `%s`", msg)
+			return nil, false, fmt.Errorf("%q: %w", msg, ErrUserNotFound)
+		}
+		exists = false
+	} else {
+		exists = user.Active
+		for _, r := range user.Roles {
+			if role, err := domain.ParseRole(r); err == nil {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	c.set(userID, tenantID, exists, roles)
+	return roles, exists, nil
+}
+
+// InvalidateUser drops userID from the cache, the next request checks it again
+func (c *UserCache) InvalidateUser(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[userID]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, userID)
+	}
+}
+
+func (c *UserCache) get(userID string, tenantID string) (*userCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[userID]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*userCacheEntry)
+	if entry.tenantID != tenantID || !c.now().Before(entry.expiresAt) {
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry, true
+}
+
+func (c *UserCache) set(userID string, tenantID string, exists bool, roles []domain.Role) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ttl := c.ttl
+	if !exists {
+		ttl = c.negativeTTL
+	}
+	entry := &userCacheEntry{userID: userID, tenantID: tenantID, exists: exists, roles: roles, expiresAt: c.now().Add(ttl)}
+
+	if elem, ok := c.entries[userID]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[userID] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*userCacheEntry).userID)
+	}
+}
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+// fakeUsers answers user lookups from memory and counts them
+type fakeUsers struct {
+	interfaces.IAuthService
+	users   map[string]*domain.User
+	err     error
+	lookups int
+}
+
+func (f *fakeUsers) GetUserByID(userID string, tenantID *string) (*domain.User, error) {
+	f.lookups++
+	if f.err != nil {
+		return nil, f.err
+	}
+	user, ok := f.users[userID]
+	if !ok {
+		return nil, services.NewFaError(http.StatusNotFound, "")
+	}
+	return user, nil
+}
+
+func Test_UserCache(t *testing.T) {
+	active := &domain.User{ID: "active", Active: true}
+	inactive := &domain.User{ID: "inactive", Active: false}
+
+	tests := []struct {
+		name string
+		// run makes the calls of the scenario, the result is that of the last one
+		run         func(c *UserCache, users *fakeUsers, now *time.Time) (bool, error)
+		wantExists  bool
+		wantErr     error
+		wantLookups int
+	}{
+		{
+			name: "Existing users are cached",
+			run: func(c *UserCache, users *fakeUsers, now *time.Time) (bool, error) {
+				c.UserExists("active", "tenant-1")
+				*now = now.Add(4 * time.Minute)
+				return c.UserExists("active", "tenant-1")
+			},
+			wantExists:  true,
+			wantLookups: 1,
+		},
+		{
+			name: "Existing users expire after the TTL",
+			run: func(c *UserCache, users *fakeUsers, now *time.Time) (bool, error) {
+				c.UserExists("active", "tenant-1")
+				*now = now.Add(5 * time.Minute)
+				return c.UserExists("active", "tenant-1")
+			},
+			wantExists:  true,
+			wantLookups: 2,
+		},
+		{
+			name: "Missing users are cached",
+			run: func(c *UserCache, users *fakeUsers, now *time.Time) (bool, error) {
+				c.UserExists("missing", "tenant-1")
+				*now = now.Add(29 * time.Second)
+				return c.UserExists("missing", "tenant-1")
+			},
+			wantExists:  false,
+			wantLookups: 1,
+		},
+		{
+			name: "Missing users expire after the negative TTL",
+			run: func(c *UserCache, users *fakeUsers, now *time.Time) (bool, error) {
+				c.UserExists("missing", "tenant-1")
+				*now = now.Add(30 * time.Second)
+				return c.UserExists("missing", "tenant-1")
+			},
+			wantExists:  false,
+			wantLookups: 2,
+		},
+		{
+			name: "Inactive users don't exist",
+			run: func(c *UserCache, users *fakeUsers, now *time.Time) (bool, error) {
+				return c.UserExists("inactive", "tenant-1")
+			},
+			wantExists:  false,
+			wantLookups: 1,
+		},
+		{
+			name: "Users of another tenant are looked up",
+			run: func(c *UserCache, users *fakeUsers, now *time.Time) (bool, error) {
+				c.UserExists("active", "tenant-1")
+				return c.UserExists("active", "tenant-2")
+			},
+			wantExists:  true,
+			wantLookups: 2,
+		},
+		{
+			name: "Invalidated users are looked up",
+			run: func(c *UserCache, users *fakeUsers, now *time.Time) (bool, error) {
+				c.UserExists("active", "tenant-1")
+				c.InvalidateUser("active")
+				delete(users.users, "active")
+				return c.UserExists("active", "tenant-1")
+			},
+			wantExists:  false,
+			wantLookups: 2,
+		},
+		{
+			name: "Least recently used user is evicted",
+			run: func(c *UserCache, users *fakeUsers, now *time.Time) (bool, error) {
+				c.UserExists("active", "tenant-1")
+				c.UserExists("inactive", "tenant-1")
+				c.UserExists("active", "tenant-1")
+				c.UserExists("missing", "tenant-1")
+				return c.UserExists("inactive", "tenant-1")
+			},
+			wantExists:  false,
+			wantLookups: 4,
+		},
+		{
+			name: "FusionAuth errors aren't cached",
+			run: func(c *UserCache, users *fakeUsers, now *time.Time) (bool, error) {
+				users.err = services.NewFaError(http.StatusUnauthorized, "invalid API key")
+				c.UserExists("active", "tenant-1")
+				return c.UserExists("active", "tenant-1")
+			},
+			wantErr:     ErrUserNotFound,
+			wantLookups: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUsers{users: map[string]*domain.User{"active": active, "inactive": inactive}}
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			c := NewUserCache(users, 2, 5*time.Minute, 30*time.Second)
+			c.now = func() time.Time { return now }
+
+			exists, err := tt.run(c, users, &now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error `%v`, got `%v`", tt.wantErr, err)
+			}
+			if exists != tt.wantExists {
+				t.Errorf("Expected exists `%v`, got `%v`", tt.wantExists, exists)
+			}
+			if users.lookups != tt.wantLookups {
+				t.Errorf("Expected %d lookups, got %d", tt.wantLookups, users.lookups)
+			}
+		})
+	}
+}
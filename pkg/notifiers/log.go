@@ -0,0 +1,43 @@
+package notifiers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+// LogNotifier writes notifications to a writer instead of delivering them,
+// it's meant for local development and tests
+type LogNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+var _ interfaces.INotifier = (*LogNotifier)(nil)
+
+func NewLogNotifier(w io.Writer) *LogNotifier {
+	return &LogNotifier{w: w}
+}
+
+// NewFileNotifier appends notifications to the file at path
+func NewFileNotifier(path string) (*LogNotifier, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open notifications file: %w", err)
+	}
+	return NewLogNotifier(f), nil
+}
+
+func (n *LogNotifier) Send(message *domain.NotificationMessage) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, err := fmt.Fprintf(n.w, "--- %s\nTo: %s\nSubject: %s\n\n%s\n",
+		time.Now().UTC().Format(time.RFC3339), message.To, message.Subject, message.Body)
+	return err
+}
@@ -0,0 +1,49 @@
+package notifiers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+func Test_LogNotifierSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  *domain.NotificationMessage
+		contains []string
+	}{
+		{
+			name:     "Writes recipient, subject and body",
+			message:  &domain.NotificationMessage{To: "ana@example.com", Subject: "Scan completed", Body: "All good"},
+			contains: []string{"To: ana@example.com\n", "Subject: Scan completed\n", "\n\nAll good\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewLogNotifier(&buf).Send(tt.message); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("Expected output to contain `%q`, got `%q`", want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func Test_BuildMail(t *testing.T) {
+	mail := string(buildMail("noreply@example.com", &domain.NotificationMessage{
+		To: "ana@example.com", Subject: "Scan completed", Body: "line 1\nline 2",
+	}))
+
+	for _, want := range []string{"From: noreply@example.com\r\n", "To: ana@example.com\r\n", "Subject: Scan completed\r\n", "\r\n\r\nline 1\r\nline 2"} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("Expected mail to contain `%q`, got `%q`", want, mail)
+		}
+	}
+}
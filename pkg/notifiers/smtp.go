@@ -0,0 +1,57 @@
+package notifiers
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+// SMTPNotifier emails notifications through an SMTP relay
+type SMTPNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+var _ interfaces.INotifier = (*SMTPNotifier)(nil)
+
+// NewSMTPNotifier authenticates with PLAIN auth when a username is given
+func NewSMTPNotifier(host string, port string, username string, password string, from string) *SMTPNotifier {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPNotifier{
+		addr: net.JoinHostPort(host, port),
+		from: from,
+		auth: auth,
+	}
+}
+
+func (n *SMTPNotifier) Send(message *domain.NotificationMessage) error {
+	if err := smtp.SendMail(n.addr, n.auth, n.from, []string{message.To}, buildMail(n.from, message)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+func buildMail(from string, message *domain.NotificationMessage) []byte {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "From: %s\r\n", from)
+	fmt.Fprintf(&sb, "To: %s\r\n", message.To)
+	fmt.Fprintf(&sb, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&sb, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return []byte(sb.String())
+}
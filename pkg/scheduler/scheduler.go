@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kptm-tools/common/common/enums"
+	cmmn "github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/services"
+)
+
+// Scheduler starts the scans of due schedules. It runs in-process,
+// so a single core-service instance should run it.
+type Scheduler struct {
+	scheduleService interfaces.IScheduleService
+	scanService     interfaces.IScanService
+	eventBus        cmmn.EventBus
+	interval        time.Duration
+}
+
+func NewScheduler(scheduleService interfaces.IScheduleService, scanService interfaces.IScanService, bus cmmn.EventBus, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		scheduleService: scheduleService,
+		scanService:     scanService,
+		eventBus:        bus,
+		interval:        interval,
+	}
+}
+
+// Run checks for due schedules every interval, it never returns
+func (s *Scheduler) Run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.RunDue(now.UTC())
+	}
+}
+
+// RunDue starts a scan for every schedule that is due at now
+func (s *Scheduler) RunDue(now time.Time) {
+	schedules, err := s.scheduleService.GetDueSchedules(now)
+	if err != nil {
+		log.Printf("Error fetching due schedules: `%+v`", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		scanID, err := s.runSchedule(schedule)
+		if err != nil {
+			log.Printf("Error running schedule `%s`: `%+v`", schedule.ID, err)
+		}
+
+		// Always move the schedule forward, so a failing schedule doesn't run on every tick
+		if err := s.scheduleService.MarkScheduleRun(schedule, now, scanID); err != nil {
+			log.Printf("Error updating schedule `%s`: `%+v`", schedule.ID, err)
+		}
+	}
+}
+
+func (s *Scheduler) runSchedule(schedule *domain.ScanSchedule) (string, error) {
+	active, err := s.scanService.HasActiveScan(schedule.HostIDs, schedule.TenantID)
+	if err != nil {
+		return "", err
+	}
+	if active {
+		log.Printf("Skipping schedule `%s`: a previous scan on its hosts is still in progress", schedule.ID)
+		return "", nil
+	}
+
+	actor := domain.Actor{TenantID: schedule.TenantID, UserID: schedule.OperatorID}
+	scan, err := s.scanService.CreateScans(actor, domain.HostSelector{HostIDs: schedule.HostIDs}, schedule.Tools)
+	if err != nil {
+		return "", fmt.Errorf("failed to create scan: %w", err)
+	}
+
+	scanStartedBytes, err := json.Marshal(services.NewScanStartedEvent(scan))
+	if err != nil {
+		return scan.ID, fmt.Errorf("failed to marshal scan started event: %w", err)
+	}
+	if err := s.eventBus.Publish(string(enums.ScanStartedEventSubject), scanStartedBytes); err != nil {
+		return scan.ID, fmt.Errorf("failed to publish scan started event: %w", err)
+	}
+
+	log.Printf("Schedule `%s` started scan `%s`", schedule.ID, scan.ID)
+	return scan.ID, nil
+}
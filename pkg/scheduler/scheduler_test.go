@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kptm-tools/common/common/enums"
+	cmmn "github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+// fakeSchedules returns the due schedules and records the runs
+type fakeSchedules struct {
+	interfaces.IScheduleService
+	due  []*domain.ScanSchedule
+	runs map[string]string
+}
+
+func (f *fakeSchedules) GetDueSchedules(now time.Time) ([]*domain.ScanSchedule, error) {
+	return f.due, nil
+}
+
+func (f *fakeSchedules) MarkScheduleRun(sc *domain.ScanSchedule, runAt time.Time, scanID string) error {
+	f.runs[sc.ID] = scanID
+	return nil
+}
+
+// fakeScans starts a scan for every call unless the hosts are busy or it fails
+type fakeScans struct {
+	interfaces.IScanService
+	busy  []int
+	err   error
+	tools [][]domain.ScanTool
+}
+
+func (f *fakeScans) HasActiveScan(hostIDs []int, tenantID string) (bool, error) {
+	for _, hostID := range hostIDs {
+		if slices.Contains(f.busy, hostID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeScans) CreateScans(actor domain.Actor, selector domain.HostSelector, tools []domain.ScanTool) (*domain.Scan, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.tools = append(f.tools, tools)
+	return &domain.Scan{ID: "scan-" + actor.UserID, TenantID: actor.TenantID, OperatorID: actor.UserID}, nil
+}
+
+// fakeBus records the subjects of the published messages
+type fakeBus struct {
+	cmmn.EventBus
+	subjects []string
+}
+
+func (f *fakeBus) Publish(subject string, payload []byte) error {
+	f.subjects = append(f.subjects, subject)
+	return nil
+}
+
+func Test_RunDue(t *testing.T) {
+	now := time.Date(2024, 12, 2, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		due               []*domain.ScanSchedule
+		busy              []int
+		createErr         error
+		expectedRuns      map[string]string
+		expectedTools     [][]domain.ScanTool
+		expectedPublished int
+	}{
+		{
+			name: "Due schedules start scans",
+			due: []*domain.ScanSchedule{
+				{ID: "schedule-1", TenantID: "tenant-1", OperatorID: "user-1", HostIDs: []int{1}},
+				{ID: "schedule-2", TenantID: "tenant-1", OperatorID: "user-2", HostIDs: []int{2}, Tools: []domain.ScanTool{
+					{Service: enums.ServiceNmap, Options: map[string]interface{}{"ports": "1-1024"}},
+				}},
+			},
+			expectedRuns: map[string]string{"schedule-1": "scan-user-1", "schedule-2": "scan-user-2"},
+			expectedTools: [][]domain.ScanTool{
+				nil,
+				{{Service: enums.ServiceNmap, Options: map[string]interface{}{"ports": "1-1024"}}},
+			},
+			expectedPublished: 2,
+		},
+		{
+			name: "Schedule with a scan in progress is skipped",
+			due: []*domain.ScanSchedule{
+				{ID: "schedule-1", TenantID: "tenant-1", OperatorID: "user-1", HostIDs: []int{1, 2}},
+			},
+			busy:              []int{2},
+			expectedRuns:      map[string]string{"schedule-1": ""},
+			expectedPublished: 0,
+		},
+		{
+			name: "Failing schedule still moves forward",
+			due: []*domain.ScanSchedule{
+				{ID: "schedule-1", TenantID: "tenant-1", OperatorID: "user-1", HostIDs: []int{1}},
+			},
+			createErr:         errors.New("no hosts"),
+			expectedRuns:      map[string]string{"schedule-1": ""},
+			expectedPublished: 0,
+		},
+		{
+			name:              "Nothing due",
+			expectedRuns:      map[string]string{},
+			expectedPublished: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedules := &fakeSchedules{due: tt.due, runs: map[string]string{}}
+			scans := &fakeScans{busy: tt.busy, err: tt.createErr}
+			bus := &fakeBus{}
+			s := NewScheduler(schedules, scans, bus, time.Minute)
+
+			s.RunDue(now)
+
+			if len(schedules.runs) != len(tt.expectedRuns) {
+				t.Fatalf("Expected runs `%v`, got `%v`", tt.expectedRuns, schedules.runs)
+			}
+			for id, scanID := range tt.expectedRuns {
+				got, ok := schedules.runs[id]
+				if !ok || got != scanID {
+					t.Errorf("Expected schedule `%s` to run with scan `%s`, got `%s`", id, scanID, got)
+				}
+			}
+			if len(scans.tools) != len(tt.expectedTools) {
+				t.Fatalf("Expected tools `%v`, got `%v`", tt.expectedTools, scans.tools)
+			}
+			for i, tools := range tt.expectedTools {
+				if !reflect.DeepEqual(scans.tools[i], tools) {
+					t.Errorf("Expected scan %d to run tools `%v`, got `%v`", i, tools, scans.tools[i])
+				}
+			}
+			if len(bus.subjects) != tt.expectedPublished {
+				t.Fatalf("Expected %d published events, got %d", tt.expectedPublished, len(bus.subjects))
+			}
+			for _, subject := range bus.subjects {
+				if subject != string(enums.ScanStartedEventSubject) {
+					t.Errorf("Expected subject `%s`, got `%s`", enums.ScanStartedEventSubject, subject)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyRejected = errors.New("api key rejected")
+)
+
+// apiKeyTouchInterval limits how often the last use of a key is written,
+// busy clients would otherwise update their key on every request
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyService manages the API keys of the tenants and authenticates the
+// requests made with them
+type APIKeyService struct {
+	storage interfaces.IStorage
+	now     func() time.Time
+}
+
+var _ interfaces.IAPIKeyService = (*APIKeyService)(nil)
+
+func NewAPIKeyService(storage interfaces.IStorage) *APIKeyService {
+	return &APIKeyService{
+		storage: storage,
+		now:     time.Now,
+	}
+}
+
+// CreateAPIKey generates a key for the tenant, the key is returned along with
+// it and can't be retrieved afterwards. The scopes must be roles of the actor.
+func (s *APIKeyService) CreateAPIKey(actor domain.Actor, tenantID string, name string, scopes []string, expiresAt *time.Time) (*domain.APIKey, string, error) {
+	name, roles, err := s.validateAPIKey(actor, name, scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.validateExpiry(expiresAt); err != nil {
+		return nil, "", err
+	}
+
+	k, secret, err := domain.NewAPIKey(tenantID, name, roles, actor.UserID, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, err := s.storage.CreateAPIKey(k)
+	if err != nil {
+		return nil, "", err
+	}
+
+	recordAudit(s.storage, actor, domain.AuditAPIKeyCreated, "api_key", key.ID, nil, key)
+	return key, secret, nil
+}
+
+func (s *APIKeyService) GetAPIKeysByTenantID(tenantID string) ([]*domain.APIKey, error) {
+	return s.storage.GetAPIKeysByTenantID(tenantID)
+}
+
+func (s *APIKeyService) GetAPIKeyByID(ID string, tenantID string) (*domain.APIKey, error) {
+	return s.storage.GetAPIKeyByID(ID, tenantID)
+}
+
+// PatchAPIKeyByID updates the fields of the patch, the key itself doesn't change.
+// The actor must have every scope the key ends up with.
+func (s *APIKeyService) PatchAPIKeyByID(actor domain.Actor, ID string, tenantID string, patch domain.APIKeyPatch) (*domain.APIKey, error) {
+	before, err := s.storage.GetAPIKeyByID(ID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := before.Name
+	if patch.Name != nil {
+		name = *patch.Name
+	}
+	scopes := make([]string, len(before.Scopes))
+	for i, scope := range before.Scopes {
+		scopes[i] = scope.String()
+	}
+	if patch.Scopes != nil {
+		scopes = *patch.Scopes
+	}
+	expiresAt := before.ExpiresAt
+	if patch.SetExpiresAt {
+		if err := s.validateExpiry(patch.ExpiresAt); err != nil {
+			return nil, err
+		}
+		expiresAt = patch.ExpiresAt
+	}
+
+	name, roles, err := s.validateAPIKey(actor, name, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := s.storage.PatchAPIKeyByID(&domain.APIKey{ID: ID, TenantID: tenantID, Name: name, Scopes: roles, ExpiresAt: expiresAt})
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(s.storage, actor, domain.AuditAPIKeyUpdated, "api_key", key.ID, before, key)
+	return key, nil
+}
+
+func (s *APIKeyService) DeleteAPIKeyByID(actor domain.Actor, ID string, tenantID string) (bool, error) {
+	before, err := s.storage.GetAPIKeyByID(ID, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	isDeleted, err := s.storage.DeleteAPIKeyByID(ID, tenantID)
+	if err != nil {
+		return false, err
+	}
+
+	if isDeleted {
+		recordAudit(s.storage, actor, domain.AuditAPIKeyDeleted, "api_key", ID, before, nil)
+	}
+	return isDeleted, nil
+}
+
+// Authenticate returns the key of a request, unknown and expired keys return
+// ErrAPIKeyRejected
+func (s *APIKeyService) Authenticate(secret string) (*domain.APIKey, error) {
+	key, err := s.storage.GetAPIKeyByHash(domain.HashAPIKey(secret))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%q: %w", "Unknown api key", ErrAPIKeyRejected)
+		}
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	if key.Expired(now) {
+		msg := fmt.Sprintf("Api key `%s` expired", key.ID)
+		return nil, fmt.Errorf("%q: %w", msg, ErrAPIKeyRejected)
+	}
+
+	// Failing to record the use shouldn't fail the request
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.storage.TouchAPIKey(key.ID, now); err != nil {
+			log.Printf("Error recording use of api key `%s`: `%+v`", key.ID, err)
+		} else {
+			key.LastUsedAt = &now
+		}
+	}
+
+	return key, nil
+}
+
+// validateAPIKey returns the trimmed name and the roles of the scopes. Keys can't
+// have roles their actor lacks, or tenants granting users:manage to other roles
+// would let those roles mint admin keys.
+func (s *APIKeyService) validateAPIKey(actor domain.Actor, name string, scopes []string) (string, []domain.Role, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 255 {
+		msg := "name must have between 1 and 255 characters"
+		return "", nil, fmt.Errorf("%q: %w", msg, ErrInvalidAPIKey)
+	}
+
+	roles := []domain.Role{}
+	for _, scope := range scopes {
+		role, err := domain.ParseRole(scope)
+		if err != nil {
+			return "", nil, fmt.Errorf("%q: %w", err.Error(), ErrInvalidAPIKey)
+		}
+		if !slices.Contains(actor.Roles, role) {
+			msg := fmt.Sprintf("scope `%s` is not a role of the user", role)
+			return "", nil, fmt.Errorf("%q: %w", msg, ErrInvalidAPIKey)
+		}
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	if len(roles) == 0 {
+		msg := "at least one scope is required"
+		return "", nil, fmt.Errorf("%q: %w", msg, ErrInvalidAPIKey)
+	}
+
+	return name, roles, nil
+}
+
+func (s *APIKeyService) validateExpiry(expiresAt *time.Time) error {
+	if expiresAt != nil && !expiresAt.After(s.now()) {
+		msg := "expires_at must be in the future"
+		return fmt.Errorf("%q: %w", msg, ErrInvalidAPIKey)
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+// apiKeyStorage keeps the keys in memory
+type apiKeyStorage struct {
+	interfaces.IStorage
+	keys    map[string]*domain.APIKey
+	touched int
+}
+
+func (s *apiKeyStorage) CreateAPIKey(k *domain.APIKey) (*domain.APIKey, error) {
+	s.keys[k.Hash] = k
+	return k, nil
+}
+
+func (s *apiKeyStorage) GetAPIKeyByHash(hash string) (*domain.APIKey, error) {
+	k, ok := s.keys[hash]
+	if !ok {
+		return nil, fmt.Errorf("failed to fetch api key: %w", sql.ErrNoRows)
+	}
+	return k, nil
+}
+
+func (s *apiKeyStorage) TouchAPIKey(ID string, usedAt time.Time) error {
+	s.touched++
+	return nil
+}
+
+func (s *apiKeyStorage) GetAPIKeyByID(ID string, tenantID string) (*domain.APIKey, error) {
+	for _, k := range s.keys {
+		if k.ID == ID && k.TenantID == tenantID {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to fetch api key: %w", sql.ErrNoRows)
+}
+
+func (s *apiKeyStorage) PatchAPIKeyByID(k *domain.APIKey) (*domain.APIKey, error) {
+	key, err := s.GetAPIKeyByID(k.ID, k.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	patched := *key
+	patched.Name, patched.Scopes, patched.ExpiresAt = k.Name, k.Scopes, k.ExpiresAt
+	s.keys[key.Hash] = &patched
+	return &patched, nil
+}
+
+func (s *apiKeyStorage) CreateAuditEvent(*domain.AuditEvent) error {
+	return nil
+}
+
+func Test_CreateAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		keyName    string
+		scopes     []string
+		expiresAt  *time.Time
+		wantScopes []domain.Role
+		wantErr    error
+	}{
+		{
+			name:       "Duplicate scopes are dropped",
+			keyName:    " ci ",
+			scopes:     []string{"operator", "analyst", "operator"},
+			wantScopes: []domain.Role{domain.RoleOperator, domain.RoleAnalyst},
+		},
+		{
+			name:    "Scope the user lacks",
+			keyName: "ci",
+			scopes:  []string{"admin"},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name:    "Missing name",
+			keyName: " ",
+			scopes:  []string{"operator"},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name:    "Unknown scope",
+			keyName: "ci",
+			scopes:  []string{"root"},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name:    "No scopes",
+			keyName: "ci",
+			scopes:  []string{},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name:      "Expired",
+			keyName:   "ci",
+			scopes:    []string{"operator"},
+			expiresAt: &past,
+			wantErr:   ErrInvalidAPIKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &apiKeyStorage{keys: map[string]*domain.APIKey{}}
+			s := NewAPIKeyService(storage)
+
+			actor := domain.Actor{TenantID: "tenant-1", UserID: "user-1", Roles: []domain.Role{domain.RoleOperator, domain.RoleAnalyst}}
+			key, secret, err := s.CreateAPIKey(actor, "tenant-1", tt.keyName, tt.scopes, tt.expiresAt)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if key.Name != "ci" || key.CreatedBy != "user-1" {
+				t.Errorf("unexpected key %+v", key)
+			}
+			if fmt.Sprint(key.Scopes) != fmt.Sprint(tt.wantScopes) {
+				t.Errorf("expected scopes %v, got %v", tt.wantScopes, key.Scopes)
+			}
+			if !domain.IsAPIKey(secret) || key.Hash != domain.HashAPIKey(secret) || key.Hash == secret {
+				t.Errorf("expected the hash of the key to be stored, got %+v", key)
+			}
+		})
+	}
+}
+
+func Test_Authenticate(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Second)
+	recent := now.Add(-time.Second)
+
+	tests := []struct {
+		name        string
+		key         *domain.APIKey
+		secret      string
+		wantErr     error
+		wantTouched int
+	}{
+		{
+			name:        "Valid key",
+			key:         &domain.APIKey{ID: "key-1"},
+			secret:      "kptm_secret",
+			wantTouched: 1,
+		},
+		{
+			name:        "Recently used key",
+			key:         &domain.APIKey{ID: "key-1", LastUsedAt: &recent},
+			secret:      "kptm_secret",
+			wantTouched: 0,
+		},
+		{
+			name:    "Unknown key",
+			key:     &domain.APIKey{ID: "key-1"},
+			secret:  "kptm_other",
+			wantErr: ErrAPIKeyRejected,
+		},
+		{
+			name:    "Expired key",
+			key:     &domain.APIKey{ID: "key-1", ExpiresAt: &expired},
+			secret:  "kptm_secret",
+			wantErr: ErrAPIKeyRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &apiKeyStorage{keys: map[string]*domain.APIKey{domain.HashAPIKey("kptm_secret"): tt.key}}
+			s := NewAPIKeyService(storage)
+			s.now = func() time.Time { return now }
+
+			key, err := s.Authenticate(tt.secret)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && key.ID != tt.key.ID {
+				t.Errorf("expected key %s, got %+v", tt.key.ID, key)
+			}
+			if storage.touched != tt.wantTouched {
+				t.Errorf("expected %d last use updates, got %d", tt.wantTouched, storage.touched)
+			}
+		})
+	}
+}
+
+func Test_PatchAPIKeyByID(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(24 * time.Hour)
+	later := now.Add(48 * time.Hour)
+	name := "deploy"
+	admin := []string{"admin"}
+	operator := domain.Actor{TenantID: "tenant-1", UserID: "user-1", Roles: []domain.Role{domain.RoleOperator}}
+
+	tests := []struct {
+		name          string
+		actor         domain.Actor
+		patch         domain.APIKeyPatch
+		wantName      string
+		wantExpiresAt *time.Time
+		wantErr       error
+	}{
+		{
+			name:          "Rename keeps the expiry",
+			actor:         operator,
+			patch:         domain.APIKeyPatch{Name: &name},
+			wantName:      "deploy",
+			wantExpiresAt: &expiresAt,
+		},
+		{
+			name:          "New expiry",
+			actor:         operator,
+			patch:         domain.APIKeyPatch{SetExpiresAt: true, ExpiresAt: &later},
+			wantName:      "ci",
+			wantExpiresAt: &later,
+		},
+		{
+			name:     "Expiry removed",
+			actor:    operator,
+			patch:    domain.APIKeyPatch{SetExpiresAt: true},
+			wantName: "ci",
+		},
+		{
+			name:    "Scope the user lacks",
+			actor:   operator,
+			patch:   domain.APIKeyPatch{Scopes: &admin},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name:    "Key with roles the user lacks",
+			actor:   domain.Actor{TenantID: "tenant-1", UserID: "user-2", Roles: []domain.Role{domain.RoleAnalyst}},
+			patch:   domain.APIKeyPatch{Name: &name},
+			wantErr: ErrInvalidAPIKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := &domain.APIKey{ID: "key-1", TenantID: "tenant-1", Name: "ci", Hash: "hash", Scopes: []domain.Role{domain.RoleOperator}, ExpiresAt: &expiresAt}
+			storage := &apiKeyStorage{keys: map[string]*domain.APIKey{"hash": existing}}
+			s := NewAPIKeyService(storage)
+			s.now = func() time.Time { return now }
+
+			key, err := s.PatchAPIKeyByID(tt.actor, "key-1", "tenant-1", tt.patch)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if key.Name != tt.wantName {
+				t.Errorf("expected name %s, got %s", tt.wantName, key.Name)
+			}
+			if fmt.Sprint(key.Scopes) != fmt.Sprint(existing.Scopes) {
+				t.Errorf("expected scopes %v, got %v", existing.Scopes, key.Scopes)
+			}
+			if (key.ExpiresAt == nil) != (tt.wantExpiresAt == nil) || (key.ExpiresAt != nil && !key.ExpiresAt.Equal(*tt.wantExpiresAt)) {
+				t.Errorf("expected expiry %v, got %v", tt.wantExpiresAt, key.ExpiresAt)
+			}
+		})
+	}
+}
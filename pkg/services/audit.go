@@ -0,0 +1,148 @@
+package services
+
+import (
+	"encoding/json"
+	"log"
+	"reflect"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+// redacted replaces secrets in the changes of audit events
+const redacted = "[REDACTED]"
+
+// redactedFields are never written to the audit log, only whether they changed
+var redactedFields = map[string]bool{
+	"credentials": true,
+	"password":    true,
+}
+
+type AuditService struct {
+	storage interfaces.IStorage
+}
+
+var _ interfaces.IAuditService = (*AuditService)(nil)
+
+func NewAuditService(storage interfaces.IStorage) *AuditService {
+	return &AuditService{
+		storage: storage,
+	}
+}
+
+func (s *AuditService) GetAuditEvents(filter domain.AuditFilter) (*domain.AuditPage, error) {
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, ErrInvalidDateRange
+	}
+
+	return s.storage.GetAuditEvents(filter)
+}
+
+// ExportAuditEvents calls write with every page of audit events matching the
+// filter, starting at filter.Cursor
+func (s *AuditService) ExportAuditEvents(filter domain.AuditFilter, write func([]*domain.AuditEvent) error) error {
+	filter.Limit = domain.MaxAuditPageSize
+	for {
+		page, err := s.GetAuditEvents(filter)
+		if err != nil {
+			return err
+		}
+		if err := write(page.Events); err != nil {
+			return err
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+// recordAudit writes an audit event for a change of a resource. before is nil for
+// created resources and after for deleted ones. The change already happened, so
+// failing to record it is logged instead of failing the request.
+func recordAudit(storage interfaces.IStorage, actor domain.Actor, action domain.AuditAction, resourceType string, resourceID string, before any, after any) {
+	changes, err := auditChanges(before, after)
+	if err != nil {
+		log.Printf("Error building audit changes of `%s` `%s`: `%+v`", action, resourceID, err)
+		return
+	}
+
+	event := domain.NewAuditEvent(actor, action, resourceType, resourceID, changes)
+	if err := storage.CreateAuditEvent(event); err != nil {
+		log.Printf("Error recording audit event `%s` `%s`: `%+v`", action, resourceID, err)
+	}
+}
+
+// auditChanges compares the JSON fields of before and after and returns the ones
+// that changed, with the values of redactedFields replaced
+func auditChanges(before any, after any) (map[string]domain.AuditChange, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]domain.AuditChange{}
+	for field := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			afterFields[field] = nil
+		}
+	}
+	for field, afterValue := range afterFields {
+		beforeValue := beforeFields[field]
+		if reflect.DeepEqual(beforeValue, afterValue) {
+			continue
+		}
+		changes[field] = domain.AuditChange{Before: redact(field, beforeValue), After: redact(field, afterValue)}
+	}
+
+	return changes, nil
+}
+
+// jsonFields returns the top level fields of v as it's encoded to JSON, a nil v has none
+func jsonFields(v any) (map[string]any, error) {
+	fields := map[string]any{}
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil()) {
+		return fields, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// redact hides the value of secret fields, and of secret fields nested in value
+func redact(field string, value any) any {
+	if value == nil {
+		return nil
+	}
+	if redactedFields[field] {
+		return redacted
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, nested := range v {
+			out[key] = redact(key, nested)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, nested := range v {
+			out[i] = redact("", nested)
+		}
+		return out
+	default:
+		return value
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+func Test_auditChanges(t *testing.T) {
+	host := &domain.Host{
+		ID:          1,
+		Name:        "web",
+		Domain:      "example.com",
+		Credentials: []domain.Credential{{Username: "root", Password: "secret"}},
+	}
+	renamed := *host
+	renamed.Name = "api"
+
+	tests := []struct {
+		name            string
+		before          any
+		after           any
+		expectedChanges map[string]domain.AuditChange
+	}{
+		{
+			name:   "Created",
+			before: nil,
+			after:  map[string]any{"name": "web", "ip": "10.0.0.1"},
+			expectedChanges: map[string]domain.AuditChange{
+				"name": {Before: nil, After: "web"},
+				"ip":   {Before: nil, After: "10.0.0.1"},
+			},
+		},
+		{
+			name:   "Deleted",
+			before: map[string]any{"name": "web"},
+			after:  nil,
+			expectedChanges: map[string]domain.AuditChange{
+				"name": {Before: "web", After: nil},
+			},
+		},
+		{
+			name:   "Only changed fields",
+			before: host,
+			after:  &renamed,
+			expectedChanges: map[string]domain.AuditChange{
+				"name": {Before: "web", After: "api"},
+			},
+		},
+		{
+			name:            "Nil pointer has no fields",
+			before:          (*domain.Host)(nil),
+			after:           (*domain.Host)(nil),
+			expectedChanges: map[string]domain.AuditChange{},
+		},
+		{
+			name:   "Credentials are redacted",
+			before: map[string]any{"credentials": []any{"old"}},
+			after:  map[string]any{"credentials": []any{"new"}},
+			expectedChanges: map[string]domain.AuditChange{
+				"credentials": {Before: redacted, After: redacted},
+			},
+		},
+		{
+			name:   "Nested passwords are redacted",
+			before: nil,
+			after:  map[string]any{"user": map[string]any{"email": "a@example.com", "password": "secret"}},
+			expectedChanges: map[string]domain.AuditChange{
+				"user": {Before: nil, After: map[string]any{"email": "a@example.com", "password": redacted}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := auditChanges(tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(changes, tt.expectedChanges) {
+				t.Errorf("expected %v, got %v", tt.expectedChanges, changes)
+			}
+		})
+	}
+}
@@ -79,7 +79,53 @@ func (s *AuthService) Login(email, password, applicationID string) (*fusionauth.
 
 }
 
-func (s *AuthService) RegisterTenant(tenantName string) (*domain.Tenant, *domain.User, error) {
+// RefreshToken exchanges a refresh token for a new access token through the
+// FusionAuth refresh grant. token is the expiring access token, it may be empty.
+func (s *AuthService) RefreshToken(refreshToken, token string) (*fusionauth.JWTRefreshResponse, error) {
+	client, err := s.NewFusionAuthClient()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshReq := fusionauth.RefreshRequest{
+		RefreshToken: refreshToken,
+		Token:        token,
+	}
+
+	refreshResponse, faErr, err := client.ExchangeRefreshTokenForJWT(refreshReq)
+	if err != nil {
+		return nil, err
+	}
+	if faErr != nil {
+		return nil, NewFaError(refreshResponse.StatusCode, faErr.Error())
+	}
+
+	return refreshResponse, nil
+}
+
+// Logout revokes the refresh token so it can't be exchanged anymore,
+// tokens that are already revoked are ignored
+func (s *AuthService) Logout(refreshToken string) error {
+	client, err := s.NewFusionAuthClient()
+	if err != nil {
+		return err
+	}
+
+	revokeResponse, faErr, err := client.RevokeRefreshTokenByToken(refreshToken)
+	if err != nil {
+		return err
+	}
+	if revokeResponse.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if faErr != nil {
+		return NewFaError(revokeResponse.StatusCode, faErr.Error())
+	}
+
+	return nil
+}
+
+func (s *AuthService) RegisterTenant(actor domain.Actor, tenantName string) (*domain.Tenant, *domain.User, error) {
 
 	client, err := s.NewFusionAuthClient()
 	if err != nil {
@@ -130,6 +176,9 @@ func (s *AuthService) RegisterTenant(tenantName string) (*domain.Tenant, *domain
 		return nil, nil, err
 	}
 
+	// Tenants register themselves, the change is made by their initial user
+	actor.TenantID, actor.UserID = domainTenant.ID, domainUser.ID
+	recordAudit(s.storage, actor, domain.AuditTenantRegistered, "tenant", domainTenant.ID, nil, domainTenant)
 	return domainTenant, domainUser, nil
 }
 
@@ -357,6 +406,7 @@ func scanIntoDomainUser(faUser fusionauth.User) (*domain.User, error) {
 	}
 
 	u := domain.NewUser(faUser.Id, faUser.Email, faUser.Password, faUser.TenantId, appID, roles, faUser.FirstName, faUser.LastName)
+	u.Active = faUser.Active
 	return u, nil
 }
 
@@ -398,7 +448,7 @@ func (s *AuthService) ForgotPassword(email, applicationID string) (*fusionauth.F
 	return forgotResponse, nil
 
 }
-func (s *AuthService) RegisterUser(firstname, lastname, email, password, applicationID string, roles []string) (*fusionauth.RegistrationResponse, error) {
+func (s *AuthService) RegisterUser(actor domain.Actor, firstname, lastname, email, password, applicationID string, roles []string) (*fusionauth.RegistrationResponse, error) {
 	client, err := s.NewFusionAuthClient()
 	if err != nil {
 		return nil, err
@@ -430,6 +480,15 @@ func (s *AuthService) RegisterUser(firstname, lastname, email, password, applica
 		return nil, NewFaError(registerResponse.StatusCode, faErr.Error())
 	}
 
+	// Users register themselves, unless an authenticated user registers them
+	if actor.TenantID == "" {
+		actor.TenantID = registerResponse.User.TenantId
+	}
+	if actor.UserID == "" {
+		actor.UserID = registerResponse.User.Id
+	}
+	after := domain.NewUser(registerResponse.User.Id, email, "", registerResponse.User.TenantId, applicationID, roles, firstname, lastname)
+	recordAudit(s.storage, actor, domain.AuditUserRegistered, "user", registerResponse.User.Id, nil, after)
 	return registerResponse, nil
 
 }
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+var (
+	ErrInvalidTag   = errors.New("invalid tag")
+	ErrTagExists    = errors.New("tag already exists")
+	ErrInvalidGroup = errors.New("invalid group")
+	ErrGroupExists  = errors.New("group already exists")
+)
+
+// tagName allows tags such as `prod`, `web-server` or `env:staging`
+var tagName = regexp.MustCompile(`^[a-z0-9][a-z0-9._:-]{0,63}$`)
+
+// GroupService organizes the hosts of a tenant with tags and named groups
+type GroupService struct {
+	storage interfaces.IStorage
+}
+
+var _ interfaces.IGroupService = (*GroupService)(nil)
+
+func NewGroupService(storage interfaces.IStorage) *GroupService {
+	return &GroupService{
+		storage: storage,
+	}
+}
+
+func (s *GroupService) CreateTag(actor domain.Actor, t *domain.Tag) (*domain.Tag, error) {
+	if err := s.validateTag(t); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.storage.CreateTag(t)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(s.storage, actor, domain.AuditTagCreated, "tag", tag.ID, nil, tag)
+	return tag, nil
+}
+
+func (s *GroupService) GetTagsByTenantID(tenantID string) ([]*domain.Tag, error) {
+	return s.storage.GetTagsByTenantID(tenantID)
+}
+
+// PatchTagByID renames a tag, hosts keep it under its new name
+func (s *GroupService) PatchTagByID(actor domain.Actor, t *domain.Tag) (*domain.Tag, error) {
+	before, err := s.storage.GetTagByID(t.ID, t.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateTag(t); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.storage.PatchTagByID(t)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(s.storage, actor, domain.AuditTagUpdated, "tag", tag.ID, before, tag)
+	return tag, nil
+}
+
+func (s *GroupService) DeleteTagByID(actor domain.Actor, ID string, tenantID string) (bool, error) {
+	before, err := s.storage.GetTagByID(ID, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	isDeleted, err := s.storage.DeleteTagByID(ID, tenantID)
+	if err != nil {
+		return false, err
+	}
+
+	if isDeleted {
+		recordAudit(s.storage, actor, domain.AuditTagDeleted, "tag", ID, before, nil)
+	}
+	return isDeleted, nil
+}
+
+// SetHostTags replaces the tags of a host, unknown tags are created for the tenant
+func (s *GroupService) SetHostTags(actor domain.Actor, hostID int, tenantID string, tags []string) ([]string, error) {
+	names := []string{}
+	for _, tag := range tags {
+		name := NormalizeTag(tag)
+		if !tagName.MatchString(name) {
+			return nil, fmt.Errorf("%q: %w", tag, ErrInvalidTag)
+		}
+		names = append(names, name)
+	}
+
+	host, err := s.storage.GetHostByID(hostID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	hostTags, err := s.storage.SetHostTags(hostID, tenantID, names)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(s.storage, actor, domain.AuditHostTagsUpdated, "host", strconv.Itoa(hostID),
+		map[string][]string{"tags": host.Tags}, map[string][]string{"tags": hostTags})
+	return hostTags, nil
+}
+
+func (s *GroupService) CreateHostGroup(actor domain.Actor, g *domain.HostGroup) (*domain.HostGroup, error) {
+	if err := s.validateHostGroup(g); err != nil {
+		return nil, err
+	}
+
+	group, err := s.storage.CreateHostGroup(g)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(s.storage, actor, domain.AuditGroupCreated, "group", group.ID, nil, group)
+	return group, nil
+}
+
+func (s *GroupService) GetHostGroupsByTenantID(tenantID string) ([]*domain.HostGroup, error) {
+	return s.storage.GetHostGroupsByTenantID(tenantID)
+}
+
+func (s *GroupService) GetHostGroupByID(ID string, tenantID string) (*domain.HostGroup, error) {
+	return s.storage.GetHostGroupByID(ID, tenantID)
+}
+
+func (s *GroupService) PatchHostGroupByID(actor domain.Actor, g *domain.HostGroup) (*domain.HostGroup, error) {
+	before, err := s.storage.GetHostGroupByID(g.ID, g.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateHostGroup(g); err != nil {
+		return nil, err
+	}
+
+	group, err := s.storage.PatchHostGroupByID(g)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(s.storage, actor, domain.AuditGroupUpdated, "group", group.ID, before, group)
+	return group, nil
+}
+
+func (s *GroupService) DeleteHostGroupByID(actor domain.Actor, ID string, tenantID string) (bool, error) {
+	before, err := s.storage.GetHostGroupByID(ID, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	isDeleted, err := s.storage.DeleteHostGroupByID(ID, tenantID)
+	if err != nil {
+		return false, err
+	}
+
+	if isDeleted {
+		recordAudit(s.storage, actor, domain.AuditGroupDeleted, "group", ID, before, nil)
+	}
+	return isDeleted, nil
+}
+
+// NormalizeTag trims and lowercases a tag so `Prod` and `prod` are the same tag
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+func (s *GroupService) validateTag(t *domain.Tag) error {
+	t.Name = NormalizeTag(t.Name)
+	if !tagName.MatchString(t.Name) {
+		msg := "tags are up to 64 lowercase letters, digits, `.`, `_`, `:` and `-`"
+		return fmt.Errorf("%q: %w", msg, ErrInvalidTag)
+	}
+
+	exists, err := s.storage.ExistsTagName(t.TenantID, t.Name, t.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%q: %w", t.Name, ErrTagExists)
+	}
+
+	return nil
+}
+
+func (s *GroupService) validateHostGroup(g *domain.HostGroup) error {
+	g.Name = strings.TrimSpace(g.Name)
+	if g.Name == "" {
+		return fmt.Errorf("%q: %w", "name is required", ErrInvalidGroup)
+	}
+
+	exists, err := s.storage.ExistsHostGroupName(g.TenantID, g.Name, g.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%q: %w", g.Name, ErrGroupExists)
+	}
+
+	for _, hostID := range g.HostIDs {
+		if _, err := s.storage.GetHostByID(hostID, g.TenantID); err != nil {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("unknown host `%d`", hostID), ErrInvalidGroup)
+		}
+	}
+
+	return nil
+}
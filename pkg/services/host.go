@@ -2,10 +2,16 @@ package services
 
 import (
 	"crypto/tls"
+	"database/sql"
 	"errors"
+	"fmt"
 	"log"
 	"net"
+	"net/netip"
+	"net/url"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,6 +19,7 @@ import (
 	cmmn "github.com/kptm-tools/common/common/events"
 	"github.com/kptm-tools/core-service/pkg/domain"
 	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/keyring"
 	probing "github.com/prometheus-community/pro-bing"
 )
 
@@ -20,53 +27,149 @@ var (
 	ErrInvalidHostValue = errors.New("invalid host")
 	ErrHostUnhealthy    = errors.New("unable to connect to host")
 	ErrAliasTaken       = errors.New("alias is taken")
+	ErrForbiddenOwner   = errors.New("operators can only list their own hosts")
+	ErrInvalidOwner     = errors.New("owner must be an operator of the tenant")
+	ErrForbiddenDeleted = errors.New("only admins can list deleted hosts")
 )
 
 type HostService struct {
-	storage interfaces.IStorage
+	storage     interfaces.IStorage
+	users       interfaces.IAuthService
+	keyring     *keyring.Keyring
+	importLimit int
 }
 
 var _ interfaces.IHostService = (*HostService)(nil)
 
-func NewHostService(storage interfaces.IStorage) *HostService {
+// NewHostService creates the host service, users looks up the operators hosts
+// are transferred to and importLimit is the most hosts a single import may
+// create once CIDR blocks are expanded
+func NewHostService(storage interfaces.IStorage, users interfaces.IAuthService, keyring *keyring.Keyring, importLimit int) *HostService {
 	return &HostService{
-		storage: storage,
+		storage:     storage,
+		users:       users,
+		keyring:     keyring,
+		importLimit: importLimit,
 	}
 }
 
-func (s *HostService) CreateHost(t *domain.Host) (*domain.Host, error) {
+func (s *HostService) CreateHost(actor domain.Actor, t *domain.Host) (*domain.Host, error) {
+	credentials, err := s.sealCredentials(t.Credentials, false)
+	if err != nil {
+		return nil, err
+	}
+	t.Credentials = credentials
+
+	host, err := s.storage.CreateHost(t)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.storage.CreateHost(t)
+	recordAudit(s.storage, actor, domain.AuditHostCreated, "host", strconv.Itoa(host.ID), nil, host)
+	return host, nil
 }
 
-func (s *HostService) GetHostsByTenantIDAndUserID(tenantID string, userID string) ([]*domain.Host, error) {
+// GetHosts lists the hosts userID can see. Admins and analysts see every host
+// of the tenant, or those of filter.Owner, operators only see their own.
+// Only admins list deleted hosts.
+func (s *HostService) GetHosts(filter domain.HostFilter, userID string, roles []domain.Role) (*domain.HostPage, error) {
+	if filter.Deleted && !slices.Contains(roles, domain.RoleAdmin) {
+		return nil, ErrForbiddenDeleted
+	}
+	if len(domain.ContainsRole(roles, []domain.Role{domain.RoleAdmin, domain.RoleAnalyst})) > 0 {
+		filter.OperatorID = filter.Owner
+	} else {
+		if filter.Owner != "" && filter.Owner != userID {
+			return nil, ErrForbiddenOwner
+		}
+		filter.OperatorID = userID
+	}
+
+	for i, tag := range filter.Tags {
+		filter.Tags[i] = NormalizeTag(tag)
+	}
 
-	hosts, err := s.storage.GetHostsByTenantIDAndUserID(tenantID, userID)
+	page, err := s.storage.GetHosts(filter)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return hosts, nil
+	return page, nil
 }
 
-func (s *HostService) GetHostByID(ID int) (*domain.Host, error) {
-	host, err := s.storage.GetHostByID(ID)
-
+// GetHostByID returns the host when userID can see it under the rule of
+// GetHosts, hosts of other operators are reported as missing
+func (s *HostService) GetHostByID(ID int, tenantID string, userID string, roles []domain.Role) (*domain.Host, error) {
+	host, err := s.storage.GetHostByID(ID, tenantID)
 	if err != nil {
 		return nil, err
 	}
+	if !canSeeHost(host, userID, roles) {
+		return nil, fmt.Errorf("failed to fetch host: %w", sql.ErrNoRows)
+	}
 
 	return host, nil
 }
 
-func (s *HostService) DeleteHostByID(ID int) (bool, error) {
-	isDeleted, err := s.storage.DeleteHostByID(ID)
+// canSeeHost tells if userID sees the host, admins and analysts see every
+// host of the tenant and operators only their own
+func canSeeHost(host *domain.Host, userID string, roles []domain.Role) bool {
+	if len(domain.ContainsRole(roles, []domain.Role{domain.RoleAdmin, domain.RoleAnalyst})) > 0 {
+		return true
+	}
+	return host.OperatorID == userID
+}
 
+// TransferHost makes operatorID, an operator of the tenant, the owner of the host
+func (s *HostService) TransferHost(actor domain.Actor, ID int, tenantID string, operatorID string) (*domain.Host, error) {
+	host, err := s.storage.GetHostByID(ID, tenantID)
 	if err != nil {
+		return nil, err
+	}
+	if host.OperatorID == operatorID {
+		return host, nil
+	}
+
+	user, err := s.users.GetUserByID(operatorID, &tenantID)
+	if err != nil {
+		var faErr *FaError
+		if errors.As(err, &faErr) {
+			return nil, fmt.Errorf("%q: %w", faErr.Error(), ErrInvalidOwner)
+		}
+		return nil, err
+	}
+	if !slices.Contains(user.Roles, domain.RoleOperator.String()) {
+		return nil, fmt.Errorf("%q: %w", "user is not an operator", ErrInvalidOwner)
+	}
+
+	transferred, err := s.storage.TransferHostByID(ID, tenantID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(s.storage, actor, domain.AuditHostTransferred, "host", strconv.Itoa(ID), host, transferred)
+	return transferred, nil
+}
+
+func (s *HostService) DeleteHostByID(actor domain.Actor, ID int, tenantID string) (bool, error) {
+	host, err := s.GetHostByID(ID, tenantID, actor.UserID, actor.Roles)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
 		return false, err
 	}
 
+	isDeleted, err := s.storage.DeleteHostByID(ID, tenantID)
+
+	if err != nil {
+		return false, err
+	}
+
+	if isDeleted {
+		recordAudit(s.storage, actor, domain.AuditHostDeleted, "host", strconv.Itoa(ID), host, nil)
+	}
 	return isDeleted, nil
 }
 
@@ -100,22 +203,92 @@ func (s *HostService) GetHostname(ipPort string) string {
 	return domainname
 }
 
-func (s *HostService) PatchHostByID(h *domain.Host) (*domain.Host, error) {
+// RestoreHost brings back a deleted host that hasn't been purged yet
+func (s *HostService) RestoreHost(actor domain.Actor, ID int, tenantID string) (*domain.Host, error) {
+	host, err := s.storage.RestoreHostByID(ID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(s.storage, actor, domain.AuditHostRestored, "host", strconv.Itoa(ID), nil, host)
+	return host, nil
+}
+
+// PurgeDeletedHosts removes for good the hosts deleted longer than retention ago
+func (s *HostService) PurgeDeletedHosts(retention time.Duration) (int64, error) {
+	return s.storage.PurgeDeletedHosts(time.Now().Add(-retention))
+}
+
+func (s *HostService) PatchHostByID(actor domain.Actor, h *domain.Host) (*domain.Host, error) {
+	before, err := s.GetHostByID(h.ID, h.TenantID, actor.UserID, actor.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	// Credentials sent without a password keep the stored one
+	credentials, err := s.sealCredentials(h.Credentials, true)
+	if err != nil {
+		return nil, err
+	}
+	h.Credentials = credentials
+
 	host, err := s.storage.PatchHostByID(h)
 
 	if err != nil {
 		return nil, err
 	}
 
+	recordAudit(s.storage, actor, domain.AuditHostUpdated, "host", strconv.Itoa(host.ID), before, host)
 	return host, nil
 }
 
+// RotateCredentials re-encrypts every stored credential with the active key.
+// legacyPassphrase decrypts credentials stored before the keyring existed.
+func (s *HostService) RotateCredentials(legacyPassphrase string) (int, error) {
+	return s.storage.RotateCredentials(legacyPassphrase, func(c *domain.Credential) error {
+		if c.KeyID == s.keyring.ActiveKeyID() {
+			return nil
+		}
+
+		plaintext := c.Password
+		if c.KeyID != "" {
+			var err error
+			if plaintext, err = s.keyring.Decrypt(c.KeyID, c.Password); err != nil {
+				return err
+			}
+		}
+
+		keyID, ciphertext, err := s.keyring.Encrypt(plaintext)
+		if err != nil {
+			return err
+		}
+		c.KeyID, c.Password = keyID, ciphertext
+		return nil
+	})
+}
+
+// sealCredentials encrypts the passwords before they reach storage
+func (s *HostService) sealCredentials(credentials []domain.Credential, keepEmpty bool) ([]domain.Credential, error) {
+	sealed := make([]domain.Credential, 0, len(credentials))
+	for _, c := range credentials {
+		if c.Password == "" && keepEmpty {
+			sealed = append(sealed, c)
+			continue
+		}
+		keyID, ciphertext, err := s.keyring.Encrypt(c.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt credential: %w", err)
+		}
+		c.KeyID, c.Password = keyID, ciphertext
+		sealed = append(sealed, c)
+	}
+	return sealed, nil
+}
+
 func (s *HostService) ValidateHost(host string) error {
 
 	if IsValidHostValue(host) {
-		normalizedHost := cmmn.NormalizeURL(host)
-		addr := strings.Split(normalizedHost, "//")[1]
-		pinger, err := probing.NewPinger(addr)
+		pinger, err := probing.NewPinger(probeAddress(host))
 		if err != nil {
 			log.Printf("failed to probe host: %v", err)
 			return ErrHostUnhealthy
@@ -136,8 +309,26 @@ func (s *HostService) ValidateHost(host string) error {
 	}
 }
 
+// probeAddress returns the address to ping for a valid host value, without
+// protocol, brackets or port
+func probeAddress(value string) string {
+	if addr, ok := ParseIPHost(value); ok {
+		return addr.String()
+	}
+	u, err := url.Parse(cmmn.NormalizeURL(value))
+	if err != nil {
+		return value
+	}
+	return u.Hostname()
+}
+
 func IsValidHostValue(value string) bool {
 
+	// IP address, with or without protocol prefix and port
+	if _, ok := ParseIPHost(value); ok {
+		return true
+	}
+
 	normalizedValue := cmmn.NormalizeURL(value)
 	if cmmn.IsURL(normalizedValue) {
 		domain, err := cmmn.ExtractDomain(normalizedValue)
@@ -150,21 +341,47 @@ func IsValidHostValue(value string) bool {
 		if IsValidDomain(domain) {
 			return true
 		}
+	}
 
-		// IP address with protocol prefix
-		if cmmn.IsValidIPv4(strings.Split(normalizedValue, "//")[1]) {
-			return true
+	log.Println("Invalid IP:", value)
+	return false
+
+}
+
+// ParseIPHost returns the IPv4 or IPv6 address of value. The address may have a
+// protocol prefix and a port, IPv6 addresses with a port must be bracketed,
+// e.g. `[2001:db8::1]:8443`. Zoned addresses aren't valid hosts.
+func ParseIPHost(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+
+	if strings.Contains(value, "://") {
+		u, err := url.Parse(value)
+		if err != nil {
+			return netip.Addr{}, false
 		}
+		value = u.Host
 	}
 
-	// IP address on its own
-	if cmmn.IsValidIPv4(value) {
-		return true
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		addrPort, portErr := netip.ParseAddrPort(value)
+		if portErr != nil {
+			if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+				return netip.Addr{}, false
+			}
+			if addr, err = netip.ParseAddr(value[1 : len(value)-1]); err != nil || !addr.Is6() {
+				return netip.Addr{}, false
+			}
+		} else {
+			addr = addrPort.Addr()
+		}
 	}
 
-	log.Println("Invalid IP:", value)
-	return false
+	if addr.Zone() != "" {
+		return netip.Addr{}, false
+	}
 
+	return addr.Unmap(), true
 }
 
 func IsValidDomain(domain string) bool {
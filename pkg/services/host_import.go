@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	cmmn "github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+var ErrEmptyImport = errors.New("no hosts to import")
+
+// importCandidate is a host an import row expands to, result is its index in the report
+type importCandidate struct {
+	host   *domain.Host
+	result int
+}
+
+// ImportHosts creates the hosts of rows in a single transaction. CIDR blocks are
+// expanded to a host per address, up to the import limit of the service. Invalid
+// rows and hosts whose alias is taken don't fail the import, they're reported.
+func (s *HostService) ImportHosts(actor domain.Actor, tenantID string, operatorID string, rows []domain.HostImportRow) (*domain.HostImportReport, error) {
+	if len(rows) == 0 {
+		return nil, ErrEmptyImport
+	}
+
+	report := &domain.HostImportReport{Results: []domain.HostImportResult{}}
+	candidates := []importCandidate{}
+	aliases := map[string]bool{}
+
+	for _, row := range rows {
+		hosts, err := expandImportRow(row, s.importLimit-len(candidates))
+		if err != nil {
+			report.Results = append(report.Results, domain.HostImportResult{
+				Row:    row.Row,
+				Value:  row.Value,
+				Alias:  row.Name,
+				Status: domain.HostImportInvalid,
+				Reason: err.Error(),
+			})
+			continue
+		}
+
+		credentials, err := s.sealCredentials(row.Credentials, false)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, h := range hosts {
+			result := domain.HostImportResult{Row: row.Row, Value: row.Value, Alias: h.Name}
+			if aliases[h.Name] {
+				result.Status = domain.HostImportSkipped
+				result.Reason = "duplicate alias in import"
+				report.Results = append(report.Results, result)
+				continue
+			}
+			aliases[h.Name] = true
+
+			h.TenantID, h.OperatorID = tenantID, operatorID
+			h.Credentials, h.Rapporteurs = credentials, row.Rapporteurs
+			candidates = append(candidates, importCandidate{host: h, result: len(report.Results)})
+			report.Results = append(report.Results, result)
+		}
+	}
+
+	hosts := make([]*domain.Host, 0, len(candidates))
+	for _, c := range candidates {
+		hosts = append(hosts, c.host)
+	}
+	if len(hosts) > 0 {
+		created, err := s.storage.CreateHosts(tenantID, hosts)
+		if err != nil {
+			return nil, err
+		}
+		for i, c := range candidates {
+			result := &report.Results[c.result]
+			if created[i] == nil {
+				result.Status = domain.HostImportSkipped
+				result.Reason = ErrAliasTaken.Error()
+				continue
+			}
+			result.Status = domain.HostImportCreated
+			result.HostID = created[i].ID
+			recordAudit(s.storage, actor, domain.AuditHostImported, "host", strconv.Itoa(created[i].ID), nil, created[i])
+		}
+	}
+
+	for _, r := range report.Results {
+		switch r.Status {
+		case domain.HostImportCreated:
+			report.Created++
+		case domain.HostImportSkipped:
+			report.Skipped++
+		case domain.HostImportInvalid:
+			report.Invalid++
+		}
+	}
+
+	return report, nil
+}
+
+// expandImportRow returns the hosts of a row, at most limit of them. Domains and IPs
+// are a single host aliased by the row name, or by the value when there's no name.
+// CIDR blocks are a host per address aliased `name-address`.
+func expandImportRow(row domain.HostImportRow, limit int) ([]*domain.Host, error) {
+	value := strings.TrimSpace(row.Value)
+	name := strings.TrimSpace(row.Name)
+	if value == "" {
+		return nil, fmt.Errorf("value is required")
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("import limit reached")
+	}
+
+	// Only an address before the slash makes a CIDR block, `example.com/path` is a host
+	if isCIDRValue(value) {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR block: %s", value)
+		}
+		addrs, err := expandPrefix(prefix, limit)
+		if err != nil {
+			return nil, err
+		}
+
+		hosts := []*domain.Host{}
+		for _, addr := range addrs {
+			alias := addr.String()
+			if name != "" {
+				alias = name + "-" + alias
+			}
+			hosts = append(hosts, domain.NewHost("", addr.String(), "", "", alias, nil, nil))
+		}
+		return hosts, nil
+	}
+
+	if addr, ok := ParseIPHost(value); ok {
+		return []*domain.Host{domain.NewHost("", addr.String(), "", "", firstNonEmpty(name, addr.String()), nil, nil)}, nil
+	}
+
+	if !IsValidHostValue(value) {
+		return nil, ErrInvalidHostValue
+	}
+	hostDomain, err := cmmn.ExtractDomain(cmmn.NormalizeURL(value))
+	if err != nil {
+		return nil, ErrInvalidHostValue
+	}
+	return []*domain.Host{domain.NewHost(hostDomain, "", "", "", firstNonEmpty(name, hostDomain), nil, nil)}, nil
+}
+
+// isCIDRValue reports whether the value is an address followed by a slash
+func isCIDRValue(value string) bool {
+	addr, _, found := strings.Cut(value, "/")
+	if !found {
+		return false
+	}
+	_, err := netip.ParseAddr(addr)
+	return err == nil
+}
+
+// expandPrefix lists the addresses of an IPv4 or IPv6 block. The network and
+// broadcast addresses of IPv4 blocks larger than a /31 aren't hosts.
+func expandPrefix(prefix netip.Prefix, limit int) ([]netip.Addr, error) {
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+
+	size := 0
+	if hostBits < 31 {
+		size = 1 << hostBits
+	}
+	skipEnds := prefix.Addr().Is4() && hostBits > 1
+	if skipEnds {
+		size -= 2
+	}
+	if size <= 0 || size > limit {
+		return nil, fmt.Errorf("CIDR block %s exceeds the import limit, %d hosts left", prefix, limit)
+	}
+
+	addrs := make([]netip.Addr, 0, size)
+	addr := prefix.Addr()
+	if skipEnds {
+		addr = addr.Next()
+	}
+	for len(addrs) < size {
+		addrs = append(addrs, addr)
+		addr = addr.Next()
+	}
+
+	return addrs, nil
+}
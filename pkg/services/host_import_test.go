@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+func Test_expandImportRow(t *testing.T) {
+	tests := []struct {
+		name            string
+		row             domain.HostImportRow
+		expectedAliases []string
+		expectedErr     error
+		expectedErrText string
+	}{
+		{
+			name:            "IP address",
+			row:             domain.HostImportRow{Value: "10.0.0.1", Name: "db"},
+			expectedAliases: []string{"db"},
+		},
+		{
+			name:            "Domain",
+			row:             domain.HostImportRow{Value: "https://example.com"},
+			expectedAliases: []string{"example.com"},
+		},
+		{
+			name:            "CIDR block",
+			row:             domain.HostImportRow{Value: "10.0.0.0/30", Name: "lab"},
+			expectedAliases: []string{"lab-10.0.0.1", "lab-10.0.0.2"},
+		},
+		{
+			name:            "Invalid CIDR block",
+			row:             domain.HostImportRow{Value: "10.0.0.0/33"},
+			expectedErrText: "invalid CIDR block",
+		},
+		{
+			name:            "Domain with a path isn't a CIDR block",
+			row:             domain.HostImportRow{Value: "example.com/path"},
+			expectedAliases: []string{"example.com"},
+		},
+		{
+			name:        "Invalid host with a slash isn't a CIDR block",
+			row:         domain.HostImportRow{Value: "not a host/24"},
+			expectedErr: ErrInvalidHostValue,
+		},
+		{
+			name:        "Invalid host",
+			row:         domain.HostImportRow{Value: "not a host"},
+			expectedErr: ErrInvalidHostValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts, err := expandImportRow(tt.row, 10)
+			if tt.expectedErr != nil || tt.expectedErrText != "" {
+				if tt.expectedErr != nil && !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("Expected error `%v`, got `%v`", tt.expectedErr, err)
+				}
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErrText) {
+					t.Fatalf("Expected error `%s`, got `%v`", tt.expectedErrText, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			aliases := []string{}
+			for _, h := range hosts {
+				aliases = append(aliases, h.Name)
+			}
+			if !reflect.DeepEqual(aliases, tt.expectedAliases) {
+				t.Errorf("Expected aliases `%v`, got `%v`", tt.expectedAliases, aliases)
+			}
+		})
+	}
+}
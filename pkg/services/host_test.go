@@ -0,0 +1,245 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+func Test_ParseIPHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		valid    bool
+	}{
+		{name: "IPv4", input: "192.168.0.1", expected: "192.168.0.1", valid: true},
+		{name: "IPv4 with port", input: "192.168.0.1:8080", expected: "192.168.0.1", valid: true},
+		{name: "IPv4 with protocol", input: "https://192.168.0.1/login", expected: "192.168.0.1", valid: true},
+		{name: "IPv6", input: "2001:db8::1", expected: "2001:db8::1", valid: true},
+		{name: "IPv6 expanded", input: "2001:0db8:0000:0000:0000:0000:0000:0001", expected: "2001:db8::1", valid: true},
+		{name: "IPv6 bracketed", input: "[2001:db8::1]", expected: "2001:db8::1", valid: true},
+		{name: "IPv6 bracketed with port", input: "[2001:db8::1]:8443", expected: "2001:db8::1", valid: true},
+		{name: "IPv6 with protocol and port", input: "http://[2001:db8::1]:8080/", expected: "2001:db8::1", valid: true},
+		{name: "IPv4 mapped IPv6", input: "::ffff:10.0.0.1", expected: "10.0.0.1", valid: true},
+		{name: "IPv6 with port and no brackets", input: "2001:db8:0:0:0:0:0:1:8443", valid: false},
+		{name: "IPv6 with zone", input: "fe80::1%eth0", valid: false},
+		{name: "Bracketed IPv4", input: "[192.168.0.1]", valid: false},
+		{name: "Domain", input: "example.com", valid: false},
+		{name: "Invalid IPv4", input: "192.168.0.256", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, ok := ParseIPHost(tt.input)
+			if ok != tt.valid {
+				t.Fatalf("Expected valid `%v`, got `%v`", tt.valid, ok)
+			}
+			if ok && addr.String() != tt.expected {
+				t.Errorf("Expected `%s`, got `%s`", tt.expected, addr.String())
+			}
+		})
+	}
+}
+
+func Test_IsValidHostValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{input: "example.com", expected: true},
+		{input: "https://example.com", expected: true},
+		{input: "10.0.0.1", expected: true},
+		{input: "2001:db8::1", expected: true},
+		{input: "[2001:db8::1]:443", expected: true},
+		{input: "https://[2001:db8::1]", expected: true},
+		{input: "not a host", expected: false},
+		{input: "2001:db8::g", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := IsValidHostValue(tt.input); got != tt.expected {
+				t.Errorf("Expected `%v`, got `%v`", tt.expected, got)
+			}
+		})
+	}
+}
+
+func Test_createTarget(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     domain.Host
+		expected string
+		kind     enums.TargetType
+	}{
+		{name: "IPv6 host", host: domain.Host{Name: "v6", IP: "2001:db8::1"}, expected: "2001:db8::1", kind: enums.IP},
+		{name: "Domain host", host: domain.Host{Name: "web", Domain: "example.com", IP: "2001:db8::1"}, expected: "example.com", kind: enums.Domain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := createTarget(tt.host)
+			if target.Value != tt.expected || target.Type != tt.kind {
+				t.Errorf("Expected `%s` `%s`, got `%s` `%s`", tt.kind, tt.expected, target.Type, target.Value)
+			}
+		})
+	}
+}
+
+const (
+	adminID    = "aaaaaaaa-0000-0000-0000-000000000001"
+	operatorID = "aaaaaaaa-0000-0000-0000-000000000002"
+	analystID  = "aaaaaaaa-0000-0000-0000-000000000003"
+)
+
+// ownerStorage records the last host filter and owns a single host
+type ownerStorage struct {
+	interfaces.IStorage
+	filter domain.HostFilter
+	host   *domain.Host
+	events []*domain.AuditEvent
+}
+
+func (s *ownerStorage) CreateAuditEvent(e *domain.AuditEvent) error {
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *ownerStorage) GetHosts(filter domain.HostFilter) (*domain.HostPage, error) {
+	s.filter = filter
+	return &domain.HostPage{Hosts: []*domain.Host{}}, nil
+}
+
+func (s *ownerStorage) GetHostByID(ID int, tenantID string) (*domain.Host, error) {
+	if s.host == nil || s.host.ID != ID || s.host.TenantID != tenantID {
+		return nil, fmt.Errorf("failed to fetch host: %w", sql.ErrNoRows)
+	}
+	return s.host, nil
+}
+
+func (s *ownerStorage) TransferHostByID(ID int, tenantID string, operatorID string) (*domain.Host, error) {
+	transferred := *s.host
+	transferred.OperatorID = operatorID
+	s.host = &transferred
+	return s.host, nil
+}
+
+// userDirectory knows the users of a single tenant
+type userDirectory struct {
+	interfaces.IAuthService
+	users map[string]*domain.User
+}
+
+func (d *userDirectory) GetUserByID(userID string, tenantID *string) (*domain.User, error) {
+	user, ok := d.users[userID]
+	if !ok {
+		return nil, NewFaError(http.StatusNotFound, "user not found")
+	}
+	return user, nil
+}
+
+func Test_GetHostsVisibility(t *testing.T) {
+	tests := []struct {
+		name               string
+		userID             string
+		roles              []domain.Role
+		owner              string
+		deleted            bool
+		expectedOperatorID string
+		expectedErr        error
+	}{
+		{name: "Admin sees every host", userID: adminID, roles: []domain.Role{domain.RoleAdmin}, expectedOperatorID: ""},
+		{name: "Analyst sees every host", userID: analystID, roles: []domain.Role{domain.RoleAnalyst}, expectedOperatorID: ""},
+		{name: "Admin filters by owner", userID: adminID, roles: []domain.Role{domain.RoleAdmin}, owner: operatorID, expectedOperatorID: operatorID},
+		{name: "Operator sees own hosts", userID: operatorID, roles: []domain.Role{domain.RoleOperator}, expectedOperatorID: operatorID},
+		{name: "Operator filters by self", userID: operatorID, roles: []domain.Role{domain.RoleOperator}, owner: operatorID, expectedOperatorID: operatorID},
+		{name: "Operator filters by another owner", userID: operatorID, roles: []domain.Role{domain.RoleOperator}, owner: adminID, expectedErr: ErrForbiddenOwner},
+		{name: "Operator that is also an analyst", userID: operatorID, roles: []domain.Role{domain.RoleOperator, domain.RoleAnalyst}, expectedOperatorID: ""},
+		{name: "No roles", userID: operatorID, roles: nil, expectedOperatorID: operatorID},
+		{name: "Admin lists deleted hosts", userID: adminID, roles: []domain.Role{domain.RoleAdmin}, deleted: true, expectedOperatorID: ""},
+		{name: "Analyst lists deleted hosts", userID: analystID, roles: []domain.Role{domain.RoleAnalyst}, deleted: true, expectedErr: ErrForbiddenDeleted},
+		{name: "Operator lists deleted hosts", userID: operatorID, roles: []domain.Role{domain.RoleOperator}, deleted: true, expectedErr: ErrForbiddenDeleted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &ownerStorage{}
+			s := NewHostService(storage, nil, nil, 0)
+
+			_, err := s.GetHosts(domain.HostFilter{TenantID: "tenant-1", Owner: tt.owner, Deleted: tt.deleted}, tt.userID, tt.roles)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("Expected error `%v`, got `%v`", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if storage.filter.OperatorID != tt.expectedOperatorID {
+				t.Errorf("Expected operator `%s`, got `%s`", tt.expectedOperatorID, storage.filter.OperatorID)
+			}
+		})
+	}
+}
+
+func Test_TransferHost(t *testing.T) {
+	users := &userDirectory{users: map[string]*domain.User{
+		adminID:    {ID: adminID, Roles: []string{"admin"}},
+		operatorID: {ID: operatorID, Roles: []string{"operator"}},
+	}}
+
+	tests := []struct {
+		name          string
+		hostID        int
+		newOwnerID    string
+		expectedOwner string
+		expectedErr   error
+	}{
+		{name: "Transfer to an operator", hostID: 1, newOwnerID: operatorID, expectedOwner: operatorID},
+		{name: "Transfer to the current owner", hostID: 1, newOwnerID: analystID, expectedOwner: analystID},
+		{name: "Transfer to a user that isn't an operator", hostID: 1, newOwnerID: adminID, expectedErr: ErrInvalidOwner},
+		{name: "Transfer to an unknown user", hostID: 1, newOwnerID: "aaaaaaaa-0000-0000-0000-000000000009", expectedErr: ErrInvalidOwner},
+		{name: "Transfer a missing host", hostID: 2, newOwnerID: operatorID, expectedErr: sql.ErrNoRows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &ownerStorage{host: &domain.Host{ID: 1, TenantID: "tenant-1", OperatorID: analystID}}
+			s := NewHostService(storage, users, nil, 0)
+
+			host, err := s.TransferHost(domain.Actor{TenantID: "tenant-1", UserID: adminID}, tt.hostID, "tenant-1", tt.newOwnerID)
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("Expected error `%v`, got `%v`", tt.expectedErr, err)
+				}
+				if storage.host.OperatorID != analystID {
+					t.Errorf("Expected the owner to be unchanged, got `%s`", storage.host.OperatorID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if host.OperatorID != tt.expectedOwner {
+				t.Errorf("Expected owner `%s`, got `%s`", tt.expectedOwner, host.OperatorID)
+			}
+			if tt.expectedOwner != analystID {
+				if len(storage.events) != 1 || storage.events[0].Action != domain.AuditHostTransferred {
+					t.Fatalf("Expected a transfer audit event, got %+v", storage.events)
+				}
+				change := storage.events[0].Changes["user_id"]
+				if change.Before != analystID || change.After != tt.expectedOwner {
+					t.Errorf("Expected owner change `%s` -> `%s`, got %+v", analystID, tt.expectedOwner, change)
+				}
+			}
+		})
+	}
+}
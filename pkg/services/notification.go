@@ -0,0 +1,344 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+var ErrInvalidTemplate = errors.New("invalid notification template")
+
+const (
+	DefaultNotificationSubject = `[Kriptome] Scan {{.Status}} for {{.Host.Alias}}`
+	DefaultNotificationBody    = `Hello {{.Rapporteur.Name}},
+
+The scan {{.ScanID}} of {{.Host.Alias}}{{with .Host.Domain}} ({{.}}){{end}} finished with status {{.Status}}.
+{{range .Services}}
+- {{.Service}}: {{.Progress}}, {{.Findings}} findings{{with .Error}} (error: {{.}}){{end}}{{end}}
+
+You are receiving this email because you are a rapporteur of {{.Host.Alias}}.
+`
+)
+
+type NotificationService struct {
+	storage     interfaces.IStorage
+	notifier    interfaces.INotifier
+	maxAttempts int
+}
+
+var _ interfaces.INotificationService = (*NotificationService)(nil)
+
+func NewNotificationService(storage interfaces.IStorage, notifier interfaces.INotifier, maxAttempts int) *NotificationService {
+	return &NotificationService{
+		storage:     storage,
+		notifier:    notifier,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// notificationData is what tenant templates are executed against
+type notificationData struct {
+	ScanID     string
+	Status     domain.ScanStatus
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Host       domain.HostReport
+	Rapporteur domain.Rapporteur
+	Services   []serviceSummary
+}
+
+type serviceSummary struct {
+	Service  enums.ServiceName
+	Progress string
+	Error    string
+	Findings int
+}
+
+// NotifyScanFinished emails a summary of a finished scan to the rapporteurs of each
+// scanned host. Rapporteurs already notified for the scan are skipped, so it's
+// safe to call more than once; failed notifications are sent again until they
+// run out of attempts. A failure for one rapporteur doesn't stop the others,
+// the failures are returned together.
+func (s *NotificationService) NotifyScanFinished(scanID string, tenantID string) ([]*domain.Notification, error) {
+	scan, err := s.storage.GetScanByID(scanID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !scan.Status.IsTerminal() {
+		return nil, fmt.Errorf("%q: scan is not finished", scan.Status.String())
+	}
+
+	tmpl, err := s.GetNotificationTemplate(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	subject, body, err := parseNotificationTemplate(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := []string{}
+	for _, t := range scan.Targets {
+		aliases = append(aliases, t.Alias)
+	}
+	hosts, err := s.storage.GetHostsByAliases(tenantID, aliases)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hosts: %w", err)
+	}
+	report := buildScanReport(scan, hosts)
+
+	sent := []*domain.Notification{}
+	errs := []error{}
+	for _, host := range report.Hosts {
+		summaries, err := summarizeServices(host)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to summarize `%s`: %w", host.Alias, err))
+			continue
+		}
+
+		for _, rapporteur := range host.Rapporteurs {
+			if _, err := mail.ParseAddress(rapporteur.Email); err != nil {
+				log.Printf("Skipping rapporteur of `%s` with invalid email `%s`", host.Alias, rapporteur.Email)
+				continue
+			}
+			data := notificationData{
+				ScanID:     report.ScanID,
+				Status:     report.Status,
+				CreatedAt:  report.CreatedAt,
+				UpdatedAt:  report.UpdatedAt,
+				Host:       host,
+				Rapporteur: rapporteur,
+				Services:   summaries,
+			}
+
+			n, err := s.notify(tenantID, data, subject, body)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to notify `%s` about `%s`: %w", rapporteur.Email, host.Alias, err))
+				continue
+			}
+			if n != nil {
+				sent = append(sent, n)
+			}
+		}
+	}
+
+	return sent, errors.Join(errs...)
+}
+
+// RetryFailedNotifications notifies again the scans of every tenant that have
+// failed notifications with attempts left
+func (s *NotificationService) RetryFailedNotifications() ([]*domain.Notification, error) {
+	failed, err := s.storage.GetRetryableNotifications(s.maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+
+	type scanKey struct{ scanID, tenantID string }
+	retried := map[scanKey]bool{}
+	sent := []*domain.Notification{}
+	errs := []error{}
+	for _, n := range failed {
+		key := scanKey{n.ScanID, n.TenantID}
+		if retried[key] {
+			continue
+		}
+		retried[key] = true
+
+		notified, err := s.NotifyScanFinished(n.ScanID, n.TenantID)
+		sent = append(sent, notified...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to retry notifications of scan `%s`: %w", n.ScanID, err))
+		}
+	}
+
+	return sent, errors.Join(errs...)
+}
+
+// notify sends a single notification, it returns nil when it was already sent
+// or can't be attempted again. A notification that fails to render is
+// recorded as failed.
+func (s *NotificationService) notify(tenantID string, data notificationData, subject, body *template.Template) (*domain.Notification, error) {
+	message, renderErr := renderNotification(data, subject, body)
+
+	n := domain.NewNotification(tenantID, data.ScanID, data.Host.Alias, data.Rapporteur.Email, "")
+	if renderErr == nil {
+		n.Subject = message.Subject
+	}
+	claimed, err := s.storage.ClaimNotification(n, s.maxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, nil
+	}
+
+	err = renderErr
+	if err == nil {
+		err = s.notifier.Send(message)
+	}
+	if err != nil {
+		log.Printf("Failed to notify `%s` about scan `%s`: `%s`", n.Recipient, n.ScanID, err.Error())
+		n.Status = domain.NotificationStatusFailed
+		n.Error = err.Error()
+	} else {
+		now := time.Now().UTC()
+		n.Status = domain.NotificationStatusSent
+		n.SentAt = &now
+	}
+
+	if err := s.storage.UpdateNotificationStatus(n); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func (s *NotificationService) GetNotificationsByScanID(scanID string, tenantID string) ([]*domain.Notification, error) {
+	if _, err := s.storage.GetScanByID(scanID, tenantID); err != nil {
+		return nil, err
+	}
+	return s.storage.GetNotificationsByScanID(scanID, tenantID)
+}
+
+// GetNotificationTemplate returns the tenant template, or the default one if the tenant has none
+func (s *NotificationService) GetNotificationTemplate(tenantID string) (*domain.NotificationTemplate, error) {
+	t, err := s.storage.GetNotificationTemplate(tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return defaultNotificationTemplate(tenantID), nil
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *NotificationService) UpdateNotificationTemplate(actor domain.Actor, t *domain.NotificationTemplate) (*domain.NotificationTemplate, error) {
+	if err := ValidateNotificationTemplate(t); err != nil {
+		return nil, err
+	}
+
+	before, err := s.GetNotificationTemplate(t.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := s.storage.UpsertNotificationTemplate(t)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(s.storage, actor, domain.AuditTemplateUpdated, "notification_template", t.TenantID, before, tmpl)
+	return tmpl, nil
+}
+
+// ResetNotificationTemplate drops the tenant template so the default one is used again
+func (s *NotificationService) ResetNotificationTemplate(actor domain.Actor, tenantID string) (*domain.NotificationTemplate, error) {
+	before, err := s.GetNotificationTemplate(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	isDeleted, err := s.storage.DeleteNotificationTemplate(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := defaultNotificationTemplate(tenantID)
+	if isDeleted {
+		recordAudit(s.storage, actor, domain.AuditTemplateReset, "notification_template", tenantID, before, tmpl)
+	}
+	return tmpl, nil
+}
+
+// ValidateNotificationTemplate parses the template and renders it against sample data
+func ValidateNotificationTemplate(t *domain.NotificationTemplate) error {
+	if strings.TrimSpace(t.Subject) == "" || strings.TrimSpace(t.Body) == "" {
+		return fmt.Errorf("%q: %w", "subject and body are required", ErrInvalidTemplate)
+	}
+
+	subject, body, err := parseNotificationTemplate(t)
+	if err != nil {
+		return err
+	}
+
+	sample := notificationData{
+		ScanID:     "00000000-0000-0000-0000-000000000000",
+		Status:     domain.ScanStatusCompleted,
+		Host:       domain.HostReport{Alias: "example", Domain: "example.com"},
+		Rapporteur: domain.Rapporteur{Name: "Example", Email: "rapporteur@example.com"},
+		Services:   []serviceSummary{{Service: enums.ServiceNmap, Progress: domain.ProgressCompleted}},
+	}
+	if _, err := renderNotification(sample, subject, body); err != nil {
+		return fmt.Errorf("%q: %w", err.Error(), ErrInvalidTemplate)
+	}
+
+	return nil
+}
+
+func defaultNotificationTemplate(tenantID string) *domain.NotificationTemplate {
+	return &domain.NotificationTemplate{
+		TenantID:  tenantID,
+		Subject:   DefaultNotificationSubject,
+		Body:      DefaultNotificationBody,
+		IsDefault: true,
+	}
+}
+
+func parseNotificationTemplate(t *domain.NotificationTemplate) (*template.Template, *template.Template, error) {
+	subject, err := template.New("subject").Option("missingkey=error").Parse(t.Subject)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%q: %w", err.Error(), ErrInvalidTemplate)
+	}
+	body, err := template.New("body").Option("missingkey=error").Parse(t.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%q: %w", err.Error(), ErrInvalidTemplate)
+	}
+	return subject, body, nil
+}
+
+// renderNotification executes the templates for a rapporteur, the subject is
+// flattened to a single line so it's safe to use as a mail header
+func renderNotification(data notificationData, subject, body *template.Template) (*domain.NotificationMessage, error) {
+	var sb strings.Builder
+	if err := subject.Execute(&sb, data); err != nil {
+		return nil, fmt.Errorf("failed to render notification subject: %w", err)
+	}
+	subjectText := strings.Join(strings.Fields(sb.String()), " ")
+
+	sb.Reset()
+	if err := body.Execute(&sb, data); err != nil {
+		return nil, fmt.Errorf("failed to render notification body: %w", err)
+	}
+
+	return &domain.NotificationMessage{
+		To:      data.Rapporteur.Email,
+		Subject: subjectText,
+		Body:    sb.String(),
+	}, nil
+}
+
+func summarizeServices(host domain.HostReport) ([]serviceSummary, error) {
+	summaries := []serviceSummary{}
+	for _, service := range host.Services {
+		findings, err := serviceFindings(host.Alias, service)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, serviceSummary{
+			Service:  service.Service,
+			Progress: service.Progress,
+			Error:    service.Error,
+			Findings: len(findings),
+		})
+	}
+	return summaries, nil
+}
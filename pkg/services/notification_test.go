@@ -0,0 +1,258 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+func Test_RenderNotification(t *testing.T) {
+	data := notificationData{
+		ScanID:     "scan-1",
+		Status:     domain.ScanStatusPartiallyFailed,
+		Host:       domain.HostReport{Alias: "web", Domain: "example.com"},
+		Rapporteur: domain.Rapporteur{Name: "Ana", Email: "ana@example.com"},
+		Services: []serviceSummary{
+			{Service: enums.ServiceNmap, Progress: domain.ProgressCompleted, Findings: 3},
+			{Service: enums.ServiceWhoIs, Progress: domain.ProgressPending, Error: "timeout"},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		template        *domain.NotificationTemplate
+		expectedSubject string
+		bodyContains    []string
+	}{
+		{
+			name:            "Default template",
+			template:        defaultNotificationTemplate("tenant-1"),
+			expectedSubject: "[Kriptome] Scan partially_failed for web",
+			bodyContains: []string{
+				"Hello Ana,",
+				"The scan scan-1 of web (example.com) finished with status partially_failed.",
+				"- Nmap: 100%, 3 findings",
+				"- WhoIs: 0%, 0 findings (error: timeout)",
+			},
+		},
+		{
+			name:            "Subject is flattened to a single line",
+			template:        &domain.NotificationTemplate{Subject: "Scan\r\nBcc: evil@example.com", Body: "{{.Rapporteur.Email}}"},
+			expectedSubject: "Scan Bcc: evil@example.com",
+			bodyContains:    []string{"ana@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, body, err := parseNotificationTemplate(tt.template)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			message, err := renderNotification(data, subject, body)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if message.To != "ana@example.com" {
+				t.Errorf("Expected `%s`, got `%s`", "ana@example.com", message.To)
+			}
+			if message.Subject != tt.expectedSubject {
+				t.Errorf("Expected `%s`, got `%s`", tt.expectedSubject, message.Subject)
+			}
+			for _, want := range tt.bodyContains {
+				if !strings.Contains(message.Body, want) {
+					t.Errorf("Expected body to contain `%s`, got:\n%s", want, message.Body)
+				}
+			}
+		})
+	}
+}
+
+func Test_ValidateNotificationTemplate(t *testing.T) {
+	tests := []struct {
+		name        string
+		template    *domain.NotificationTemplate
+		expectedErr error
+	}{
+		{name: "Default template", template: defaultNotificationTemplate("tenant-1")},
+		{name: "Missing body", template: &domain.NotificationTemplate{Subject: "Scan"}, expectedErr: ErrInvalidTemplate},
+		{name: "Syntax error", template: &domain.NotificationTemplate{Subject: "{{.Status", Body: "body"}, expectedErr: ErrInvalidTemplate},
+		{name: "Unknown field", template: &domain.NotificationTemplate{Subject: "{{.Password}}", Body: "body"}, expectedErr: ErrInvalidTemplate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNotificationTemplate(tt.template)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Expected `%v`, got `%v`", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+// notificationStorage claims notifications like the database does, a failed
+// notification is claimed again while it has attempts left
+type notificationStorage struct {
+	reportStorage
+	notifications map[string]*domain.Notification
+	claimErrs     map[string]error
+}
+
+func (s *notificationStorage) GetNotificationTemplate(tenantID string) (*domain.NotificationTemplate, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (s *notificationStorage) ClaimNotification(n *domain.Notification, maxAttempts int) (bool, error) {
+	if err := s.claimErrs[n.Recipient]; err != nil {
+		return false, err
+	}
+	claimed, ok := s.notifications[n.Recipient]
+	if !ok {
+		s.notifications[n.Recipient] = n
+		return true, nil
+	}
+	if claimed.Status != domain.NotificationStatusFailed || claimed.Attempts >= maxAttempts {
+		return false, nil
+	}
+	n.ID, n.Attempts = claimed.ID, claimed.Attempts+1
+	s.notifications[n.Recipient] = n
+	return true, nil
+}
+
+func (s *notificationStorage) UpdateNotificationStatus(n *domain.Notification) error {
+	s.notifications[n.Recipient] = n
+	return nil
+}
+
+func (s *notificationStorage) GetRetryableNotifications(maxAttempts int) ([]*domain.Notification, error) {
+	failed := []*domain.Notification{}
+	for _, n := range s.notifications {
+		if n.Status == domain.NotificationStatusFailed && n.Attempts < maxAttempts {
+			failed = append(failed, n)
+		}
+	}
+	return failed, nil
+}
+
+func newNotificationStorage(rapporteurs ...domain.Rapporteur) *notificationStorage {
+	return &notificationStorage{
+		reportStorage: reportStorage{
+			scan: &domain.Scan{
+				ID:          "scan-1",
+				TenantID:    "tenant-1",
+				Status:      domain.ScanStatusCompleted,
+				Targets:     []events.Target{{Alias: "web"}},
+				HostsStatus: []domain.StatusHost{{Host: "web"}},
+			},
+			hosts: []*domain.Host{{Name: "web", Rapporteurs: rapporteurs}},
+		},
+		notifications: map[string]*domain.Notification{},
+		claimErrs:     map[string]error{},
+	}
+}
+
+// flakyNotifier fails the first sends
+type flakyNotifier struct {
+	failures int
+	sends    int
+}
+
+func (n *flakyNotifier) Send(*domain.NotificationMessage) error {
+	n.sends++
+	if n.sends <= n.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func Test_NotifyScanFinishedRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		calls            int
+		expectedSends    int
+		expectedStatus   domain.NotificationStatus
+		expectedAttempts int
+	}{
+		{
+			name:             "Sent notifications aren't sent again",
+			failures:         0,
+			calls:            3,
+			expectedSends:    1,
+			expectedStatus:   domain.NotificationStatusSent,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "Failed notifications are sent again",
+			failures:         1,
+			calls:            3,
+			expectedSends:    2,
+			expectedStatus:   domain.NotificationStatusSent,
+			expectedAttempts: 2,
+		},
+		{
+			name:             "Failed notifications stop at the attempt limit",
+			failures:         10,
+			calls:            5,
+			expectedSends:    3,
+			expectedStatus:   domain.NotificationStatusFailed,
+			expectedAttempts: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newNotificationStorage(domain.Rapporteur{Name: "Ana", Email: "ana@example.com"})
+			notifier := &flakyNotifier{failures: tt.failures}
+			s := NewNotificationService(storage, notifier, 3)
+
+			if _, err := s.NotifyScanFinished("scan-1", "tenant-1"); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			// The rest of the calls are sweeps
+			for i := 1; i < tt.calls; i++ {
+				if _, err := s.RetryFailedNotifications(); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			if notifier.sends != tt.expectedSends {
+				t.Errorf("Expected %d sends, got %d", tt.expectedSends, notifier.sends)
+			}
+			n := storage.notifications["ana@example.com"]
+			if n.Status != tt.expectedStatus {
+				t.Errorf("Expected status `%s`, got `%s`", tt.expectedStatus, n.Status)
+			}
+			if n.Attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, n.Attempts)
+			}
+		})
+	}
+}
+
+func Test_NotifyScanFinishedContinuesAfterFailure(t *testing.T) {
+	storage := newNotificationStorage(
+		domain.Rapporteur{Name: "Ana", Email: "ana@example.com"},
+		domain.Rapporteur{Name: "Bob", Email: "bob@example.com"},
+	)
+	claimErr := errors.New("connection reset")
+	storage.claimErrs["ana@example.com"] = claimErr
+	notifier := &flakyNotifier{}
+
+	sent, err := NewNotificationService(storage, notifier, 3).NotifyScanFinished("scan-1", "tenant-1")
+	if !errors.Is(err, claimErr) {
+		t.Fatalf("Expected error `%v`, got `%v`", claimErr, err)
+	}
+	if len(sent) != 1 || sent[0].Recipient != "bob@example.com" {
+		t.Fatalf("Expected bob@example.com to be notified, got `%+v`", sent)
+	}
+	if notifier.sends != 1 {
+		t.Errorf("Expected 1 send, got %d", notifier.sends)
+	}
+}
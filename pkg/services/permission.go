@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+var (
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrInvalidPermissions = errors.New("invalid permissions")
+)
+
+// PermissionService decides what the roles of a token can do. Tenants may
+// override the default permissions of a role, the overrides are cached for ttl
+// so authorizing a request doesn't query the database every time.
+type PermissionService struct {
+	storage interfaces.IStorage
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	bindings map[string]tenantBindings
+}
+
+type tenantBindings struct {
+	roles     map[domain.Role][]domain.Permission
+	expiresAt time.Time
+}
+
+var _ interfaces.IPermissionService = (*PermissionService)(nil)
+
+func NewPermissionService(storage interfaces.IStorage, ttl time.Duration) *PermissionService {
+	return &PermissionService{
+		storage:  storage,
+		ttl:      ttl,
+		now:      time.Now,
+		bindings: map[string]tenantBindings{},
+	}
+}
+
+// Authorize returns ErrPermissionDenied unless the roles, taken together,
+// have every required permission in the tenant
+func (s *PermissionService) Authorize(tenantID string, roles []domain.Role, required []domain.Permission) error {
+	bindings, err := s.tenantBindings(tenantID)
+	if err != nil {
+		return err
+	}
+
+	return CheckPermissions(roles, bindings, required)
+}
+
+// CheckPermissions returns ErrPermissionDenied unless the roles have every
+// required permission, bindings overrides the default permissions of its roles
+func CheckPermissions(roles []domain.Role, bindings map[domain.Role][]domain.Permission, required []domain.Permission) error {
+	if missing := domain.MissingPermissions(roles, bindings, required); len(missing) > 0 {
+		msg := fmt.Sprintf("Permissions missing: Have roles `%v`, want `%v`", roles, missing)
+		return fmt.Errorf("%q: %w", msg, ErrPermissionDenied)
+	}
+	return nil
+}
+
+// GetRolePermissions returns the permissions of every role in the tenant
+func (s *PermissionService) GetRolePermissions(tenantID string) ([]*domain.RolePermissions, error) {
+	overrides, err := s.storage.GetRolePermissions(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings := []*domain.RolePermissions{}
+	for _, role := range domain.Roles {
+		i := slices.IndexFunc(overrides, func(b *domain.RolePermissions) bool { return b.Role == role })
+		if i >= 0 {
+			bindings = append(bindings, overrides[i])
+			continue
+		}
+		bindings = append(bindings, defaultRolePermissions(tenantID, role))
+	}
+
+	return bindings, nil
+}
+
+// SetRolePermissions replaces the permissions of the role in the tenant.
+// Admins always keep users:manage so a tenant can't lock itself out.
+func (s *PermissionService) SetRolePermissions(actor domain.Actor, tenantID string, role domain.Role, permissions []string) (*domain.RolePermissions, error) {
+	parsed := []domain.Permission{}
+	for _, p := range permissions {
+		permission, err := domain.ParsePermission(p)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", err.Error(), ErrInvalidPermissions)
+		}
+		if !slices.Contains(parsed, permission) {
+			parsed = append(parsed, permission)
+		}
+	}
+	if role == domain.RoleAdmin && !slices.Contains(parsed, domain.PermUsersManage) {
+		msg := fmt.Sprintf("admins must keep `%s`", domain.PermUsersManage)
+		return nil, fmt.Errorf("%q: %w", msg, ErrInvalidPermissions)
+	}
+
+	before, err := s.rolePermissions(tenantID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	binding, err := s.storage.UpsertRolePermissions(&domain.RolePermissions{TenantID: tenantID, Role: role, Permissions: parsed})
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(tenantID)
+
+	recordAudit(s.storage, actor, domain.AuditPermissionsSet, "role_permissions", role.String(), before, binding)
+	return binding, nil
+}
+
+// ResetRolePermissions drops the override of the role so it has the default permissions again
+func (s *PermissionService) ResetRolePermissions(actor domain.Actor, tenantID string, role domain.Role) (*domain.RolePermissions, error) {
+	before, err := s.rolePermissions(tenantID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	isDeleted, err := s.storage.DeleteRolePermissions(tenantID, role)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidate(tenantID)
+
+	binding := defaultRolePermissions(tenantID, role)
+	if isDeleted {
+		recordAudit(s.storage, actor, domain.AuditPermissionsReset, "role_permissions", role.String(), before, binding)
+	}
+	return binding, nil
+}
+
+func (s *PermissionService) rolePermissions(tenantID string, role domain.Role) (*domain.RolePermissions, error) {
+	bindings, err := s.GetRolePermissions(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bindings {
+		if b.Role == role {
+			return b, nil
+		}
+	}
+	return defaultRolePermissions(tenantID, role), nil
+}
+
+// tenantBindings returns the overridden roles of the tenant, from the cache when fresh.
+// Loads aren't deduplicated, so when an entry expires every concurrent request
+// of the tenant queries the database until one of them stores the result.
+func (s *PermissionService) tenantBindings(tenantID string) (map[domain.Role][]domain.Permission, error) {
+	s.mu.Lock()
+	cached, ok := s.bindings[tenantID]
+	s.mu.Unlock()
+	if ok && s.now().Before(cached.expiresAt) {
+		return cached.roles, nil
+	}
+
+	overrides, err := s.storage.GetRolePermissions(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	roles := map[domain.Role][]domain.Permission{}
+	for _, b := range overrides {
+		roles[b.Role] = b.Permissions
+	}
+
+	s.mu.Lock()
+	s.bindings[tenantID] = tenantBindings{roles: roles, expiresAt: s.now().Add(s.ttl)}
+	s.mu.Unlock()
+	return roles, nil
+}
+
+func (s *PermissionService) invalidate(tenantID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.bindings, tenantID)
+}
+
+func defaultRolePermissions(tenantID string, role domain.Role) *domain.RolePermissions {
+	return &domain.RolePermissions{
+		TenantID:    tenantID,
+		Role:        role,
+		Permissions: slices.Clone(domain.DefaultRolePermissions[role]),
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+// bindingStorage keeps the role overrides of a tenant in memory
+type bindingStorage struct {
+	interfaces.IStorage
+	overrides map[domain.Role][]domain.Permission
+	reads     int
+}
+
+func (s *bindingStorage) GetRolePermissions(tenantID string) ([]*domain.RolePermissions, error) {
+	s.reads++
+	bindings := []*domain.RolePermissions{}
+	for role, permissions := range s.overrides {
+		bindings = append(bindings, &domain.RolePermissions{TenantID: tenantID, Role: role, Permissions: permissions, Overridden: true})
+	}
+	return bindings, nil
+}
+
+func (s *bindingStorage) UpsertRolePermissions(b *domain.RolePermissions) (*domain.RolePermissions, error) {
+	s.overrides[b.Role] = b.Permissions
+	b.Overridden = true
+	return b, nil
+}
+
+func (s *bindingStorage) DeleteRolePermissions(tenantID string, role domain.Role) (bool, error) {
+	_, ok := s.overrides[role]
+	delete(s.overrides, role)
+	return ok, nil
+}
+
+func (s *bindingStorage) CreateAuditEvent(*domain.AuditEvent) error {
+	return nil
+}
+
+func Test_Authorize(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[domain.Role][]domain.Permission
+		roles     []domain.Role
+		required  []domain.Permission
+		wantErr   error
+	}{
+		{
+			name:     "Default permissions",
+			roles:    []domain.Role{domain.RoleOperator},
+			required: []domain.Permission{domain.PermScansCreate},
+		},
+		{
+			name:     "Missing default permission",
+			roles:    []domain.Role{domain.RoleAnalyst},
+			required: []domain.Permission{domain.PermScansCreate},
+			wantErr:  ErrPermissionDenied,
+		},
+		{
+			name:      "Override grants a permission",
+			overrides: map[domain.Role][]domain.Permission{domain.RoleAnalyst: {domain.PermScansCreate}},
+			roles:     []domain.Role{domain.RoleAnalyst},
+			required:  []domain.Permission{domain.PermScansCreate},
+		},
+		{
+			name:      "Override replaces the default permissions",
+			overrides: map[domain.Role][]domain.Permission{domain.RoleAnalyst: {domain.PermScansCreate}},
+			roles:     []domain.Role{domain.RoleAnalyst},
+			required:  []domain.Permission{domain.PermHostsRead},
+			wantErr:   ErrPermissionDenied,
+		},
+		{
+			name:     "Every required permission is needed",
+			roles:    []domain.Role{domain.RoleAnalyst},
+			required: []domain.Permission{domain.PermHostsRead, domain.PermHostsWrite},
+			wantErr:  ErrPermissionDenied,
+		},
+		{
+			name:     "Roles are combined",
+			roles:    []domain.Role{domain.RoleAnalyst, domain.RoleOperator},
+			required: []domain.Permission{domain.PermTenantsRead, domain.PermScansCreate},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overrides := tt.overrides
+			if overrides == nil {
+				overrides = map[domain.Role][]domain.Permission{}
+			}
+			s := NewPermissionService(&bindingStorage{overrides: overrides}, time.Minute)
+
+			err := s.Authorize("tenant-1", tt.roles, tt.required)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_AuthorizeCache(t *testing.T) {
+	storage := &bindingStorage{overrides: map[domain.Role][]domain.Permission{}}
+	s := NewPermissionService(storage, time.Minute)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	analyst := []domain.Role{domain.RoleAnalyst}
+	required := []domain.Permission{domain.PermScansCreate}
+
+	if err := s.Authorize("tenant-1", analyst, required); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("expected %v, got %v", ErrPermissionDenied, err)
+	}
+
+	// Changes made elsewhere show up once the cache expires
+	storage.overrides[domain.RoleAnalyst] = []domain.Permission{domain.PermScansCreate}
+	if err := s.Authorize("tenant-1", analyst, required); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected cached %v, got %v", ErrPermissionDenied, err)
+	}
+	now = now.Add(time.Minute)
+	if err := s.Authorize("tenant-1", analyst, required); err != nil {
+		t.Errorf("expected refreshed permissions, got %v", err)
+	}
+
+	// Changes made through the service show up right away
+	if _, err := s.ResetRolePermissions(domain.Actor{}, "tenant-1", domain.RoleAnalyst); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Authorize("tenant-1", analyst, required); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected %v after reset, got %v", ErrPermissionDenied, err)
+	}
+}
+
+func Test_SetRolePermissions(t *testing.T) {
+	tests := []struct {
+		name            string
+		role            domain.Role
+		permissions     []string
+		wantPermissions []domain.Permission
+		wantErr         error
+	}{
+		{
+			name:            "Duplicates are dropped",
+			role:            domain.RoleAnalyst,
+			permissions:     []string{"hosts:read", "scans:create", "hosts:read"},
+			wantPermissions: []domain.Permission{domain.PermHostsRead, domain.PermScansCreate},
+		},
+		{
+			name:            "No permissions",
+			role:            domain.RoleAnalyst,
+			permissions:     []string{},
+			wantPermissions: []domain.Permission{},
+		},
+		{
+			name:        "Unknown permission",
+			role:        domain.RoleAnalyst,
+			permissions: []string{"hosts:delete"},
+			wantErr:     ErrInvalidPermissions,
+		},
+		{
+			name:        "Admins keep users:manage",
+			role:        domain.RoleAdmin,
+			permissions: []string{"hosts:read"},
+			wantErr:     ErrInvalidPermissions,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewPermissionService(&bindingStorage{overrides: map[domain.Role][]domain.Permission{}}, time.Minute)
+
+			binding, err := s.SetRolePermissions(domain.Actor{}, "tenant-1", tt.role, tt.permissions)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !binding.Overridden || len(binding.Permissions) != len(tt.wantPermissions) {
+				t.Fatalf("expected overridden %v, got %+v", tt.wantPermissions, binding)
+			}
+			for i, p := range tt.wantPermissions {
+				if binding.Permissions[i] != p {
+					t.Errorf("expected %v, got %v", tt.wantPermissions, binding.Permissions)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,421 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/common/common/results"
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+var ErrInvalidReportFormat = errors.New("invalid report format")
+
+type ReportFormat string
+
+const (
+	ReportFormatJSON     ReportFormat = "json"
+	ReportFormatCSV      ReportFormat = "csv"
+	ReportFormatMarkdown ReportFormat = "md"
+	ReportFormatSARIF    ReportFormat = "sarif"
+)
+
+const (
+	sarifVersion    = "2.1.0"
+	sarifSchema     = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifToolName   = "kptm-core-service"
+	highCVSSScore   = 7.0
+	mediumCVSSScore = 4.0
+)
+
+var reportContentTypes = map[ReportFormat]string{
+	ReportFormatJSON:     "application/json",
+	ReportFormatCSV:      "text/csv; charset=utf-8",
+	ReportFormatMarkdown: "text/markdown; charset=utf-8",
+	ReportFormatSARIF:    "application/sarif+json",
+}
+
+// ParseReportFormat returns the report format, json when empty
+func ParseReportFormat(format string) (ReportFormat, error) {
+	if format == "" {
+		return ReportFormatJSON, nil
+	}
+	f := ReportFormat(strings.ToLower(format))
+	if _, ok := reportContentTypes[f]; !ok {
+		return "", fmt.Errorf("%q: %w", format, ErrInvalidReportFormat)
+	}
+	return f, nil
+}
+
+func (f ReportFormat) ContentType() string {
+	return reportContentTypes[f]
+}
+
+// Finding is a single flattened result of a service for a host
+type Finding struct {
+	Host     string
+	Service  enums.ServiceName
+	Kind     string
+	Value    string
+	Detail   string
+	Severity string
+}
+
+// GetScanReport builds the report of a scan of the tenant
+func (s ScanService) GetScanReport(ID string, tenantID string) (*domain.ScanReport, error) {
+	scan, err := s.storage.GetScanByID(ID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := []string{}
+	for _, t := range scan.Targets {
+		aliases = append(aliases, t.Alias)
+	}
+	hosts, err := s.storage.GetHostsByAliases(tenantID, aliases)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hosts: %w", err)
+	}
+
+	return buildScanReport(scan, hosts), nil
+}
+
+func buildScanReport(scan *domain.Scan, hosts []*domain.Host) *domain.ScanReport {
+	hostsByAlias := map[string]*domain.Host{}
+	for _, h := range hosts {
+		hostsByAlias[h.Name] = h
+	}
+	resultsByAlias := resultsByHost(scan)
+
+	report := &domain.ScanReport{
+		ScanID:      scan.ID,
+		Status:      scan.Status,
+		CreatedAt:   scan.CreatedAt,
+		UpdatedAt:   scan.UpdatedAt,
+		GeneratedAt: time.Now().UTC(),
+		Hosts:       []domain.HostReport{},
+	}
+
+	for _, hs := range scan.HostsStatus {
+		hostReport := domain.NewHostReport(hs.Host, hostsByAlias[hs.Host])
+		for _, m := range hs.Metadata {
+			hostReport.Services = append(hostReport.Services, domain.ServiceReport{
+				Service:  m.Service,
+				Progress: m.Progress,
+				Error:    m.Error,
+				Results:  resultsByAlias[hs.Host][m.Service],
+			})
+		}
+		report.Hosts = append(report.Hosts, hostReport)
+	}
+
+	return report
+}
+
+// RenderScanReport writes the report to w in the given format
+func RenderScanReport(w io.Writer, format ReportFormat, report *domain.ScanReport) error {
+	switch format {
+	case ReportFormatJSON:
+		return renderJSON(w, report)
+	case ReportFormatCSV:
+		return renderCSV(w, report)
+	case ReportFormatMarkdown:
+		return renderMarkdown(w, report)
+	case ReportFormatSARIF:
+		return renderSARIF(w, report)
+	}
+	return fmt.Errorf("%q: %w", format, ErrInvalidReportFormat)
+}
+
+func renderJSON(w io.Writer, report *domain.ScanReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func renderCSV(w io.Writer, report *domain.ScanReport) error {
+	cw := &csvFormulaWriter{csv.NewWriter(w)}
+	header := []string{"host", "domain", "ip", "rapporteurs", "service", "progress", "error", "kind", "value", "detail", "severity"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, host := range report.Hosts {
+		rapporteurs := rapporteurEmails(host.Rapporteurs)
+		for _, service := range host.Services {
+			row := []string{host.Alias, host.Domain, host.IP, rapporteurs, string(service.Service), service.Progress, service.Error}
+
+			findings, err := serviceFindings(host.Alias, service)
+			if err != nil {
+				return err
+			}
+			if len(findings) == 0 {
+				if err := cw.Write(append(row, "", "", "", "")); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, f := range findings {
+				if err := cw.Write(append(row[:len(row):len(row)], f.Kind, f.Value, f.Detail, f.Severity)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvFormulaWriter quotes the cells a spreadsheet would read as a formula,
+// hosts and scan results are user input and may carry one
+type csvFormulaWriter struct {
+	*csv.Writer
+}
+
+func (cw *csvFormulaWriter) Write(record []string) error {
+	escaped := make([]string, len(record))
+	for i, cell := range record {
+		escaped[i] = csvEscape(cell)
+	}
+	return cw.Writer.Write(escaped)
+}
+
+func csvEscape(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func renderMarkdown(w io.Writer, report *domain.ScanReport) error {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "# Scan report `%s`\n\n", report.ScanID)
+	fmt.Fprintf(&sb, "- Status: %s\n", report.Status)
+	fmt.Fprintf(&sb, "- Created: %s\n", report.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "- Updated: %s\n", report.UpdatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "- Generated: %s\n", report.GeneratedAt.Format(time.RFC3339))
+
+	for _, host := range report.Hosts {
+		fmt.Fprintf(&sb, "\n## %s\n\n", markdownEscape(host.Alias))
+		fmt.Fprintf(&sb, "| Domain | IP | Rapporteurs |\n|---|---|---|\n")
+		fmt.Fprintf(&sb, "| %s | %s | %s |\n",
+			markdownEscape(host.Domain), markdownEscape(host.IP), markdownEscape(rapporteurEmails(host.Rapporteurs)))
+
+		for _, service := range host.Services {
+			fmt.Fprintf(&sb, "\n### %s (%s)\n\n", service.Service, service.Progress)
+			if service.Error != "" {
+				fmt.Fprintf(&sb, "Error: %s\n\n", markdownEscape(service.Error))
+			}
+
+			findings, err := serviceFindings(host.Alias, service)
+			if err != nil {
+				return err
+			}
+			if len(findings) == 0 {
+				sb.WriteString("No findings.\n")
+				continue
+			}
+			sb.WriteString("| Kind | Value | Detail | Severity |\n|---|---|---|---|\n")
+			for _, f := range findings {
+				fmt.Fprintf(&sb, "| %s | %s | %s | %s |\n",
+					f.Kind, markdownEscape(f.Value), markdownEscape(f.Detail), f.Severity)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName,omitempty"`
+	Kind               string `json:"kind"`
+}
+
+func renderSARIF(w io.Writer, report *domain.ScanReport) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: sarifToolName, Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+	rules := map[string]bool{}
+
+	for _, host := range report.Hosts {
+		location := sarifLocation{LogicalLocations: []sarifLogicalLocation{{
+			Name:               host.Alias,
+			FullyQualifiedName: firstNonEmpty(host.Domain, host.IP),
+			Kind:               "resource",
+		}}}
+
+		for _, service := range host.Services {
+			findings, err := serviceFindings(host.Alias, service)
+			if err != nil {
+				return err
+			}
+			for _, f := range findings {
+				ruleID := fmt.Sprintf("%s/%s", f.Service, f.Kind)
+				if !rules[ruleID] {
+					rules[ruleID] = true
+					run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: ruleID})
+				}
+
+				text := f.Value
+				if f.Detail != "" {
+					text = fmt.Sprintf("%s: %s", f.Value, f.Detail)
+				}
+				run.Results = append(run.Results, sarifResult{
+					RuleID:    ruleID,
+					Level:     f.Severity,
+					Message:   sarifMessage{Text: text},
+					Locations: []sarifLocation{location},
+				})
+			}
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}})
+}
+
+// serviceFindings flattens the raw results of a service, severities use SARIF levels
+func serviceFindings(host string, service domain.ServiceReport) ([]Finding, error) {
+	if len(service.Results) == 0 || string(service.Results) == "null" {
+		return nil, nil
+	}
+
+	newFinding := func(kind, value, detail, severity string) Finding {
+		return Finding{Host: host, Service: service.Service, Kind: kind, Value: value, Detail: detail, Severity: severity}
+	}
+	findings := []Finding{}
+
+	switch service.Service {
+	case enums.ServiceNmap:
+		var r results.NmapResult
+		if err := json.Unmarshal(service.Results, &r); err != nil {
+			return nil, fmt.Errorf("failed to decode %s results: %w", service.Service, err)
+		}
+		for _, p := range r.ScannedPorts {
+			port := fmt.Sprintf("%d/%s", p.ID, p.Protocol)
+			if p.State == "open" {
+				findings = append(findings, newFinding("open_port", port, strings.TrimSpace(p.Service.Name+" "+p.Service.Version), "note"))
+			}
+			for _, v := range p.Vulnerabilities {
+				detail := fmt.Sprintf("%s CVSS %.1f", port, v.CVSS)
+				findings = append(findings, newFinding("vulnerability", v.ID, detail, cvssLevel(v.CVSS)))
+			}
+		}
+
+	case enums.ServiceDNSLookup:
+		var r results.DNSLookupResult
+		if err := json.Unmarshal(service.Results, &r); err != nil {
+			return nil, fmt.Errorf("failed to decode %s results: %w", service.Service, err)
+		}
+		for _, rec := range r.DNSRecords {
+			findings = append(findings, newFinding("dns_record", fmt.Sprintf("%s %s", rec.Type, rec.Name), fmt.Sprint(rec.Value), "note"))
+		}
+
+	case enums.ServiceHarvester:
+		var r results.HarvesterResult
+		if err := json.Unmarshal(service.Results, &r); err != nil {
+			return nil, fmt.Errorf("failed to decode %s results: %w", service.Service, err)
+		}
+		for _, email := range r.Emails {
+			findings = append(findings, newFinding("email", email, "", "note"))
+		}
+		for _, sub := range r.Subdomains {
+			findings = append(findings, newFinding("subdomain", sub, "", "note"))
+		}
+
+	case enums.ServiceWhoIs:
+		var r results.WhoIsResult
+		if err := json.Unmarshal(service.Results, &r); err != nil {
+			return nil, fmt.Errorf("failed to decode %s results: %w", service.Service, err)
+		}
+		fields := whoIsFields(r)
+		for _, field := range whoIsFieldNames {
+			if fields[field] != "" {
+				findings = append(findings, newFinding("whois", field, fields[field], "note"))
+			}
+		}
+	}
+
+	return findings, nil
+}
+
+func cvssLevel(score float64) string {
+	switch {
+	case score >= highCVSSScore:
+		return "error"
+	case score >= mediumCVSSScore:
+		return "warning"
+	}
+	return "note"
+}
+
+func rapporteurEmails(rapporteurs []domain.Rapporteur) string {
+	emails := []string{}
+	for _, r := range rapporteurs {
+		emails = append(emails, r.Email)
+	}
+	return strings.Join(emails, ";")
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+func newReportFixture(t *testing.T) *domain.ScanReport {
+	t.Helper()
+
+	nmap, err := json.Marshal(map[string]interface{}{
+		"scanned_ports": []map[string]interface{}{
+			{"id": 22, "protocol": "tcp", "state": "open", "service": map[string]string{"name": "ssh"},
+				"vulnerabilities": []map[string]interface{}{{"id": "CVE-2023-0001", "cvss": 9.8}}},
+			{"id": 25, "protocol": "tcp", "state": "closed"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scan := &domain.Scan{
+		ID:     "scan-1",
+		Status: domain.ScanStatusCompleted,
+		HostsStatus: []domain.StatusHost{{
+			Host: "web",
+			Metadata: []domain.Metadata{
+				{Service: enums.ServiceNmap, Progress: domain.ProgressCompleted},
+				{Service: enums.ServiceHarvester, Progress: domain.ProgressCompleted, Error: "timeout"},
+			},
+		}},
+		HostsResults: []domain.ResultHost{{
+			Host:    "web",
+			Results: map[enums.ServiceName]json.RawMessage{enums.ServiceNmap: nmap},
+		}},
+	}
+	host := &domain.Host{
+		Name:        "web",
+		Domain:      "example.com",
+		IP:          "10.0.0.1",
+		Credentials: []domain.Credential{{Username: "root", Password: "s3cr3t-password"}},
+		Rapporteurs: []domain.Rapporteur{{Name: "Ana", Email: "ana@example.com"}},
+	}
+
+	return buildScanReport(scan, []*domain.Host{host})
+}
+
+func Test_RenderScanReport(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   ReportFormat
+		contains []string
+	}{
+		{
+			name:     "JSON",
+			format:   ReportFormatJSON,
+			contains: []string{`"scan_id": "scan-1"`, `"domain": "example.com"`, "ana@example.com"},
+		},
+		{
+			name:   "CSV",
+			format: ReportFormatCSV,
+			contains: []string{
+				"host,domain,ip,rapporteurs,service,progress,error,kind,value,detail,severity",
+				"web,example.com,10.0.0.1,ana@example.com,Nmap,100%,,open_port,22/tcp,ssh,note",
+				"web,example.com,10.0.0.1,ana@example.com,Nmap,100%,,vulnerability,CVE-2023-0001,22/tcp CVSS 9.8,error",
+				"web,example.com,10.0.0.1,ana@example.com,Harvester,100%,timeout,,,,",
+			},
+		},
+		{
+			name:     "Markdown",
+			format:   ReportFormatMarkdown,
+			contains: []string{"# Scan report `scan-1`", "## web", "| open_port | 22/tcp | ssh | note |", "Error: timeout"},
+		},
+		{
+			name:     "SARIF",
+			format:   ReportFormatSARIF,
+			contains: []string{`"version": "2.1.0"`, `"ruleId": "Nmap/vulnerability"`, `"level": "error"`, `"fullyQualifiedName": "example.com"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := RenderScanReport(&buf, tt.format, newReportFixture(t)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			out := buf.String()
+
+			for _, want := range tt.contains {
+				if !strings.Contains(out, want) {
+					t.Errorf("Expected output to contain `%s`, got:\n%s", want, out)
+				}
+			}
+			for _, secret := range []string{"s3cr3t-password", "root", "credentials"} {
+				if strings.Contains(out, secret) {
+					t.Errorf("Expected output to not contain `%s`, got:\n%s", secret, out)
+				}
+			}
+		})
+	}
+}
+
+func Test_RenderScanReportCSVFormulas(t *testing.T) {
+	report := newReportFixture(t)
+	report.Hosts[0].Alias = "=HYPERLINK(\"http://example.com\")"
+	report.Hosts[0].Services[1].Error = "@SUM(A1)"
+	report.Hosts[0].Rapporteurs = []domain.Rapporteur{{Email: "+ana@example.com"}}
+
+	var buf bytes.Buffer
+	if err := RenderScanReport(&buf, ReportFormatCSV, report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := buf.String()
+
+	want := `"'=HYPERLINK(""http://example.com"")",example.com,10.0.0.1,'+ana@example.com,Harvester,100%,'@SUM(A1),,,,`
+	if !strings.Contains(out, want) {
+		t.Errorf("Expected output to contain `%s`, got:\n%s", want, out)
+	}
+}
+
+func Test_ParseReportFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		expected    ReportFormat
+		expectedErr error
+	}{
+		{name: "Empty defaults to JSON", input: "", expected: ReportFormatJSON},
+		{name: "Case insensitive", input: "SARIF", expected: ReportFormatSARIF},
+		{name: "Markdown", input: "md", expected: ReportFormatMarkdown},
+		{name: "Unknown format", input: "pdf", expectedErr: ErrInvalidReportFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReportFormat(tt.input)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Expected error `%v`, got `%v`", tt.expectedErr, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected `%v`, got `%v`", tt.expected, got)
+			}
+		})
+	}
+}
+
+// reportStorage holds the scan and hosts of a report
+type reportStorage struct {
+	interfaces.IStorage
+	scan  *domain.Scan
+	hosts []*domain.Host
+}
+
+func (s *reportStorage) GetScanByID(ID string, tenantID string) (*domain.Scan, error) {
+	return s.scan, nil
+}
+
+func (s *reportStorage) GetHostsByAliases(tenantID string, aliases []string) ([]*domain.Host, error) {
+	hosts := []*domain.Host{}
+	for _, h := range s.hosts {
+		if slices.Contains(aliases, h.Name) {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts, nil
+}
+
+func Test_GetScanReportDeletedHost(t *testing.T) {
+	deletedAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	storage := &reportStorage{
+		scan: &domain.Scan{
+			ID:          "scan-1",
+			Status:      domain.ScanStatusCompleted,
+			Targets:     []events.Target{{Alias: "web"}},
+			HostsStatus: []domain.StatusHost{{Host: "web"}},
+		},
+		hosts: []*domain.Host{{
+			Name:        "web",
+			Domain:      "example.com",
+			IP:          "10.0.0.1",
+			Rapporteurs: []domain.Rapporteur{{Name: "Ana", Email: "ana@example.com"}},
+			DeletedAt:   &deletedAt,
+		}},
+	}
+
+	report, err := NewScanService(storage).GetScanReport("scan-1", "tenant-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(report.Hosts) != 1 {
+		t.Fatalf("Expected 1 host, got %d", len(report.Hosts))
+	}
+	host := report.Hosts[0]
+	if host.Domain != "example.com" || host.IP != "10.0.0.1" || len(host.Rapporteurs) != 1 {
+		t.Errorf("Expected the deleted host data in the report, got %+v", host)
+	}
+}
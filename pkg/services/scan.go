@@ -1,14 +1,27 @@
 package services
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/kptm-tools/common/common/enums"
 	"github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/common/common/results"
 	"github.com/kptm-tools/core-service/pkg/domain"
 	"github.com/kptm-tools/core-service/pkg/interfaces"
 )
 
+var (
+	ErrInvalidDateRange = errors.New("`from` must be before `to`")
+	ErrUnknownTarget    = errors.New("target is not part of the scan")
+	ErrScanFinished     = errors.New("scan has already finished")
+	ErrNoHostsSelected  = errors.New("no hosts selected")
+)
+
 type ScanService struct {
 	storage interfaces.IStorage
 }
@@ -21,18 +34,35 @@ func NewScanService(storage interfaces.IStorage) *ScanService {
 	}
 }
 
-func (s ScanService) CreateScans(hostIDs []int) (*domain.Scan, error) {
+// CreateScans creates a scan of the selected hosts running the given tools,
+// every known tool runs when tools is empty. Groups and tags are resolved to
+// their hosts at this point, so later changes don't affect the scan.
+func (s ScanService) CreateScans(actor domain.Actor, selector domain.HostSelector, tools []domain.ScanTool) (*domain.Scan, error) {
+	if len(tools) == 0 {
+		tools = DefaultTools()
+	}
+	if err := ValidateTools(tools); err != nil {
+		return nil, err
+	}
+
+	hostIDs, err := s.resolveHostSelector(selector, actor.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
 	scanDB := domain.NewScan()
-	metadataDefault := createMetadata()
+	scanDB.TenantID = actor.TenantID
+	scanDB.OperatorID = actor.UserID
+	scanDB.Tools = tools
 
 	for _, hostID := range hostIDs {
-		host, err := s.storage.GetHostByID(hostID)
+		host, err := s.storage.GetHostByID(hostID, actor.TenantID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get host: %w", err)
 		}
 
 		// Process the host data into the scan
-		scanDB.HostsStatus = append(scanDB.HostsStatus, createHostStatus(*host, metadataDefault))
+		scanDB.HostsStatus = append(scanDB.HostsStatus, createHostStatus(*host, createMetadata(tools)))
 		scanDB.Targets = append(scanDB.Targets, createTarget(*host))
 	}
 
@@ -42,28 +72,321 @@ func (s ScanService) CreateScans(hostIDs []int) (*domain.Scan, error) {
 	}
 
 	dataScan.Targets = scanDB.Targets
+	dataScan.HostsStatus = scanDB.HostsStatus
+
+	recordAudit(s.storage, actor, domain.AuditScanCreated, "scan", dataScan.ID, nil, scanAuditFields(dataScan))
 	return dataScan, nil
 }
 
-func createMetadata() []domain.Metadata {
-	// set dataResults of host in status scan
-	metadataWhois := domain.Metadata{
-		Progress: "0%",
-		Service:  enums.ServiceWhoIs,
+// scanAuditFields are the fields of a scan recorded in the audit log, results
+// and progress change on their own so they're left out
+func scanAuditFields(scan *domain.Scan) map[string]any {
+	aliases := []string{}
+	for _, target := range scan.Targets {
+		aliases = append(aliases, target.Alias)
+	}
+	return map[string]any{"status": scan.Status, "hosts": aliases, "tools": scan.Tools}
+}
+
+// resolveHostSelector returns the IDs of the selected hosts, explicit IDs
+// first, without duplicates
+func (s ScanService) resolveHostSelector(selector domain.HostSelector, tenantID string) ([]int, error) {
+	for _, groupID := range selector.GroupIDs {
+		if _, err := s.storage.GetHostGroupByID(groupID, tenantID); err != nil {
+			return nil, fmt.Errorf("failed to get group: %w", err)
+		}
+	}
+
+	hostIDs := []int{}
+	seen := map[int]bool{}
+	add := func(ids []int) {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				hostIDs = append(hostIDs, id)
+			}
+		}
+	}
+	add(selector.HostIDs)
+
+	if len(selector.GroupIDs) > 0 || len(selector.Tags) > 0 {
+		tags := make([]string, 0, len(selector.Tags))
+		for _, tag := range selector.Tags {
+			tags = append(tags, NormalizeTag(tag))
+		}
+		ids, err := s.storage.GetHostIDsBySelector(tenantID, selector.GroupIDs, tags)
+		if err != nil {
+			return nil, err
+		}
+		add(ids)
+	}
+
+	if len(hostIDs) == 0 {
+		return nil, ErrNoHostsSelected
+	}
+
+	return hostIDs, nil
+}
+
+func (s ScanService) GetScanByID(ID string, tenantID string) (*domain.Scan, error) {
+	scan, err := s.storage.GetScanByID(ID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scan, nil
+}
+
+func (s ScanService) GetScans(filter domain.ScanFilter) (*domain.ScanPage, error) {
+	if filter.From != nil && filter.To != nil && filter.From.After(*filter.To) {
+		return nil, ErrInvalidDateRange
+	}
+
+	// Scans reference hosts by alias, so resolve the requested host first
+	if filter.HostID != 0 {
+		host, err := s.storage.GetHostByID(filter.HostID, filter.TenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get host: %w", err)
+		}
+		filter.HostAlias = host.Name
+	}
+
+	page, err := s.storage.GetScans(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// UpdateServiceProgress stores the progress reported by a scanning service.
+// An empty target updates the progress of the service on every host.
+func (s ScanService) UpdateServiceProgress(scanID string, service enums.ServiceName, target string, progress string) (*domain.Scan, error) {
+	scan, err := s.storage.UpdateScanByID(scanID, func(scan *domain.Scan) error {
+		if err := startScan(scan); err != nil {
+			return err
+		}
+
+		alias := ""
+		if target != "" {
+			var ok bool
+			alias, ok = scan.HostAlias(target)
+			if !ok {
+				return fmt.Errorf("%q: %w", target, ErrUnknownTarget)
+			}
+		}
+
+		for i := range scan.HostsStatus {
+			if alias != "" && scan.HostsStatus[i].Host != alias {
+				continue
+			}
+			setServiceProgress(&scan.HostsStatus[i], service, progress, "")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update scan progress: %w", err)
+	}
+
+	return scan, nil
+}
+
+// SaveServiceResults stores the results of a finished scanning service and
+// marks the service as completed on every host of the scan.
+func (s ScanService) SaveServiceResults(scanID string, service enums.ServiceName, targetResults []results.TargetResult, eventErr *events.EventError) (*domain.Scan, error) {
+	scan, err := s.storage.UpdateScanByID(scanID, func(scan *domain.Scan) error {
+		if err := startScan(scan); err != nil {
+			return err
+		}
+
+		for _, tr := range targetResults {
+			alias, ok := scan.HostAlias(tr.Target)
+			if !ok {
+				log.Printf("Ignoring %s result for unknown target `%s` in scan `%s`", service, tr.Target, scanID)
+				continue
+			}
+
+			raw, err := json.Marshal(tr.Results[service])
+			if err != nil {
+				return fmt.Errorf("failed to marshal %s results: %w", service, err)
+			}
+			setServiceResult(scan, alias, service, raw)
+		}
+
+		errMsg := ""
+		if eventErr != nil {
+			errMsg = fmt.Sprintf("%s: %s", eventErr.Code, eventErr.Message)
+		}
+		for i := range scan.HostsStatus {
+			setServiceProgress(&scan.HostsStatus[i], service, domain.ProgressCompleted, errMsg)
+		}
+
+		return finishScan(scan)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save scan results: %w", err)
+	}
+
+	return scan, nil
+}
+
+// CancelScan marks a pending or running scan of the tenant as cancelled
+func (s ScanService) CancelScan(actor domain.Actor, ID string) (*domain.Scan, error) {
+	var previous domain.ScanStatus
+	scan, err := s.storage.UpdateScanByID(ID, func(scan *domain.Scan) error {
+		if scan.TenantID != actor.TenantID {
+			return sql.ErrNoRows
+		}
+		previous = scan.Status
+		return scan.TransitionTo(domain.ScanStatusCancelled)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel scan: %w", err)
 	}
-	metadataHarvester := domain.Metadata{
-		Progress: "0%",
-		Service:  enums.ServiceHarvester,
+
+	recordAudit(s.storage, actor, domain.AuditScanCancelled, "scan", scan.ID,
+		map[string]any{"status": previous}, map[string]any{"status": scan.Status})
+	return scan, nil
+}
+
+// TimeoutScans marks every scan that has been active for longer than
+// timeout as timed out, returns the scans that were updated
+func (s ScanService) TimeoutScans(timeout time.Duration) ([]*domain.Scan, error) {
+	stale, err := s.storage.GetScansByStatus(domain.ActiveScanStatuses(), time.Now().UTC().Add(-timeout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch active scans: %w", err)
 	}
-	metadataDNSLookup := domain.Metadata{
-		Progress: "0%",
-		Service:  enums.ServiceDNSLookup,
+
+	timedOut := []*domain.Scan{}
+	for _, sc := range stale {
+		scan, err := s.storage.UpdateScanByID(sc.ID, func(scan *domain.Scan) error {
+			return scan.TransitionTo(domain.ScanStatusTimedOut)
+		})
+		if err != nil {
+			// The scan may have finished in the meantime
+			if errors.Is(err, domain.ErrInvalidScanTransition) {
+				continue
+			}
+			return timedOut, fmt.Errorf("failed to time out scan `%s`: %w", sc.ID, err)
+		}
+		timedOut = append(timedOut, scan)
+	}
+
+	return timedOut, nil
+}
+
+// HasActiveScan reports whether any of the hosts is part of a scan
+// of the tenant that is still pending or running
+func (s ScanService) HasActiveScan(hostIDs []int, tenantID string) (bool, error) {
+	aliases := []string{}
+	for _, hostID := range hostIDs {
+		host, err := s.storage.GetHostByID(hostID, tenantID)
+		if err != nil {
+			return false, fmt.Errorf("failed to get host: %w", err)
+		}
+		aliases = append(aliases, host.Name)
 	}
-	metadataNmap := domain.Metadata{
-		Progress: "0%",
-		Service:  enums.ServiceNmap,
+
+	return s.storage.ExistsActiveScanForHosts(tenantID, aliases)
+}
+
+// NewScanStartedEvent builds the payload that tells the scanning services to start working on a scan
+func NewScanStartedEvent(scan *domain.Scan) *domain.ScanStartedEvent {
+	return &domain.ScanStartedEvent{
+		ScanStartedEvent: events.ScanStartedEvent{
+			ScanID:    scan.ID,
+			Targets:   scan.Targets,
+			Timestamp: scan.CreatedAt.Unix(),
+		},
+		Tools: scan.Tools,
+	}
+}
+
+// NewScanUpdatedEvent builds the payload that tells listeners the status of a scan changed
+func NewScanUpdatedEvent(scan *domain.Scan) *domain.ScanUpdatedEvent {
+	return &domain.ScanUpdatedEvent{
+		ScanID:      scan.ID,
+		TenantID:    scan.TenantID,
+		Status:      scan.Status,
+		HostsStatus: scan.HostsStatus,
+		UpdatedAt:   scan.UpdatedAt,
+	}
+}
+
+// PublishScanUpdated publishes the current status of the scan on the event bus
+func PublishScanUpdated(bus events.EventBus, scan *domain.Scan) error {
+	payload, err := json.Marshal(NewScanUpdatedEvent(scan))
+	if err != nil {
+		return fmt.Errorf("failed to marshal scan updated event: %w", err)
+	}
+
+	return bus.Publish(domain.ScanUpdatedEventSubject, payload)
+}
+
+// startScan moves a pending scan to running when the first service reports back
+func startScan(scan *domain.Scan) error {
+	if scan.Status.IsTerminal() {
+		return fmt.Errorf("%q: %w", scan.Status.String(), ErrScanFinished)
+	}
+	if scan.Status == domain.ScanStatusPending {
+		return scan.TransitionTo(domain.ScanStatusRunning)
+	}
+	return nil
+}
+
+// finishScan completes the scan once every service finished on every host
+func finishScan(scan *domain.Scan) error {
+	if !scan.IsFinished() {
+		return nil
+	}
+	if scan.HasFailures() {
+		return scan.TransitionTo(domain.ScanStatusPartiallyFailed)
+	}
+	return scan.TransitionTo(domain.ScanStatusCompleted)
+}
+
+func setServiceProgress(hostStatus *domain.StatusHost, service enums.ServiceName, progress string, errMsg string) {
+	for i := range hostStatus.Metadata {
+		m := &hostStatus.Metadata[i]
+		if m.Service != service {
+			continue
+		}
+		// Late progress events must not reopen a finished service
+		if m.Progress == domain.ProgressCompleted && progress != domain.ProgressCompleted {
+			return
+		}
+		m.Progress = progress
+		m.Error = errMsg
+		return
+	}
+}
+
+func setServiceResult(scan *domain.Scan, alias string, service enums.ServiceName, raw json.RawMessage) {
+	for i := range scan.HostsResults {
+		if scan.HostsResults[i].Host == alias {
+			if scan.HostsResults[i].Results == nil {
+				scan.HostsResults[i].Results = map[enums.ServiceName]json.RawMessage{}
+			}
+			scan.HostsResults[i].Results[service] = raw
+			return
+		}
+	}
+	scan.HostsResults = append(scan.HostsResults, domain.ResultHost{
+		Host:    alias,
+		Results: map[enums.ServiceName]json.RawMessage{service: raw},
+	})
+}
+
+// createMetadata sets the progress of every selected tool on a host
+func createMetadata(tools []domain.ScanTool) []domain.Metadata {
+	metadata := []domain.Metadata{}
+	for _, tool := range tools {
+		metadata = append(metadata, domain.Metadata{
+			Progress: domain.ProgressPending,
+			Service:  tool.Service,
+		})
 	}
-	return []domain.Metadata{metadataHarvester, metadataWhois, metadataDNSLookup, metadataNmap}
+	return metadata
 }
 
 func createTarget(host domain.Host) events.Target {
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/common/common/results"
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+const portStateOpen = "open"
+
+// DiffScans compares the results of two scans of the tenant host by host
+func (s ScanService) DiffScans(baseID string, targetID string, tenantID string) (*domain.ScanDiff, error) {
+	base, err := s.storage.GetScanByID(baseID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get base scan: %w", err)
+	}
+	target, err := s.storage.GetScanByID(targetID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get target scan: %w", err)
+	}
+
+	return diffScans(base, target)
+}
+
+func diffScans(base *domain.Scan, target *domain.Scan) (*domain.ScanDiff, error) {
+	diff := &domain.ScanDiff{
+		BaseScanID:        base.ID,
+		TargetScanID:      target.ID,
+		Hosts:             []domain.HostDiff{},
+		HostsOnlyInBase:   []string{},
+		HostsOnlyInTarget: []string{},
+	}
+
+	baseResults := resultsByHost(base)
+	targetResults := resultsByHost(target)
+
+	for _, t := range base.Targets {
+		if !scanHasHost(target, t.Alias) {
+			diff.HostsOnlyInBase = append(diff.HostsOnlyInBase, t.Alias)
+		}
+	}
+
+	for _, t := range target.Targets {
+		if !scanHasHost(base, t.Alias) {
+			diff.HostsOnlyInTarget = append(diff.HostsOnlyInTarget, t.Alias)
+			continue
+		}
+
+		hostDiff, err := diffHostResults(t.Alias, baseResults[t.Alias], targetResults[t.Alias])
+		if err != nil {
+			return nil, err
+		}
+		diff.Hosts = append(diff.Hosts, *hostDiff)
+	}
+
+	return diff, nil
+}
+
+func diffHostResults(host string, base map[enums.ServiceName]json.RawMessage, target map[enums.ServiceName]json.RawMessage) (*domain.HostDiff, error) {
+	hostDiff := &domain.HostDiff{Host: host}
+
+	var baseNmap, targetNmap results.NmapResult
+	if ok, err := decodeBoth(base, target, enums.ServiceNmap, &baseNmap, &targetNmap); err != nil {
+		return nil, err
+	} else if ok {
+		hostDiff.NewPorts = openPortsMissingFrom(targetNmap.ScannedPorts, baseNmap.ScannedPorts)
+		hostDiff.ClosedPorts = openPortsMissingFrom(baseNmap.ScannedPorts, targetNmap.ScannedPorts)
+	}
+
+	var baseDNS, targetDNS results.DNSLookupResult
+	if ok, err := decodeBoth(base, target, enums.ServiceDNSLookup, &baseDNS, &targetDNS); err != nil {
+		return nil, err
+	} else if ok {
+		hostDiff.DNS = &domain.DNSDiff{
+			Added:   dnsRecordsMissingFrom(targetDNS.DNSRecords, baseDNS.DNSRecords),
+			Removed: dnsRecordsMissingFrom(baseDNS.DNSRecords, targetDNS.DNSRecords),
+		}
+	}
+
+	var baseHarvester, targetHarvester results.HarvesterResult
+	if ok, err := decodeBoth(base, target, enums.ServiceHarvester, &baseHarvester, &targetHarvester); err != nil {
+		return nil, err
+	} else if ok {
+		hostDiff.NewEmails = stringsMissingFrom(targetHarvester.Emails, baseHarvester.Emails)
+		hostDiff.NewSubdomains = stringsMissingFrom(targetHarvester.Subdomains, baseHarvester.Subdomains)
+	}
+
+	var baseWhoIs, targetWhoIs results.WhoIsResult
+	if ok, err := decodeBoth(base, target, enums.ServiceWhoIs, &baseWhoIs, &targetWhoIs); err != nil {
+		return nil, err
+	} else if ok {
+		hostDiff.WhoIs = diffWhoIs(baseWhoIs, targetWhoIs)
+	}
+
+	return hostDiff, nil
+}
+
+// decodeBoth decodes the results of service from both scans,
+// returns false when either scan has no results for it
+func decodeBoth(base, target map[enums.ServiceName]json.RawMessage, service enums.ServiceName, baseDst, targetDst interface{}) (bool, error) {
+	baseRaw, okBase := base[service]
+	targetRaw, okTarget := target[service]
+	if !okBase || !okTarget {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(baseRaw, baseDst); err != nil {
+		return false, fmt.Errorf("failed to decode base %s results: %w", service, err)
+	}
+	if err := json.Unmarshal(targetRaw, targetDst); err != nil {
+		return false, fmt.Errorf("failed to decode target %s results: %w", service, err)
+	}
+	return true, nil
+}
+
+func openPortsMissingFrom(ports []results.PortData, other []results.PortData) []domain.PortDiff {
+	isOpenIn := func(list []results.PortData, p results.PortData) bool {
+		return slices.ContainsFunc(list, func(o results.PortData) bool {
+			return o.ID == p.ID && o.Protocol == p.Protocol && o.State == portStateOpen
+		})
+	}
+
+	diff := []domain.PortDiff{}
+	for _, p := range ports {
+		if p.State == portStateOpen && !isOpenIn(other, p) {
+			diff = append(diff, domain.PortDiff{ID: p.ID, Protocol: p.Protocol, Service: p.Service.Name})
+		}
+	}
+	return diff
+}
+
+func dnsRecordsMissingFrom(records []results.DNSRecord, other []results.DNSRecord) []results.DNSRecord {
+	// TTLs change on every lookup, so records are compared by type, name and value
+	key := func(r results.DNSRecord) string {
+		value, _ := json.Marshal(r.Value)
+		return fmt.Sprintf("%s|%s|%s", r.Type, r.Name, value)
+	}
+
+	otherKeys := map[string]bool{}
+	for _, r := range other {
+		otherKeys[key(r)] = true
+	}
+
+	diff := []results.DNSRecord{}
+	for _, r := range records {
+		if !otherKeys[key(r)] {
+			diff = append(diff, r)
+		}
+	}
+	return diff
+}
+
+func stringsMissingFrom(values []string, other []string) []string {
+	diff := []string{}
+	for _, v := range values {
+		if !slices.Contains(other, v) && !slices.Contains(diff, v) {
+			diff = append(diff, v)
+		}
+	}
+	return diff
+}
+
+var whoIsFieldNames = []string{"expiration_date", "registrant.name", "registrant.organization", "registrant.email"}
+
+func diffWhoIs(base results.WhoIsResult, target results.WhoIsResult) []domain.FieldChange {
+	baseFields := whoIsFields(base)
+	targetFields := whoIsFields(target)
+
+	changes := []domain.FieldChange{}
+	for _, field := range whoIsFieldNames {
+		if baseFields[field] != targetFields[field] {
+			changes = append(changes, domain.FieldChange{Field: field, Before: baseFields[field], After: targetFields[field]})
+		}
+	}
+	return changes
+}
+
+func whoIsFields(r results.WhoIsResult) map[string]string {
+	f := map[string]string{}
+	if r.RawData == nil {
+		return f
+	}
+	if r.RawData.Domain != nil {
+		f["expiration_date"] = r.RawData.Domain.ExpirationDate
+	}
+	if r.RawData.Registrant != nil {
+		f["registrant.name"] = r.RawData.Registrant.Name
+		f["registrant.organization"] = r.RawData.Registrant.Organization
+		f["registrant.email"] = r.RawData.Registrant.Email
+	}
+	return f
+}
+
+func resultsByHost(scan *domain.Scan) map[string]map[enums.ServiceName]json.RawMessage {
+	byHost := map[string]map[enums.ServiceName]json.RawMessage{}
+	for _, rh := range scan.HostsResults {
+		byHost[rh.Host] = rh.Results
+	}
+	return byHost
+}
+
+func scanHasHost(scan *domain.Scan, alias string) bool {
+	return slices.ContainsFunc(scan.Targets, func(t events.Target) bool {
+		return t.Alias == alias
+	})
+}
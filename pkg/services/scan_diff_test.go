@@ -0,0 +1,138 @@
+package services
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/common/common/results"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	whoisparser "github.com/likexian/whois-parser"
+)
+
+func mustRaw(t *testing.T, v interface{}) json.RawMessage {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	return raw
+}
+
+func Test_diffScans(t *testing.T) {
+	base := &domain.Scan{
+		ID:      "base",
+		Targets: []events.Target{{Alias: "web"}, {Alias: "old"}},
+		HostsResults: []domain.ResultHost{{
+			Host: "web",
+			Results: map[enums.ServiceName]json.RawMessage{
+				enums.ServiceNmap: mustRaw(t, results.NmapResult{ScannedPorts: []results.PortData{
+					{ID: 22, Protocol: "tcp", State: "open"},
+					{ID: 80, Protocol: "tcp", State: "open"},
+					{ID: 8080, Protocol: "tcp", State: "closed"},
+				}}),
+				enums.ServiceDNSLookup: mustRaw(t, results.DNSLookupResult{DNSRecords: []results.DNSRecord{
+					{Type: results.ARecord, Name: "example.com", TTL: 300, Value: "1.1.1.1"},
+					{Type: results.MXRecord, Name: "example.com", TTL: 300, Value: "mx.example.com"},
+				}}),
+				enums.ServiceHarvester: mustRaw(t, results.HarvesterResult{
+					Emails:     []string{"admin@example.com"},
+					Subdomains: []string{"www.example.com"},
+				}),
+				enums.ServiceWhoIs: mustRaw(t, results.WhoIsResult{RawData: &whoisparser.WhoisInfo{
+					Domain:     &whoisparser.Domain{ExpirationDate: "2025-01-01"},
+					Registrant: &whoisparser.Contact{Name: "Jane", Organization: "Example"},
+				}}),
+			},
+		}},
+	}
+	target := &domain.Scan{
+		ID:      "target",
+		Targets: []events.Target{{Alias: "web"}, {Alias: "new"}},
+		HostsResults: []domain.ResultHost{{
+			Host: "web",
+			Results: map[enums.ServiceName]json.RawMessage{
+				enums.ServiceNmap: mustRaw(t, results.NmapResult{ScannedPorts: []results.PortData{
+					{ID: 80, Protocol: "tcp", State: "open"},
+					{ID: 8080, Protocol: "tcp", State: "open", Service: results.Service{Name: "http-proxy"}},
+				}}),
+				enums.ServiceDNSLookup: mustRaw(t, results.DNSLookupResult{DNSRecords: []results.DNSRecord{
+					{Type: results.ARecord, Name: "example.com", TTL: 60, Value: "1.1.1.1"},
+					{Type: results.ARecord, Name: "example.com", TTL: 60, Value: "2.2.2.2"},
+				}}),
+				enums.ServiceHarvester: mustRaw(t, results.HarvesterResult{
+					Emails:     []string{"admin@example.com", "ops@example.com"},
+					Subdomains: []string{"www.example.com", "vpn.example.com"},
+				}),
+				enums.ServiceWhoIs: mustRaw(t, results.WhoIsResult{RawData: &whoisparser.WhoisInfo{
+					Domain:     &whoisparser.Domain{ExpirationDate: "2026-01-01"},
+					Registrant: &whoisparser.Contact{Name: "Jane", Organization: "Example"},
+				}}),
+			},
+		}},
+	}
+
+	diff, err := diffScans(base, target)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !slices.Equal(diff.HostsOnlyInBase, []string{"old"}) {
+		t.Errorf("Expected hosts only in base `[old]`, got `%v`", diff.HostsOnlyInBase)
+	}
+	if !slices.Equal(diff.HostsOnlyInTarget, []string{"new"}) {
+		t.Errorf("Expected hosts only in target `[new]`, got `%v`", diff.HostsOnlyInTarget)
+	}
+	if len(diff.Hosts) != 1 {
+		t.Fatalf("Expected 1 host diff, got %d", len(diff.Hosts))
+	}
+
+	host := diff.Hosts[0]
+	if !slices.Equal(host.NewPorts, []domain.PortDiff{{ID: 8080, Protocol: "tcp", Service: "http-proxy"}}) {
+		t.Errorf("Unexpected new ports `%v`", host.NewPorts)
+	}
+	if !slices.Equal(host.ClosedPorts, []domain.PortDiff{{ID: 22, Protocol: "tcp"}}) {
+		t.Errorf("Unexpected closed ports `%v`", host.ClosedPorts)
+	}
+	if host.DNS == nil || len(host.DNS.Added) != 1 || len(host.DNS.Removed) != 1 {
+		t.Fatalf("Expected 1 added and 1 removed DNS record, got `%+v`", host.DNS)
+	}
+	if host.DNS.Added[0].Value != "2.2.2.2" || host.DNS.Removed[0].Type != results.MXRecord {
+		t.Errorf("Unexpected DNS diff `%+v`", host.DNS)
+	}
+	if !slices.Equal(host.NewEmails, []string{"ops@example.com"}) {
+		t.Errorf("Unexpected new emails `%v`", host.NewEmails)
+	}
+	if !slices.Equal(host.NewSubdomains, []string{"vpn.example.com"}) {
+		t.Errorf("Unexpected new subdomains `%v`", host.NewSubdomains)
+	}
+	expectedWhoIs := []domain.FieldChange{{Field: "expiration_date", Before: "2025-01-01", After: "2026-01-01"}}
+	if !slices.Equal(host.WhoIs, expectedWhoIs) {
+		t.Errorf("Expected WhoIs changes `%v`, got `%v`", expectedWhoIs, host.WhoIs)
+	}
+}
+
+func Test_diffScans_missingTool(t *testing.T) {
+	base := &domain.Scan{
+		Targets: []events.Target{{Alias: "web"}},
+		HostsResults: []domain.ResultHost{{
+			Host: "web",
+			Results: map[enums.ServiceName]json.RawMessage{
+				enums.ServiceDNSLookup: mustRaw(t, results.DNSLookupResult{}),
+			},
+		}},
+	}
+	target := &domain.Scan{
+		Targets: []events.Target{{Alias: "web"}},
+	}
+
+	diff, err := diffScans(base, target)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if diff.Hosts[0].DNS != nil || diff.Hosts[0].HasChanges() {
+		t.Errorf("Expected no changes when a tool didn't run in both scans, got `%+v`", diff.Hosts[0])
+	}
+}
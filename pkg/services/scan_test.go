@@ -0,0 +1,380 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/common/common/results"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+// selectorStorage resolves groups and tags from memory
+type selectorStorage struct {
+	interfaces.IStorage
+	groups map[string][]int
+	tags   map[string][]int
+}
+
+func (s *selectorStorage) GetHostGroupByID(ID string, tenantID string) (*domain.HostGroup, error) {
+	hostIDs, ok := s.groups[ID]
+	if !ok {
+		return nil, fmt.Errorf("failed to fetch group: %w", sql.ErrNoRows)
+	}
+	return &domain.HostGroup{ID: ID, TenantID: tenantID, HostIDs: hostIDs}, nil
+}
+
+func (s *selectorStorage) GetHostIDsBySelector(tenantID string, groupIDs []string, tags []string) ([]int, error) {
+	hostIDs := []int{}
+	for _, groupID := range groupIDs {
+		hostIDs = append(hostIDs, s.groups[groupID]...)
+	}
+	for _, tag := range tags {
+		hostIDs = append(hostIDs, s.tags[tag]...)
+	}
+	return hostIDs, nil
+}
+
+func Test_ResolveHostSelector(t *testing.T) {
+	s := ScanService{storage: &selectorStorage{
+		groups: map[string][]int{"web": {3, 4}, "empty": {}},
+		tags:   map[string][]int{"prod": {4, 5}},
+	}}
+
+	tests := []struct {
+		name            string
+		selector        domain.HostSelector
+		expectedHostIDs []int
+		expectedErr     error
+	}{
+		{
+			name:            "Host IDs",
+			selector:        domain.HostSelector{HostIDs: []int{2, 1, 2}},
+			expectedHostIDs: []int{2, 1},
+		},
+		{
+			name:            "Group",
+			selector:        domain.HostSelector{GroupIDs: []string{"web"}},
+			expectedHostIDs: []int{3, 4},
+		},
+		{
+			name:            "Tag is normalized",
+			selector:        domain.HostSelector{Tags: []string{" Prod "}},
+			expectedHostIDs: []int{4, 5},
+		},
+		{
+			name:            "Union keeps explicit hosts first",
+			selector:        domain.HostSelector{HostIDs: []int{5}, GroupIDs: []string{"web"}, Tags: []string{"prod"}},
+			expectedHostIDs: []int{5, 3, 4},
+		},
+		{
+			name:        "Unknown group",
+			selector:    domain.HostSelector{GroupIDs: []string{"db"}},
+			expectedErr: sql.ErrNoRows,
+		},
+		{
+			name:        "Empty group",
+			selector:    domain.HostSelector{GroupIDs: []string{"empty"}},
+			expectedErr: ErrNoHostsSelected,
+		},
+		{
+			name:        "Nothing selected",
+			selector:    domain.HostSelector{},
+			expectedErr: ErrNoHostsSelected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostIDs, err := s.resolveHostSelector(tt.selector, "tenant-1")
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(hostIDs, tt.expectedHostIDs) {
+				t.Errorf("expected %v, got %v", tt.expectedHostIDs, hostIDs)
+			}
+		})
+	}
+}
+
+// activeScanStorage keeps the hosts in memory and the aliases of the hosts with a running scan
+type activeScanStorage struct {
+	interfaces.IStorage
+	hosts   map[int]string
+	scanned []string
+	aliases []string
+}
+
+func (s *activeScanStorage) GetHostByID(ID int, tenantID string) (*domain.Host, error) {
+	name, ok := s.hosts[ID]
+	if !ok {
+		return nil, fmt.Errorf("failed to fetch host: %w", sql.ErrNoRows)
+	}
+	return &domain.Host{ID: ID, TenantID: tenantID, Name: name}, nil
+}
+
+func (s *activeScanStorage) ExistsActiveScanForHosts(tenantID string, aliases []string) (bool, error) {
+	s.aliases = aliases
+	for _, alias := range aliases {
+		if slices.Contains(s.scanned, alias) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func Test_HasActiveScan(t *testing.T) {
+	tests := []struct {
+		name            string
+		hostIDs         []int
+		expected        bool
+		expectedAliases []string
+		expectedErr     error
+	}{
+		{
+			name:            "Host with a running scan",
+			hostIDs:         []int{1, 2},
+			expected:        true,
+			expectedAliases: []string{"web", "db"},
+		},
+		{
+			name:            "Hosts without a running scan",
+			hostIDs:         []int{2, 3},
+			expected:        false,
+			expectedAliases: []string{"db", "mail"},
+		},
+		{
+			name:        "Unknown host",
+			hostIDs:     []int{1, 42},
+			expectedErr: sql.ErrNoRows,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &activeScanStorage{
+				hosts:   map[int]string{1: "web", 2: "db", 3: "mail"},
+				scanned: []string{"web"},
+			}
+			s := NewScanService(storage)
+
+			active, err := s.HasActiveScan(tt.hostIDs, "tenant-1")
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if active != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, active)
+			}
+			if !reflect.DeepEqual(storage.aliases, tt.expectedAliases) {
+				t.Errorf("expected aliases %v, got %v", tt.expectedAliases, storage.aliases)
+			}
+		})
+	}
+}
+
+// updateScanStorage applies updates to a single scan in memory
+type updateScanStorage struct {
+	interfaces.IStorage
+	scan *domain.Scan
+}
+
+func (s *updateScanStorage) UpdateScanByID(ID string, update func(*domain.Scan) error) (*domain.Scan, error) {
+	if err := update(s.scan); err != nil {
+		return nil, err
+	}
+	return s.scan, nil
+}
+
+// newProgressFixture is a pending scan of two hosts running Nmap and WhoIs,
+// progress overrides the Nmap progress of web
+func newProgressFixture(status domain.ScanStatus, progress string) *domain.Scan {
+	metadata := func(nmap string) []domain.Metadata {
+		return []domain.Metadata{
+			{Service: enums.ServiceNmap, Progress: nmap},
+			{Service: enums.ServiceWhoIs, Progress: domain.ProgressPending},
+		}
+	}
+	return &domain.Scan{
+		ID:     "scan-1",
+		Status: status,
+		Targets: []events.Target{
+			{Alias: "web", Value: "example.com"},
+			{Alias: "db", Value: "10.0.0.2"},
+		},
+		HostsStatus: []domain.StatusHost{
+			{Host: "web", Metadata: metadata(progress)},
+			{Host: "db", Metadata: metadata(domain.ProgressPending)},
+		},
+	}
+}
+
+// serviceProgress returns the progress and error of a service on each host
+func serviceProgress(scan *domain.Scan, service enums.ServiceName) map[string]string {
+	progress := map[string]string{}
+	for _, hs := range scan.HostsStatus {
+		for _, m := range hs.Metadata {
+			if m.Service == service {
+				progress[hs.Host] = strings.TrimSuffix(m.Progress+" "+m.Error, " ")
+			}
+		}
+	}
+	return progress
+}
+
+func Test_UpdateServiceProgress(t *testing.T) {
+	tests := []struct {
+		name             string
+		scan             *domain.Scan
+		target           string
+		expectedProgress map[string]string
+		expectedStatus   domain.ScanStatus
+		expectedErr      error
+	}{
+		{
+			name:             "Progress of a target",
+			scan:             newProgressFixture(domain.ScanStatusPending, domain.ProgressPending),
+			target:           "example.com",
+			expectedProgress: map[string]string{"web": "50%", "db": "0%"},
+			expectedStatus:   domain.ScanStatusRunning,
+		},
+		{
+			name:             "Progress of every target",
+			scan:             newProgressFixture(domain.ScanStatusRunning, domain.ProgressPending),
+			target:           "",
+			expectedProgress: map[string]string{"web": "50%", "db": "50%"},
+			expectedStatus:   domain.ScanStatusRunning,
+		},
+		{
+			name:             "Late progress doesn't reopen a completed service",
+			scan:             newProgressFixture(domain.ScanStatusRunning, domain.ProgressCompleted),
+			target:           "",
+			expectedProgress: map[string]string{"web": "100%", "db": "50%"},
+			expectedStatus:   domain.ScanStatusRunning,
+		},
+		{
+			name:        "Unknown target",
+			scan:        newProgressFixture(domain.ScanStatusRunning, domain.ProgressPending),
+			target:      "mail.example.com",
+			expectedErr: ErrUnknownTarget,
+		},
+		{
+			name:        "Finished scan",
+			scan:        newProgressFixture(domain.ScanStatusCancelled, domain.ProgressPending),
+			target:      "",
+			expectedErr: ErrScanFinished,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScanService(&updateScanStorage{scan: tt.scan})
+
+			scan, err := s.UpdateServiceProgress("scan-1", enums.ServiceNmap, tt.target, "50%")
+			if tt.expectedErr != nil {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if progress := serviceProgress(scan, enums.ServiceNmap); !reflect.DeepEqual(progress, tt.expectedProgress) {
+				t.Errorf("expected progress %v, got %v", tt.expectedProgress, progress)
+			}
+			if scan.Status != tt.expectedStatus {
+				t.Errorf("expected status %s, got %s", tt.expectedStatus, scan.Status)
+			}
+		})
+	}
+}
+
+func Test_SaveServiceResults(t *testing.T) {
+	nmapResults := []results.TargetResult{
+		{Target: "example.com", Results: map[enums.ServiceName]interface{}{enums.ServiceNmap: map[string]int{"open_ports": 2}}},
+		{Target: "unknown.example.com", Results: map[enums.ServiceName]interface{}{enums.ServiceNmap: map[string]int{"open_ports": 1}}},
+	}
+	whoIsCompleted := func(scan *domain.Scan) *domain.Scan {
+		for i := range scan.HostsStatus {
+			setServiceProgress(&scan.HostsStatus[i], enums.ServiceWhoIs, domain.ProgressCompleted, "")
+		}
+		return scan
+	}
+
+	tests := []struct {
+		name             string
+		scan             *domain.Scan
+		eventErr         *events.EventError
+		expectedProgress map[string]string
+		expectedResults  []string
+		expectedStatus   domain.ScanStatus
+	}{
+		{
+			name:             "Results complete the service on every host",
+			scan:             newProgressFixture(domain.ScanStatusRunning, "50%"),
+			expectedProgress: map[string]string{"web": "100%", "db": "100%"},
+			expectedResults:  []string{"web"},
+			expectedStatus:   domain.ScanStatusRunning,
+		},
+		{
+			name:             "Last service completes the scan",
+			scan:             whoIsCompleted(newProgressFixture(domain.ScanStatusRunning, "50%")),
+			expectedProgress: map[string]string{"web": "100%", "db": "100%"},
+			expectedResults:  []string{"web"},
+			expectedStatus:   domain.ScanStatusCompleted,
+		},
+		{
+			name:             "Service error fails the scan partially",
+			scan:             whoIsCompleted(newProgressFixture(domain.ScanStatusPending, domain.ProgressPending)),
+			eventErr:         &events.EventError{Code: "timeout", Message: "took too long"},
+			expectedProgress: map[string]string{"web": "100% timeout: took too long", "db": "100% timeout: took too long"},
+			expectedResults:  []string{"web"},
+			expectedStatus:   domain.ScanStatusPartiallyFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScanService(&updateScanStorage{scan: tt.scan})
+
+			scan, err := s.SaveServiceResults("scan-1", enums.ServiceNmap, nmapResults, tt.eventErr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if progress := serviceProgress(scan, enums.ServiceNmap); !reflect.DeepEqual(progress, tt.expectedProgress) {
+				t.Errorf("expected progress %v, got %v", tt.expectedProgress, progress)
+			}
+			hosts := []string{}
+			for _, hr := range scan.HostsResults {
+				if _, ok := hr.Results[enums.ServiceNmap]; ok {
+					hosts = append(hosts, hr.Host)
+				}
+			}
+			if !reflect.DeepEqual(hosts, tt.expectedResults) {
+				t.Errorf("expected results for %v, got %v", tt.expectedResults, hosts)
+			}
+			if scan.Status != tt.expectedStatus {
+				t.Errorf("expected status %s, got %s", tt.expectedStatus, scan.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/robfig/cron/v3"
+)
+
+const MaxSchedulePreviewRuns = 50
+
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+type ScheduleService struct {
+	storage interfaces.IStorage
+}
+
+var _ interfaces.IScheduleService = (*ScheduleService)(nil)
+
+func NewScheduleService(storage interfaces.IStorage) *ScheduleService {
+	return &ScheduleService{
+		storage: storage,
+	}
+}
+
+func (s *ScheduleService) CreateSchedule(actor domain.Actor, sc *domain.ScanSchedule) (*domain.ScanSchedule, error) {
+	if err := s.validateSchedule(sc); err != nil {
+		return nil, err
+	}
+	if err := setNextRun(sc, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.storage.CreateSchedule(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(s.storage, actor, domain.AuditScheduleCreated, "schedule", schedule.ID, nil, schedule)
+	return schedule, nil
+}
+
+func (s *ScheduleService) GetSchedulesByTenantID(tenantID string) ([]*domain.ScanSchedule, error) {
+	schedules, err := s.storage.GetSchedulesByTenantID(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+func (s *ScheduleService) GetScheduleByID(ID string, tenantID string) (*domain.ScanSchedule, error) {
+	schedule, err := s.storage.GetScheduleByID(ID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+// PatchScheduleByID updates the fields of the patch. The next run is computed
+// again only when the patch enables or disables the schedule or changes when
+// it runs, otherwise the pending run is kept.
+func (s *ScheduleService) PatchScheduleByID(actor domain.Actor, ID string, tenantID string, patch domain.ScanSchedulePatch) (*domain.ScanSchedule, error) {
+	before, err := s.storage.GetScheduleByID(ID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := patch.Apply(*before)
+	if err := s.validateSchedule(sc); err != nil {
+		return nil, err
+	}
+	if sc.Enabled != before.Enabled || sc.CronExpression != before.CronExpression || sc.IntervalMinutes != before.IntervalMinutes {
+		if err := setNextRun(sc, time.Now().UTC()); err != nil {
+			return nil, err
+		}
+	}
+
+	schedule, err := s.storage.PatchScheduleByID(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(s.storage, actor, domain.AuditScheduleUpdated, "schedule", schedule.ID, before, schedule)
+	return schedule, nil
+}
+
+func (s *ScheduleService) DeleteScheduleByID(actor domain.Actor, ID string, tenantID string) (bool, error) {
+	before, err := s.storage.GetScheduleByID(ID, tenantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	isDeleted, err := s.storage.DeleteScheduleByID(ID, tenantID)
+	if err != nil {
+		return false, err
+	}
+
+	if isDeleted {
+		recordAudit(s.storage, actor, domain.AuditScheduleDeleted, "schedule", ID, before, nil)
+	}
+	return isDeleted, nil
+}
+
+// PreviewSchedule returns the next count run times of the schedule
+func (s *ScheduleService) PreviewSchedule(ID string, tenantID string, count int) ([]time.Time, error) {
+	schedule, err := s.storage.GetScheduleByID(ID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if count <= 0 || count > MaxSchedulePreviewRuns {
+		return nil, fmt.Errorf("%q: %w", fmt.Sprintf("count must be between 1 and %d", MaxSchedulePreviewRuns), ErrInvalidSchedule)
+	}
+
+	runs := []time.Time{}
+	next := time.Now().UTC()
+	for i := 0; i < count; i++ {
+		next, err = NextScheduleRun(schedule, next)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, next)
+	}
+
+	return runs, nil
+}
+
+func (s *ScheduleService) GetDueSchedules(now time.Time) ([]*domain.ScanSchedule, error) {
+	return s.storage.GetDueSchedules(now)
+}
+
+// MarkScheduleRun records that the schedule ran at runAt and computes its
+// next run. scanID is empty when the run was skipped.
+func (s *ScheduleService) MarkScheduleRun(sc *domain.ScanSchedule, runAt time.Time, scanID string) error {
+	next, err := NextScheduleRun(sc, runAt)
+	if err != nil {
+		return err
+	}
+
+	return s.storage.MarkScheduleRun(sc.ID, runAt, &next, scanID)
+}
+
+func (s *ScheduleService) validateSchedule(sc *domain.ScanSchedule) error {
+	if err := validateScheduleTiming(sc); err != nil {
+		return err
+	}
+	if strings.TrimSpace(sc.Name) == "" {
+		return fmt.Errorf("%q: %w", "name is required", ErrInvalidSchedule)
+	}
+	if len(sc.HostIDs) == 0 {
+		return fmt.Errorf("%q: %w", "at least one host is required", ErrInvalidSchedule)
+	}
+	if err := ValidateTools(sc.Tools); err != nil {
+		return err
+	}
+
+	for _, hostID := range sc.HostIDs {
+		if _, err := s.storage.GetHostByID(hostID, sc.TenantID); err != nil {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("unknown host `%d`", hostID), ErrInvalidSchedule)
+		}
+	}
+
+	return nil
+}
+
+func validateScheduleTiming(sc *domain.ScanSchedule) error {
+	hasCron := sc.CronExpression != ""
+	hasInterval := sc.IntervalMinutes != 0
+
+	if hasCron == hasInterval {
+		return fmt.Errorf("%q: %w", "exactly one of cron_expression or interval_minutes is required", ErrInvalidSchedule)
+	}
+	if hasInterval && sc.IntervalMinutes < 0 {
+		return fmt.Errorf("%q: %w", "interval_minutes must be positive", ErrInvalidSchedule)
+	}
+	if hasCron {
+		if _, err := cron.ParseStandard(sc.CronExpression); err != nil {
+			return fmt.Errorf("%q: %w", err.Error(), ErrInvalidSchedule)
+		}
+	}
+
+	return nil
+}
+
+// NextScheduleRun returns the first run time of the schedule after the given time
+func NextScheduleRun(sc *domain.ScanSchedule, after time.Time) (time.Time, error) {
+	if err := validateScheduleTiming(sc); err != nil {
+		return time.Time{}, err
+	}
+
+	if sc.IntervalMinutes > 0 {
+		return after.Add(time.Duration(sc.IntervalMinutes) * time.Minute), nil
+	}
+
+	schedule, _ := cron.ParseStandard(sc.CronExpression)
+	return schedule.Next(after), nil
+}
+
+// setNextRun sets the next run of an enabled schedule, disabled schedules never run
+func setNextRun(sc *domain.ScanSchedule, now time.Time) error {
+	if !sc.Enabled {
+		sc.NextRunAt = nil
+		return nil
+	}
+
+	next, err := NextScheduleRun(sc, now)
+	if err != nil {
+		return err
+	}
+	sc.NextRunAt = &next
+	return nil
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+)
+
+func Test_NextScheduleRun(t *testing.T) {
+	after := time.Date(2024, 12, 2, 10, 30, 0, 0, time.UTC) // Monday
+
+	tests := []struct {
+		name     string
+		schedule *domain.ScanSchedule
+		expected time.Time
+		wantErr  error
+	}{
+		{
+			name:     "Fixed interval",
+			schedule: &domain.ScanSchedule{IntervalMinutes: 90},
+			expected: time.Date(2024, 12, 2, 12, 0, 0, 0, time.UTC),
+			wantErr:  nil,
+		},
+		{
+			name:     "Daily cron expression",
+			schedule: &domain.ScanSchedule{CronExpression: "0 3 * * *"},
+			expected: time.Date(2024, 12, 3, 3, 0, 0, 0, time.UTC),
+			wantErr:  nil,
+		},
+		{
+			name:     "Weekly cron expression",
+			schedule: &domain.ScanSchedule{CronExpression: "15 22 * * 5"},
+			expected: time.Date(2024, 12, 6, 22, 15, 0, 0, time.UTC),
+			wantErr:  nil,
+		},
+		{
+			name:     "Invalid cron expression",
+			schedule: &domain.ScanSchedule{CronExpression: "every monday"},
+			wantErr:  ErrInvalidSchedule,
+		},
+		{
+			name:     "Both cron expression and interval",
+			schedule: &domain.ScanSchedule{CronExpression: "0 3 * * *", IntervalMinutes: 60},
+			wantErr:  ErrInvalidSchedule,
+		},
+		{
+			name:     "Neither cron expression nor interval",
+			schedule: &domain.ScanSchedule{},
+			wantErr:  ErrInvalidSchedule,
+		},
+		{
+			name:     "Negative interval",
+			schedule: &domain.ScanSchedule{IntervalMinutes: -5},
+			wantErr:  ErrInvalidSchedule,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, err := NextScheduleRun(tt.schedule, after)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error `%v`, got `%v`", tt.wantErr, err)
+			}
+			if err == nil && !next.Equal(tt.expected) {
+				t.Errorf("Expected next run `%v`, got `%v`", tt.expected, next)
+			}
+		})
+	}
+}
+
+// scheduleStorage keeps the schedules and hosts in memory
+type scheduleStorage struct {
+	interfaces.IStorage
+	schedules map[string]*domain.ScanSchedule
+	hosts     []int
+	nextRunAt *time.Time
+	scanID    string
+}
+
+func (s *scheduleStorage) GetScheduleByID(ID string, tenantID string) (*domain.ScanSchedule, error) {
+	sc, ok := s.schedules[ID]
+	if !ok || sc.TenantID != tenantID {
+		return nil, fmt.Errorf("failed to fetch schedule: %w", sql.ErrNoRows)
+	}
+	return sc, nil
+}
+
+func (s *scheduleStorage) PatchScheduleByID(sc *domain.ScanSchedule) (*domain.ScanSchedule, error) {
+	s.schedules[sc.ID] = sc
+	return sc, nil
+}
+
+func (s *scheduleStorage) GetHostByID(ID int, tenantID string) (*domain.Host, error) {
+	if !slices.Contains(s.hosts, ID) {
+		return nil, fmt.Errorf("failed to fetch host: %w", sql.ErrNoRows)
+	}
+	return &domain.Host{ID: ID, TenantID: tenantID}, nil
+}
+
+func (s *scheduleStorage) MarkScheduleRun(ID string, runAt time.Time, nextRunAt *time.Time, scanID string) error {
+	s.nextRunAt, s.scanID = nextRunAt, scanID
+	return nil
+}
+
+func (s *scheduleStorage) CreateAuditEvent(*domain.AuditEvent) error {
+	return nil
+}
+
+func Test_MarkScheduleRun(t *testing.T) {
+	runAt := time.Date(2024, 12, 2, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule *domain.ScanSchedule
+		scanID   string
+		expected time.Time
+		wantErr  error
+	}{
+		{
+			name:     "Interval schedule that started a scan",
+			schedule: &domain.ScanSchedule{ID: "schedule-1", IntervalMinutes: 60},
+			scanID:   "scan-1",
+			expected: time.Date(2024, 12, 2, 11, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "Cron schedule that was skipped",
+			schedule: &domain.ScanSchedule{ID: "schedule-1", CronExpression: "0 3 * * *"},
+			expected: time.Date(2024, 12, 3, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Invalid schedule",
+			schedule: &domain.ScanSchedule{ID: "schedule-1"},
+			wantErr:  ErrInvalidSchedule,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &scheduleStorage{}
+			s := NewScheduleService(storage)
+
+			err := s.MarkScheduleRun(tt.schedule, runAt, tt.scanID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error `%v`, got `%v`", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if storage.nextRunAt == nil || !storage.nextRunAt.Equal(tt.expected) {
+				t.Errorf("Expected next run `%v`, got `%v`", tt.expected, storage.nextRunAt)
+			}
+			if storage.scanID != tt.scanID {
+				t.Errorf("Expected scan `%s`, got `%s`", tt.scanID, storage.scanID)
+			}
+		})
+	}
+}
+
+func Test_PatchScheduleByID(t *testing.T) {
+	name := "Weekly"
+	cronExpression := "0 3 * * 1"
+	interval := 30
+	disabled := false
+	hostIDs := []int{2}
+	unknownHostIDs := []int{42}
+	tools := []domain.ScanTool{{Service: enums.ServiceNmap, Options: map[string]interface{}{"ports": "1-1024"}}}
+	unknownTools := []domain.ScanTool{{Service: "Unknown"}}
+	nextRunAt := time.Date(2024, 12, 2, 11, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		patch        domain.ScanSchedulePatch
+		expected     domain.ScanSchedule
+		keepsNextRun bool
+		wantErr      error
+	}{
+		{
+			name:  "Rename keeps the other fields",
+			patch: domain.ScanSchedulePatch{Name: &name},
+			expected: domain.ScanSchedule{
+				Name: "Weekly", HostIDs: []int{1}, IntervalMinutes: 60, Enabled: true,
+			},
+			keepsNextRun: true,
+		},
+		{
+			name:  "Cron expression replaces the interval",
+			patch: domain.ScanSchedulePatch{CronExpression: &cronExpression},
+			expected: domain.ScanSchedule{
+				Name: "Hourly", HostIDs: []int{1}, CronExpression: "0 3 * * 1", Enabled: true,
+			},
+		},
+		{
+			name:  "New interval and hosts",
+			patch: domain.ScanSchedulePatch{IntervalMinutes: &interval, HostIDs: &hostIDs},
+			expected: domain.ScanSchedule{
+				Name: "Hourly", HostIDs: []int{2}, IntervalMinutes: 30, Enabled: true,
+			},
+		},
+		{
+			name:  "Disable",
+			patch: domain.ScanSchedulePatch{Enabled: &disabled},
+			expected: domain.ScanSchedule{
+				Name: "Hourly", HostIDs: []int{1}, IntervalMinutes: 60, Enabled: false,
+			},
+		},
+		{
+			name:  "Tools",
+			patch: domain.ScanSchedulePatch{Tools: &tools},
+			expected: domain.ScanSchedule{
+				Name: "Hourly", HostIDs: []int{1}, IntervalMinutes: 60, Tools: tools, Enabled: true,
+			},
+			keepsNextRun: true,
+		},
+		{
+			name:    "Unknown tool",
+			patch:   domain.ScanSchedulePatch{Tools: &unknownTools},
+			wantErr: ErrInvalidTool,
+		},
+		{
+			name:    "Both cron expression and interval",
+			patch:   domain.ScanSchedulePatch{CronExpression: &cronExpression, IntervalMinutes: &interval},
+			wantErr: ErrInvalidSchedule,
+		},
+		{
+			name:    "Unknown host",
+			patch:   domain.ScanSchedulePatch{HostIDs: &unknownHostIDs},
+			wantErr: ErrInvalidSchedule,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &scheduleStorage{
+				schedules: map[string]*domain.ScanSchedule{
+					"schedule-1": {
+						ID: "schedule-1", TenantID: "tenant-1", Name: "Hourly",
+						HostIDs: []int{1}, IntervalMinutes: 60, Enabled: true, NextRunAt: &nextRunAt,
+					},
+				},
+				hosts: []int{1, 2},
+			}
+			s := NewScheduleService(storage)
+
+			sc, err := s.PatchScheduleByID(domain.Actor{TenantID: "tenant-1"}, "schedule-1", "tenant-1", tt.patch)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error `%v`, got `%v`", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if sc.Name != tt.expected.Name || !slices.Equal(sc.HostIDs, tt.expected.HostIDs) ||
+				sc.CronExpression != tt.expected.CronExpression || sc.IntervalMinutes != tt.expected.IntervalMinutes ||
+				!reflect.DeepEqual(sc.Tools, tt.expected.Tools) || sc.Enabled != tt.expected.Enabled {
+				t.Errorf("Expected schedule `%+v`, got `%+v`", tt.expected, *sc)
+			}
+			if (sc.NextRunAt != nil) != sc.Enabled {
+				t.Errorf("Expected a next run only for enabled schedules, got `%v`", sc.NextRunAt)
+			}
+			if sc.NextRunAt != nil && sc.NextRunAt.Equal(nextRunAt) != tt.keepsNextRun {
+				t.Errorf("Expected the next run to be kept: %t, got `%v`", tt.keepsNextRun, sc.NextRunAt)
+			}
+		})
+	}
+}
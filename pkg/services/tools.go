@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/common/common/results"
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+var ErrInvalidTool = errors.New("invalid tool")
+
+// optionValidator validates a single tool option as decoded from JSON
+type optionValidator func(value interface{}) error
+
+// toolRegistry lists the scanning services a scan can run, and the options each one accepts
+var toolRegistry = map[enums.ServiceName]map[string]optionValidator{
+	enums.ServiceHarvester: {
+		"limit":   validatePositiveInt,
+		"sources": validateStringList(nil),
+	},
+	enums.ServiceWhoIs: {},
+	enums.ServiceDNSLookup: {
+		"record_types": validateStringList([]string{
+			string(results.ARecord), string(results.AAAARecord), string(results.CNAMERecord),
+			string(results.TXTRecord), string(results.NSRecord), string(results.MXRecord),
+			string(results.SOARecord), string(results.DNSKeyRecord),
+		}),
+	},
+	enums.ServiceNmap: {
+		"ports":  validatePortRange,
+		"timing": validateTimingTemplate,
+	},
+}
+
+// defaultTools is the order tools run in when a scan doesn't select any
+var defaultTools = []enums.ServiceName{
+	enums.ServiceHarvester,
+	enums.ServiceWhoIs,
+	enums.ServiceDNSLookup,
+	enums.ServiceNmap,
+}
+
+var portRangeRegex = regexp.MustCompile(`^\d+(-\d+)?(,\d+(-\d+)?)*$`)
+
+// DefaultTools returns every known tool without options
+func DefaultTools() []domain.ScanTool {
+	tools := []domain.ScanTool{}
+	for _, service := range defaultTools {
+		tools = append(tools, domain.ScanTool{Service: service})
+	}
+	return tools
+}
+
+// ValidateTools checks that every tool is known, selected once,
+// and that its options are accepted by the tool
+func ValidateTools(tools []domain.ScanTool) error {
+	seen := map[enums.ServiceName]bool{}
+
+	for _, tool := range tools {
+		options, ok := toolRegistry[tool.Service]
+		if !ok {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("unknown tool `%s`", tool.Service), ErrInvalidTool)
+		}
+		if seen[tool.Service] {
+			return fmt.Errorf("%q: %w", fmt.Sprintf("tool `%s` selected more than once", tool.Service), ErrInvalidTool)
+		}
+		seen[tool.Service] = true
+
+		for name, value := range tool.Options {
+			validate, ok := options[name]
+			if !ok {
+				return fmt.Errorf("%q: %w", fmt.Sprintf("unknown option `%s` for tool `%s`", name, tool.Service), ErrInvalidTool)
+			}
+			if err := validate(value); err != nil {
+				return fmt.Errorf("%q: %w", fmt.Sprintf("invalid option `%s` for tool `%s`: %s", name, tool.Service, err.Error()), ErrInvalidTool)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validatePositiveInt(value interface{}) error {
+	// JSON numbers are decoded as float64
+	n, ok := value.(float64)
+	if !ok || n != float64(int(n)) || n <= 0 {
+		return errors.New("must be a positive integer")
+	}
+	return nil
+}
+
+func validateTimingTemplate(value interface{}) error {
+	n, ok := value.(float64)
+	if !ok || n != float64(int(n)) || n < 0 || n > 5 {
+		return errors.New("must be an integer between 0 and 5")
+	}
+	return nil
+}
+
+func validatePortRange(value interface{}) error {
+	ports, ok := value.(string)
+	if !ok || !portRangeRegex.MatchString(ports) {
+		return errors.New("must be a list of ports or port ranges, e.g. `22,80,1000-2000`")
+	}
+
+	for _, part := range strings.Split(ports, ",") {
+		bounds := strings.Split(part, "-")
+		prev := 0
+		for _, bound := range bounds {
+			port, err := strconv.Atoi(bound)
+			if err != nil || port < 1 || port > 65535 {
+				return fmt.Errorf("port `%s` out of range", bound)
+			}
+			if port < prev {
+				return fmt.Errorf("range `%s` is reversed", part)
+			}
+			prev = port
+		}
+	}
+	return nil
+}
+
+// validateStringList accepts a list of strings, limited to allowed when it's not nil
+func validateStringList(allowed []string) optionValidator {
+	return func(value interface{}) error {
+		list, ok := value.([]interface{})
+		if !ok || len(list) == 0 {
+			return errors.New("must be a non-empty list of strings")
+		}
+		for _, item := range list {
+			str, ok := item.(string)
+			if !ok {
+				return errors.New("must be a non-empty list of strings")
+			}
+			if allowed != nil && !slices.Contains(allowed, str) {
+				return fmt.Errorf("`%s` must be one of `%v`", str, allowed)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kptm-tools/common/common/enums"
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+func Test_ValidateTools(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []domain.ScanTool
+		wantErr error
+	}{
+		{
+			name:    "Default tools",
+			input:   DefaultTools(),
+			wantErr: nil,
+		},
+		{
+			name: "Nmap with port range and timing",
+			input: []domain.ScanTool{
+				{Service: enums.ServiceNmap, Options: map[string]interface{}{"ports": "22,80,1000-2000", "timing": float64(4)}},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "DNSLookup with record types",
+			input: []domain.ScanTool{
+				{Service: enums.ServiceDNSLookup, Options: map[string]interface{}{"record_types": []interface{}{"A", "MX"}}},
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "Unknown tool",
+			input:   []domain.ScanTool{{Service: "Nikto"}},
+			wantErr: ErrInvalidTool,
+		},
+		{
+			name:    "Duplicated tool",
+			input:   []domain.ScanTool{{Service: enums.ServiceWhoIs}, {Service: enums.ServiceWhoIs}},
+			wantErr: ErrInvalidTool,
+		},
+		{
+			name: "Unknown option",
+			input: []domain.ScanTool{
+				{Service: enums.ServiceWhoIs, Options: map[string]interface{}{"ports": "80"}},
+			},
+			wantErr: ErrInvalidTool,
+		},
+		{
+			name: "Port out of range",
+			input: []domain.ScanTool{
+				{Service: enums.ServiceNmap, Options: map[string]interface{}{"ports": "1-70000"}},
+			},
+			wantErr: ErrInvalidTool,
+		},
+		{
+			name: "Reversed port range",
+			input: []domain.ScanTool{
+				{Service: enums.ServiceNmap, Options: map[string]interface{}{"ports": "443-80"}},
+			},
+			wantErr: ErrInvalidTool,
+		},
+		{
+			name: "Timing template out of range",
+			input: []domain.ScanTool{
+				{Service: enums.ServiceNmap, Options: map[string]interface{}{"timing": float64(6)}},
+			},
+			wantErr: ErrInvalidTool,
+		},
+		{
+			name: "Unknown DNS record type",
+			input: []domain.ScanTool{
+				{Service: enums.ServiceDNSLookup, Options: map[string]interface{}{"record_types": []interface{}{"PTR"}}},
+			},
+			wantErr: ErrInvalidTool,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTools(tt.input)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error `%v`, got `%v`", tt.wantErr, err)
+			}
+		})
+	}
+}
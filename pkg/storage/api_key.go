@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = `id, tenant_id, name, prefix, key_hash, scopes, created_by, expires_at, last_used_at, created_at`
+
+func (s *PostgreSQLStore) ClearAPIKeysTable() error {
+	query := `TRUNCATE TABLE api_keys`
+
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *PostgreSQLStore) CreateAPIKey(k *domain.APIKey) (*domain.APIKey, error) {
+	query := fmt.Sprintf(`
+    INSERT INTO api_keys (id, tenant_id, name, prefix, key_hash, scopes, created_by, expires_at, created_at)
+    values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    RETURNING %s
+  `, apiKeyColumns)
+
+	tx, err := s.beginTenantTx(k.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	key, err := scanIntoAPIKey(tx.QueryRow(query, k.ID, k.TenantID, k.Name, k.Prefix, k.Hash,
+		pq.Array(rolesToStrings(k.Scopes)), k.CreatedBy, k.ExpiresAt, k.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert api key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return key, nil
+}
+
+func (s *PostgreSQLStore) GetAPIKeysByTenantID(tenantID string) ([]*domain.APIKey, error) {
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM api_keys
+    WHERE tenant_id=$1
+    ORDER BY created_at, id
+  `, apiKeyColumns)
+
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*domain.APIKey{}
+	for rows.Next() {
+		key, err := scanIntoAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *PostgreSQLStore) GetAPIKeyByID(ID string, tenantID string) (*domain.APIKey, error) {
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM api_keys
+    WHERE id=$1 AND tenant_id=$2
+  `, apiKeyColumns)
+
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	key, err := scanIntoAPIKey(tx.QueryRow(query, ID, tenantID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// GetAPIKeyByHash finds the key of a request. The tenant isn't known until the
+// key is found, so the lookup runs outside row-level security.
+func (s *PostgreSQLStore) GetAPIKeyByHash(hash string) (*domain.APIKey, error) {
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM api_keys
+    WHERE key_hash=$1
+  `, apiKeyColumns)
+
+	key, err := scanIntoAPIKey(s.db.QueryRow(query, hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (s *PostgreSQLStore) PatchAPIKeyByID(k *domain.APIKey) (*domain.APIKey, error) {
+	query := fmt.Sprintf(`
+    UPDATE api_keys
+    SET name=$3, scopes=$4, expires_at=$5
+    WHERE id=$1 AND tenant_id=$2
+    RETURNING %s
+  `, apiKeyColumns)
+
+	tx, err := s.beginTenantTx(k.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	key, err := scanIntoAPIKey(tx.QueryRow(query, k.ID, k.TenantID, k.Name, pq.Array(rolesToStrings(k.Scopes)), k.ExpiresAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update api key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return key, nil
+}
+
+func (s *PostgreSQLStore) DeleteAPIKeyByID(ID string, tenantID string) (bool, error) {
+	query := `
+    DELETE
+    FROM api_keys
+    WHERE id=$1 AND tenant_id=$2
+  `
+	return s.deleteInTenantTx(tenantID, query, ID, tenantID)
+}
+
+// TouchAPIKey records when the key was last used
+func (s *PostgreSQLStore) TouchAPIKey(ID string, usedAt time.Time) error {
+	query := `
+    UPDATE api_keys
+    SET last_used_at=$2
+    WHERE id=$1
+  `
+
+	if _, err := s.db.Exec(query, ID, usedAt.UTC()); err != nil {
+		return fmt.Errorf("failed to update api key last use: %w", err)
+	}
+	return nil
+}
+
+func scanIntoAPIKey(row rowScanner) (*domain.APIKey, error) {
+	key := new(domain.APIKey)
+	var scopes pq.StringArray
+	if err := row.Scan(&key.ID, &key.TenantID, &key.Name, &key.Prefix, &key.Hash, &scopes,
+		&key.CreatedBy, &key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	key.Scopes = make([]domain.Role, len(scopes))
+	for i, scope := range scopes {
+		key.Scopes[i] = domain.Role(scope)
+	}
+	return key, nil
+}
+
+func rolesToStrings(roles []domain.Role) []string {
+	strs := make([]string, len(roles))
+	for i, role := range roles {
+		strs[i] = role.String()
+	}
+	return strs
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+const auditEventColumns = `id, tenant_id, user_id, action, resource_type, resource_id, changes, request_id, created_at`
+
+func (s *PostgreSQLStore) ClearAuditEventsTable() error {
+	query := `TRUNCATE TABLE audit_events`
+
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *PostgreSQLStore) CreateAuditEvent(e *domain.AuditEvent) error {
+	changesJSONB, err := json.Marshal(e.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit changes: %w", err)
+	}
+
+	query := `
+    INSERT INTO audit_events (` + auditEventColumns + `)
+    values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+  `
+
+	tx, err := s.beginTenantTx(e.TenantID)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(query, e.ID, e.TenantID, e.UserID, e.Action, e.ResourceType, e.ResourceID,
+		changesJSONB, e.RequestID, e.CreatedAt); err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuditEvents lists the audit events of a tenant, newest first
+func (s *PostgreSQLStore) GetAuditEvents(filter domain.AuditFilter) (*domain.AuditPage, error) {
+	conditions := []string{"tenant_id = ?"}
+	args := []interface{}{filter.TenantID}
+
+	if filter.UserID != "" {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, filter.UserID)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.ResourceType != "" {
+		conditions = append(conditions, "resource_type = ?")
+		args = append(args, filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		conditions = append(conditions, "resource_id = ?")
+		args = append(args, filter.ResourceID)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, filter.To.UTC())
+	}
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		createdAt, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", err.Error(), domain.ErrInvalidCursor)
+		}
+		conditions = append(conditions, "(created_at, id) < (?, ?)")
+		args = append(args, createdAt, c.ID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = domain.DefaultAuditPageSize
+	}
+	if limit > domain.MaxAuditPageSize {
+		limit = domain.MaxAuditPageSize
+	}
+	// Fetch one extra row to know if there is a next page
+	args = append(args, limit+1)
+
+	query := replaceSQL(fmt.Sprintf(`
+    SELECT %s
+    FROM audit_events
+    WHERE %s
+    ORDER BY created_at DESC, id DESC
+    LIMIT ?
+  `, auditEventColumns, strings.Join(conditions, " AND ")), "?")
+
+	tx, err := s.beginTenantTx(filter.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching audit events: %w", err)
+	}
+	defer rows.Close()
+
+	page := &domain.AuditPage{Events: []*domain.AuditEvent{}}
+	for rows.Next() {
+		event, err := scanIntoAuditEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		page.Events = append(page.Events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit events: %w", err)
+	}
+
+	if len(page.Events) > limit {
+		page.Events = page.Events[:limit]
+		last := page.Events[limit-1]
+		page.NextCursor = encodeCursor(last.CreatedAt.Format(time.RFC3339Nano), last.ID)
+	}
+
+	return page, nil
+}
+
+func scanIntoAuditEvent(row rowScanner) (*domain.AuditEvent, error) {
+	event := new(domain.AuditEvent)
+	var changes []byte
+	if err := row.Scan(&event.ID, &event.TenantID, &event.UserID, &event.Action, &event.ResourceType,
+		&event.ResourceID, &changes, &event.RequestID, &event.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(changes, &event.Changes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal audit changes: %w", err)
+	}
+
+	return event, nil
+}
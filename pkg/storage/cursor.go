@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+// pageCursor is the opaque keyset cursor handed out to API clients.
+// Value holds the sort column of the last row and ID its tiebreaker.
+type pageCursor struct {
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+func encodeCursor(value, id string) string {
+	b, _ := json.Marshal(pageCursor{Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(cursor string) (*pageCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", err.Error(), domain.ErrInvalidCursor)
+	}
+
+	c := &pageCursor{}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("%q: %w", err.Error(), domain.ErrInvalidCursor)
+	}
+	if c.ID == "" {
+		return nil, fmt.Errorf("%q: %w", "missing id", domain.ErrInvalidCursor)
+	}
+
+	return c, nil
+}
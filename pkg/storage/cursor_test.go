@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+func Test_decodeCursor(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantValue string
+		wantID    string
+		wantErr   error
+	}{
+		{
+			name:      "Valid cursor",
+			input:     encodeCursor("2024-12-01T10:00:00Z", "b2131c96-bc4d-4dab-86c8-e5ff3e70b3f9"),
+			wantValue: "2024-12-01T10:00:00Z",
+			wantID:    "b2131c96-bc4d-4dab-86c8-e5ff3e70b3f9",
+			wantErr:   nil,
+		},
+		{
+			name:    "Cursor that is not base64",
+			input:   "not a cursor!",
+			wantErr: domain.ErrInvalidCursor,
+		},
+		{
+			name:    "Cursor that is not JSON",
+			input:   "bm90IGpzb24",
+			wantErr: domain.ErrInvalidCursor,
+		},
+		{
+			name:    "Cursor without id",
+			input:   encodeCursor("2024-12-01T10:00:00Z", ""),
+			wantErr: domain.ErrInvalidCursor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := decodeCursor(tt.input)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error `%v`, got `%v`", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if c.Value != tt.wantValue || c.ID != tt.wantID {
+				t.Errorf("Expected cursor `%s|%s`, got `%s|%s`", tt.wantValue, tt.wantID, c.Value, c.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,494 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/lib/pq"
+)
+
+func (s *PostgreSQLStore) ClearHostGroupsTables() error {
+	query := `TRUNCATE TABLE host_group_members, host_groups, host_tags, tags RESTART IDENTITY CASCADE`
+
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const tagColumns = `tags.id, tags.tenant_id, tags.name, tags.created_at,
+  (SELECT COUNT(*) FROM host_tags JOIN hosts ON hosts.id = host_tags.host_id
+   WHERE host_tags.tag_id = tags.id AND hosts.deleted_at IS NULL)`
+
+func (s *PostgreSQLStore) CreateTag(t *domain.Tag) (*domain.Tag, error) {
+	query := fmt.Sprintf(`
+    INSERT INTO tags (id, tenant_id, name, created_at)
+    values ($1, $2, $3, $4)
+    RETURNING %s
+  `, tagColumns)
+
+	tx, err := s.beginTenantTx(t.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tag, err := scanIntoTag(tx.QueryRow(query, t.ID, t.TenantID, t.Name, t.CreatedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert tag: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return tag, nil
+}
+
+func (s *PostgreSQLStore) GetTagsByTenantID(tenantID string) ([]*domain.Tag, error) {
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM tags
+    WHERE tenant_id=$1
+    ORDER BY name
+  `, tagColumns)
+
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []*domain.Tag{}
+	for rows.Next() {
+		tag, err := scanIntoTag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (s *PostgreSQLStore) GetTagByID(ID string, tenantID string) (*domain.Tag, error) {
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM tags
+    WHERE id=$1 AND tenant_id=$2
+  `, tagColumns)
+
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tag, err := scanIntoTag(tx.QueryRow(query, ID, tenantID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+func (s *PostgreSQLStore) PatchTagByID(t *domain.Tag) (*domain.Tag, error) {
+	query := fmt.Sprintf(`
+    UPDATE tags
+    SET name=$3
+    WHERE id=$1 AND tenant_id=$2
+    RETURNING %s
+  `, tagColumns)
+
+	tx, err := s.beginTenantTx(t.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tag, err := scanIntoTag(tx.QueryRow(query, t.ID, t.TenantID, t.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return tag, nil
+}
+
+func (s *PostgreSQLStore) DeleteTagByID(ID string, tenantID string) (bool, error) {
+	query := `
+    DELETE
+    FROM tags
+    WHERE id=$1 AND tenant_id=$2
+  `
+	return s.deleteInTenantTx(tenantID, query, ID, tenantID)
+}
+
+// ExistsTagName reports whether another tag of the tenant than exceptID is called name
+func (s *PostgreSQLStore) ExistsTagName(tenantID string, name string, exceptID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM tags WHERE tenant_id=$1 AND name=$2 AND id::text <> $3)`
+
+	var exists bool
+	if err := s.db.QueryRow(query, tenantID, name, exceptID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check tag name: %w", err)
+	}
+
+	return exists, nil
+}
+
+// SetHostTags replaces the tags of a host, tags the tenant doesn't have yet are created.
+// It returns sql.ErrNoRows when the host isn't one of the tenant.
+func (s *PostgreSQLStore) SetHostTags(hostID int, tenantID string, tags []string) ([]string, error) {
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM hosts WHERE id=$1 AND tenant_id=$2 AND deleted_at IS NULL)`, hostID, tenantID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to fetch host: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("failed to fetch host: %w", sql.ErrNoRows)
+	}
+
+	insertTag := `
+    INSERT INTO tags (id, tenant_id, name, created_at)
+    SELECT gen_random_uuid(), $1, name, $3
+    FROM unnest($2::text[]) AS name
+    ON CONFLICT (tenant_id, name) DO NOTHING
+  `
+	if _, err := tx.Exec(insertTag, tenantID, pq.Array(tags), time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("failed to insert tags: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM host_tags WHERE host_id=$1`, hostID); err != nil {
+		return nil, fmt.Errorf("failed to clear host tags: %w", err)
+	}
+
+	linkTags := `
+    INSERT INTO host_tags (host_id, tag_id)
+    SELECT $1, id FROM tags WHERE tenant_id=$2 AND name = ANY($3)
+  `
+	if _, err := tx.Exec(linkTags, hostID, tenantID, pq.Array(tags)); err != nil {
+		return nil, fmt.Errorf("failed to tag host: %w", err)
+	}
+
+	host := &domain.Host{ID: hostID}
+	if err := getHostTags(tx, []*domain.Host{host}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return host.Tags, nil
+}
+
+// getHostTags sets the tag names of hosts with a single query
+func getHostTags(tx *sql.Tx, hosts []*domain.Host) error {
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	byID := map[int]*domain.Host{}
+	ids := make([]int64, 0, len(hosts))
+	for _, host := range hosts {
+		host.Tags = []string{}
+		byID[host.ID] = host
+		ids = append(ids, int64(host.ID))
+	}
+
+	query := `
+    SELECT host_tags.host_id, tags.name
+    FROM host_tags JOIN tags ON tags.id = host_tags.tag_id
+    WHERE host_tags.host_id = ANY($1)
+    ORDER BY tags.name
+  `
+	rows, err := tx.Query(query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to fetch host tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			hostID int
+			name   string
+		)
+		if err := rows.Scan(&hostID, &name); err != nil {
+			return fmt.Errorf("failed to scan host tag: %w", err)
+		}
+		byID[hostID].Tags = append(byID[hostID].Tags, name)
+	}
+
+	return rows.Err()
+}
+
+const hostGroupColumns = `host_groups.id, host_groups.tenant_id, host_groups.name, COALESCE(host_groups.description, ''),
+  ARRAY(SELECT host_id FROM host_group_members JOIN hosts ON hosts.id = host_group_members.host_id
+        WHERE group_id = host_groups.id AND hosts.deleted_at IS NULL ORDER BY host_id),
+  host_groups.created_at, host_groups.updated_at`
+
+func (s *PostgreSQLStore) CreateHostGroup(g *domain.HostGroup) (*domain.HostGroup, error) {
+	query := `
+    INSERT INTO host_groups (id, tenant_id, name, description, created_at, updated_at)
+    values ($1, $2, $3, $4, $5, $6)
+  `
+
+	tx, err := s.beginTenantTx(g.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(query, g.ID, g.TenantID, g.Name, nullableString(g.Description), g.CreatedAt, g.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to insert group: %w", err)
+	}
+	if err := setHostGroupMembers(tx, g.ID, g.HostIDs); err != nil {
+		return nil, err
+	}
+
+	group, err := getHostGroup(tx, g.ID, g.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return group, nil
+}
+
+func (s *PostgreSQLStore) GetHostGroupsByTenantID(tenantID string) ([]*domain.HostGroup, error) {
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM host_groups
+    WHERE tenant_id=$1
+    ORDER BY name
+  `, hostGroupColumns)
+
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []*domain.HostGroup{}
+	for rows.Next() {
+		group, err := scanIntoHostGroup(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+func (s *PostgreSQLStore) GetHostGroupByID(ID string, tenantID string) (*domain.HostGroup, error) {
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return getHostGroup(tx, ID, tenantID)
+}
+
+func (s *PostgreSQLStore) PatchHostGroupByID(g *domain.HostGroup) (*domain.HostGroup, error) {
+	query := `
+    UPDATE host_groups
+    SET name=$3, description=$4, updated_at=$5
+    WHERE id=$1 AND tenant_id=$2
+  `
+
+	tx, err := s.beginTenantTx(g.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, g.ID, g.TenantID, g.Name, nullableString(g.Description), time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update group: %w", err)
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return nil, fmt.Errorf("failed to update group: %w", sql.ErrNoRows)
+	}
+	if err := setHostGroupMembers(tx, g.ID, g.HostIDs); err != nil {
+		return nil, err
+	}
+
+	group, err := getHostGroup(tx, g.ID, g.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return group, nil
+}
+
+func (s *PostgreSQLStore) DeleteHostGroupByID(ID string, tenantID string) (bool, error) {
+	query := `
+    DELETE
+    FROM host_groups
+    WHERE id=$1 AND tenant_id=$2
+  `
+	return s.deleteInTenantTx(tenantID, query, ID, tenantID)
+}
+
+// ExistsHostGroupName reports whether another group of the tenant than exceptID is called name
+func (s *PostgreSQLStore) ExistsHostGroupName(tenantID string, name string, exceptID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM host_groups WHERE tenant_id=$1 AND name=$2 AND id::text <> $3)`
+
+	var exists bool
+	if err := s.db.QueryRow(query, tenantID, name, exceptID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check group name: %w", err)
+	}
+
+	return exists, nil
+}
+
+// GetHostIDsBySelector returns the IDs of the hosts in any of the groups or
+// with any of the tags, in ID order
+func (s *PostgreSQLStore) GetHostIDsBySelector(tenantID string, groupIDs []string, tags []string) ([]int, error) {
+	query := `
+    SELECT hosts.id
+    FROM hosts
+    WHERE hosts.tenant_id=$1 AND hosts.deleted_at IS NULL AND (
+      EXISTS (SELECT 1 FROM host_group_members WHERE host_group_members.host_id = hosts.id AND host_group_members.group_id::text = ANY($2))
+      OR EXISTS (
+        SELECT 1 FROM host_tags JOIN tags ON tags.id = host_tags.tag_id
+        WHERE host_tags.host_id = hosts.id AND tags.name = ANY($3)
+      )
+    )
+    ORDER BY hosts.id
+  `
+
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, tenantID, pq.Array(groupIDs), pq.Array(tags))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch hosts: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan host: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func getHostGroup(tx *sql.Tx, ID string, tenantID string) (*domain.HostGroup, error) {
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM host_groups
+    WHERE id=$1 AND tenant_id=$2
+  `, hostGroupColumns)
+
+	group, err := scanIntoHostGroup(tx.QueryRow(query, ID, tenantID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch group: %w", err)
+	}
+
+	return group, nil
+}
+
+func setHostGroupMembers(tx *sql.Tx, groupID string, hostIDs []int) error {
+	if _, err := tx.Exec(`DELETE FROM host_group_members WHERE group_id=$1`, groupID); err != nil {
+		return fmt.Errorf("failed to clear group members: %w", err)
+	}
+
+	ids := make([]int64, 0, len(hostIDs))
+	for _, id := range hostIDs {
+		ids = append(ids, int64(id))
+	}
+	query := `
+    INSERT INTO host_group_members (group_id, host_id)
+    SELECT $1, id FROM hosts WHERE id = ANY($2) AND deleted_at IS NULL
+    ON CONFLICT DO NOTHING
+  `
+	if _, err := tx.Exec(query, groupID, pq.Array(ids)); err != nil {
+		return fmt.Errorf("failed to insert group members: %w", err)
+	}
+
+	return nil
+}
+
+// deleteInTenantTx runs a delete of a single row limited to the rows of tenantID
+func (s *PostgreSQLStore) deleteInTenantTx(tenantID string, query string, args ...interface{}) (bool, error) {
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	count, _ := res.RowsAffected()
+	return count == 1, nil
+}
+
+func scanIntoTag(row rowScanner) (*domain.Tag, error) {
+	tag := new(domain.Tag)
+	if err := row.Scan(&tag.ID, &tag.TenantID, &tag.Name, &tag.CreatedAt, &tag.HostCount); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+func scanIntoHostGroup(row rowScanner) (*domain.HostGroup, error) {
+	group := new(domain.HostGroup)
+	var hostIDs pq.Int64Array
+	if err := row.Scan(&group.ID, &group.TenantID, &group.Name, &group.Description, &hostIDs, &group.CreatedAt, &group.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	group.HostIDs = []int{}
+	for _, id := range hostIDs {
+		group.HostIDs = append(group.HostIDs, int(id))
+	}
+	return group, nil
+}
@@ -3,60 +3,16 @@ package storage
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/lib/pq"
 )
 
-func (s *PostgreSQLStore) CreateHostsTable() error {
-	query := `create table if not exists hosts (
-      id SERIAL PRIMARY KEY,
-      tenant_id UUID,
-      operator_id UUID,
-      domain VARCHAR(2048),
-      ip VARCHAR(15),
-      alias VARCHAR(2048) UNIQUE NOT NULL,
-      rapporteurs JSONB,
-      created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-      updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-  )`
-
-	_, err := s.db.Query(query)
-
-	if err != nil {
-		return err
-	}
-
-	queryEnablePgcrypto := `create extension if not exists pgcrypto;`
-	_, errPgCrypto := s.db.Query(queryEnablePgcrypto)
-	if errPgCrypto != nil {
-		return errPgCrypto
-	}
-
-	return nil
-
-}
-
-func (s *PostgreSQLStore) CreateCredentialsTable() error {
-	query := `create table if not exists credentials (
-      id SERIAL PRIMARY KEY,
-      host_id integer REFERENCES hosts (id) ON DELETE CASCADE,
-      username text  NOT NULL,
-      password text  NOT NULL
-  )`
-
-	_, err := s.db.Query(query)
-
-	if err != nil {
-		return err
-	}
-
-	return nil
-
-}
-
 func (s *PostgreSQLStore) ClearHostsTable() error {
 	query := `TRUNCATE TABLE hosts RESTART IDENTITY CASCADE`
 
@@ -68,18 +24,21 @@ func (s *PostgreSQLStore) ClearHostsTable() error {
 	return nil
 }
 
+// hostColumns are scanned by scanIntoHost, ip is an inet that's NULL for hosts without one
+const hostColumns = `id, tenant_id, operator_id, domain, COALESCE(host(ip), ''), alias, rapporteurs, created_at, updated_at, deleted_at`
+
 func (s *PostgreSQLStore) CreateHost(t *domain.Host) (*domain.Host, error) {
 
-	tx, err := s.db.Begin()
+	tx, err := s.beginTenantTx(t.TenantID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start transaction %w", err)
+		return nil, err
 	}
 	defer tx.Rollback()
 
 	query := `
     INSERT INTO hosts (tenant_id, operator_id, domain, ip, alias, rapporteurs,  created_at, updated_at)
-    values ($1, $2, $3, $4, $5, $6, $7, $8)
-    RETURNING id, tenant_id, operator_id, domain, ip, alias, rapporteurs, created_at, updated_at`
+    values ($1, $2, $3, NULLIF($4, '')::inet, $5, $6, $7, $8)
+    RETURNING ` + hostColumns
 
 	rapporteursJSONB, err := json.Marshal(t.Rapporteurs)
 	if err != nil {
@@ -97,92 +56,317 @@ func (s *PostgreSQLStore) CreateHost(t *domain.Host) (*domain.Host, error) {
 		return nil, fmt.Errorf("failed to insert credentials: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	// Retreive and assign credentials
-	newHost.Credentials, err = s.GetCredentials(newHost.ID)
+	newHost.Credentials, err = getCredentials(tx, newHost.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch credentials: %w", err)
 	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return newHost, nil
 }
 
-func (s *PostgreSQLStore) GetHostsByTenantIDAndUserID(tenantID string, userID string) ([]*domain.Host, error) {
+// CreateHosts inserts hosts in a single transaction. Hosts whose alias is
+// taken are skipped, their entry of the returned slice is nil.
+func (s *PostgreSQLStore) CreateHosts(tenantID string, hosts []*domain.Host) ([]*domain.Host, error) {
+
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
 	query := `
-    SELECT *
+    INSERT INTO hosts (tenant_id, operator_id, domain, ip, alias, rapporteurs,  created_at, updated_at)
+    values ($1, $2, $3, NULLIF($4, '')::inet, $5, $6, $7, $8)
+    ON CONFLICT (alias) DO NOTHING
+    RETURNING ` + hostColumns
+
+	created := make([]*domain.Host, len(hosts))
+	for i, t := range hosts {
+		rapporteursJSONB, err := json.Marshal(t.Rapporteurs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal rapporteurs: %w", err)
+		}
+
+		row := tx.QueryRow(query, tenantID, t.OperatorID, t.Domain, t.IP, t.Name, rapporteursJSONB, t.CreatedAt, t.UpdatedAt)
+		newHost := &domain.Host{}
+		if err := scanIntoHostRow(row, newHost); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to insert host `%s`: %w", t.Name, err)
+		}
+
+		if err := s.InsertCredentials(tx, newHost.ID, t.Credentials); err != nil {
+			return nil, fmt.Errorf("failed to insert credentials: %w", err)
+		}
+		newHost.Credentials, err = getCredentials(tx, newHost.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch credentials: %w", err)
+		}
+		created[i] = newHost
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return created, nil
+}
+
+// hostSortColumns maps each sort to its column, the id breaks ties
+var hostSortColumns = map[domain.HostSort]string{
+	domain.HostSortName:      "alias",
+	domain.HostSortCreatedAt: "created_at",
+	domain.HostSortUpdatedAt: "updated_at",
+}
+
+// GetHosts returns a page of the tenant hosts that match every condition of
+// the filter, with the total count of matching hosts
+func (s *PostgreSQLStore) GetHosts(filter domain.HostFilter) (*domain.HostPage, error) {
+	conditions := []string{"tenant_id = ?", "deleted_at IS NULL"}
+	args := []interface{}{filter.TenantID}
+	if filter.Deleted {
+		conditions[1] = "deleted_at IS NOT NULL"
+	}
+
+	if filter.OperatorID != "" {
+		conditions = append(conditions, "operator_id = ?")
+		args = append(args, filter.OperatorID)
+	}
+
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, `EXISTS (
+      SELECT 1 FROM host_tags JOIN tags ON tags.id = host_tags.tag_id
+      WHERE host_tags.host_id = hosts.id AND tags.name = ?
+    )`)
+		args = append(args, tag)
+	}
+	if filter.Domain != "" {
+		conditions = append(conditions, "domain ILIKE ?")
+		args = append(args, "%"+escapeLike(filter.Domain)+"%")
+	}
+	if filter.IP != "" {
+		// A single address is a /32 or /128 network, so it matches itself
+		conditions = append(conditions, "ip <<= ?::inet")
+		args = append(args, filter.IP)
+	}
+	if filter.RapporteurEmail != "" {
+		emailJSONB, err := json.Marshal([]map[string]string{{"email": filter.RapporteurEmail}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal rapporteur filter: %w", err)
+		}
+		conditions = append(conditions, "rapporteurs @> ?::jsonb")
+		args = append(args, emailJSONB)
+	}
+
+	sort := filter.Sort
+	if sort == "" {
+		sort = domain.HostSortName
+	}
+	column, ok := hostSortColumns[sort]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort: `%s`", sort)
+	}
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	// The total ignores the cursor, it counts every page
+	countQuery := replaceSQL(fmt.Sprintf(`SELECT COUNT(*) FROM hosts WHERE %s`, strings.Join(conditions, " AND ")), "?")
+	countArgs := append([]interface{}{}, args...)
+
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		id, err := strconv.Atoi(c.ID)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", err.Error(), domain.ErrInvalidCursor)
+		}
+		var value interface{} = c.Value
+		if sort != domain.HostSortName {
+			value, err = time.Parse(time.RFC3339Nano, c.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %w", err.Error(), domain.ErrInvalidCursor)
+			}
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (?, ?)", column, comparison))
+		args = append(args, value, id)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = domain.DefaultHostPageSize
+	}
+	if limit > domain.MaxHostPageSize {
+		limit = domain.MaxHostPageSize
+	}
+	// Fetch one extra row to know if there is a next page
+	args = append(args, limit+1)
+
+	query := replaceSQL(fmt.Sprintf(`
+    SELECT %s
     FROM hosts
-    WHERE tenant_id=$1 AND operator_id= $2
-  `
+    WHERE %s
+    ORDER BY %s %s, id %s
+    LIMIT ?
+  `, hostColumns, strings.Join(conditions, " AND "), column, direction, direction), "?")
 
-	rows, err := s.db.Query(query, tenantID, userID)
+	tx, err := s.beginTenantTx(filter.TenantID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch hosts: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	hosts := []*domain.Host{}
+	page := &domain.HostPage{Hosts: []*domain.Host{}}
+	if err := tx.QueryRow(countQuery, countArgs...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("failed to count hosts: %w", err)
+	}
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch hosts: %w", err)
+	}
 	for rows.Next() {
 		host := &domain.Host{}
 		if err := scanIntoHost(rows, host); err != nil {
+			rows.Close()
 			return nil, fmt.Errorf("failed to scan host: %w", err)
 		}
-		host.Credentials, err = s.GetCredentials(host.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to fetch credentials: %w", err)
-		}
-		hosts = append(hosts, host)
+		page.Hosts = append(page.Hosts, host)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating hosts: %w", err)
 	}
 
-	return hosts, nil
+	if len(page.Hosts) > limit {
+		page.Hosts = page.Hosts[:limit]
+		last := page.Hosts[limit-1]
+		page.NextCursor = encodeCursor(hostSortValue(last, sort), strconv.Itoa(last.ID))
+	}
+
+	if err := getHostsCredentials(tx, page.Hosts); err != nil {
+		return nil, err
+	}
+	if err := getHostTags(tx, page.Hosts); err != nil {
+		return nil, err
+	}
+
+	return page, nil
 }
 
-func (s *PostgreSQLStore) GetHostByID(ID int) (*domain.Host, error) {
+// hostSortValue is the value of the sort column of a host, as stored in a cursor
+func hostSortValue(host *domain.Host, sort domain.HostSort) string {
+	switch sort {
+	case domain.HostSortCreatedAt:
+		return host.CreatedAt.Format(time.RFC3339Nano)
+	case domain.HostSortUpdatedAt:
+		return host.UpdatedAt.Format(time.RFC3339Nano)
+	default:
+		return host.Name
+	}
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func (s *PostgreSQLStore) GetHostByID(ID int, tenantID string) (*domain.Host, error) {
 
 	query := `
-    SELECT *
+    SELECT ` + hostColumns + `
     FROM hosts
-    WHERE id=$1
+    WHERE id=$1 AND tenant_id=$2 AND deleted_at IS NULL
   `
 
-	row := s.db.QueryRow(query, ID)
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRow(query, ID, tenantID)
 	host := &domain.Host{}
-	var err error
 
 	if err = scanIntoHostRow(row, host); err != nil {
 		return nil, fmt.Errorf("failed to fetch host: %w", err)
 	}
 
-	credentials, err := s.GetCredentials(ID)
+	credentials, err := getCredentials(tx, ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch credentials: %w", err)
 	}
 	host.Credentials = credentials
 
+	if err := getHostTags(tx, []*domain.Host{host}); err != nil {
+		return nil, err
+	}
+
+	return host, nil
+}
+
+// TransferHostByID makes operatorID the owner of the host
+func (s *PostgreSQLStore) TransferHostByID(ID int, tenantID string, operatorID string) (*domain.Host, error) {
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+    UPDATE hosts
+    SET operator_id=$3, updated_at=CURRENT_TIMESTAMP
+        WHERE id=$1 AND tenant_id=$2 AND deleted_at IS NULL
+    RETURNING ` + hostColumns
+
+	row := tx.QueryRow(query, ID, tenantID, operatorID)
+	host := &domain.Host{}
+	if err := scanIntoHostRow(row, host); err != nil {
+		return nil, fmt.Errorf("failed to transfer host: %w", err)
+	}
+
+	hosts := []*domain.Host{host}
+	if err := getHostsCredentials(tx, hosts); err != nil {
+		return nil, err
+	}
+	if err := getHostTags(tx, hosts); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return host, nil
 }
 
 func (s *PostgreSQLStore) PatchHostByID(h *domain.Host) (*domain.Host, error) {
-	tx, err := s.db.Begin()
+	tx, err := s.beginTenantTx(h.TenantID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, err
 	}
 	defer tx.Rollback()
 
 	query := `
     UPDATE hosts
-    SET  rapporteurs=$2, domain=$3, ip=$4, alias=$5
-        WHERE id=$1
-    RETURNING *
-  `
+    SET  rapporteurs=$2, domain=$3, ip=NULLIF($4, '')::inet, alias=$5, updated_at=CURRENT_TIMESTAMP
+        WHERE id=$1 AND tenant_id=$6 AND deleted_at IS NULL
+    RETURNING ` + hostColumns
 	rapporteursJSONB, err := json.Marshal(h.Rapporteurs)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal rapporteurs: %w", err)
 	}
 
-	row := tx.QueryRow(query, h.ID, rapporteursJSONB, h.Domain, h.IP, h.Name)
+	row := tx.QueryRow(query, h.ID, rapporteursJSONB, h.Domain, h.IP, h.Name, h.TenantID)
 	host := &domain.Host{}
 	if err := scanIntoHostRow(row, host); err != nil {
 		return nil, fmt.Errorf("error fetching host: %w", err)
@@ -192,25 +376,33 @@ func (s *PostgreSQLStore) PatchHostByID(h *domain.Host) (*domain.Host, error) {
 		return nil, fmt.Errorf("failed to update credentials: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	// Fetch and assign updated credentials
-	credentials, err := s.GetCredentials(host.ID)
+	credentials, err := getCredentials(tx, host.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch updated credentials: %w", err)
 	}
 	host.Credentials = credentials
 
+	if err := getHostTags(tx, []*domain.Host{host}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return host, nil
 }
 
+// InsertCredentials stores credentials whose passwords were sealed by the keyring
 func (s *PostgreSQLStore) InsertCredentials(tx *sql.Tx, hostID int, credentials []domain.Credential) error {
 
-	query := "INSERT INTO credentials (host_id, username, password) VALUES ($1, $2, pgp_sym_encrypt($3, 'MAMA', 'compress-algo=1, cipher-algo=aes256'))"
+	query := "INSERT INTO credentials (host_id, username, password, key_id) VALUES ($1, $2, $3, $4)"
 	for _, cred := range credentials {
-		if _, err := tx.Exec(query, hostID, cred.Username, cred.Password); err != nil {
+		if cred.KeyID == "" {
+			return fmt.Errorf("credential `%s` is not encrypted", cred.Username)
+		}
+		if _, err := tx.Exec(query, hostID, cred.Username, cred.Password, cred.KeyID); err != nil {
 			return fmt.Errorf("failed to insert credential: %w", err)
 		}
 	}
@@ -218,15 +410,15 @@ func (s *PostgreSQLStore) InsertCredentials(tx *sql.Tx, hostID int, credentials
 	return nil
 }
 
-func (s *PostgreSQLStore) GetCredentials(hostID int) ([]domain.Credential, error) {
+func getCredentials(tx *sql.Tx, hostID int) ([]domain.Credential, error) {
 
 	query := `
-    SELECT id, host_id, username,password
+    SELECT id, host_id, username
     FROM credentials
     WHERE host_id=$1
   `
 
-	rows, err := s.db.Query(query, hostID)
+	rows, err := tx.Query(query, hostID)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching Credentials: %w", err)
 	}
@@ -243,18 +435,89 @@ func (s *PostgreSQLStore) GetCredentials(hostID int) ([]domain.Credential, error
 	return credentials, nil
 }
 
+// getHostsCredentials loads the credentials of every host with a single query
+func getHostsCredentials(tx *sql.Tx, hosts []*domain.Host) error {
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	byID := map[int]*domain.Host{}
+	ids := make([]int64, 0, len(hosts))
+	for _, host := range hosts {
+		host.Credentials = []domain.Credential{}
+		byID[host.ID] = host
+		ids = append(ids, int64(host.ID))
+	}
+
+	query := `
+    SELECT id, host_id, username
+    FROM credentials
+    WHERE host_id = ANY($1)
+    ORDER BY id
+  `
+	rows, err := tx.Query(query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("error fetching Credentials: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		credential, err := scanIntoCredential(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan credential: %w", err)
+		}
+		hostID, err := strconv.Atoi(credential.HostID)
+		if err != nil {
+			return fmt.Errorf("failed to parse credential host: %w", err)
+		}
+		byID[hostID].Credentials = append(byID[hostID].Credentials, *credential)
+	}
+
+	return rows.Err()
+}
+
+// UpdateCredentials replaces the credentials of the host. Credentials with an ID
+// and no password keep the password they already had.
 func (s *PostgreSQLStore) UpdateCredentials(tx *sql.Tx, hostID int, credentials []domain.Credential) error {
-	// Step 1: Delete all credentials associated with the hostID
+	// Step 1: Load the sealed passwords of the credentials to keep
+	existing := map[int]domain.Credential{}
+	rows, err := tx.Query(`SELECT id, password, key_id FROM credentials WHERE host_id = $1`, hostID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch existing credentials for hostID %d: %w", hostID, err)
+	}
+	for rows.Next() {
+		var (
+			cred  domain.Credential
+			keyID sql.NullString
+		)
+		if err := rows.Scan(&cred.ID, &cred.Password, &keyID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan credential: %w", err)
+		}
+		cred.KeyID = keyID.String
+		existing[cred.ID] = cred
+	}
+	rows.Close()
+
+	// Step 2: Delete all credentials associated with the hostID
 	deleteQuery := `DELETE FROM credentials WHERE host_id = $1`
 	if _, err := tx.Exec(deleteQuery, hostID); err != nil {
 		return fmt.Errorf("failed to delete existing credentials for hostID %d: %w", hostID, err)
 	}
 
-	insertQuery := `INSERT INTO credentials (host_id, username, password)
-                  VALUES ($1, $2, pgp_sym_encrypt($3, 'MAMA', 'compress-algo=1, cipher-algo=aes256'))`
+	insertQuery := `INSERT INTO credentials (host_id, username, password, key_id)
+                  VALUES ($1, $2, $3, $4)`
 
 	for _, cred := range credentials {
-		_, err := tx.Exec(insertQuery, hostID, cred.Username, cred.Password)
+		if cred.Password == "" {
+			old, ok := existing[cred.ID]
+			if !ok {
+				msg := fmt.Sprintf("credential `%s`", cred.Username)
+				return fmt.Errorf("%q: %w", msg, domain.ErrMissingPassword)
+			}
+			cred.Password, cred.KeyID = old.Password, old.KeyID
+		}
+		_, err := tx.Exec(insertQuery, hostID, cred.Username, cred.Password, nullableString(cred.KeyID))
 		if err != nil {
 			return fmt.Errorf("failed to insert new credential for hostID %d: %w", hostID, err)
 		}
@@ -262,27 +525,184 @@ func (s *PostgreSQLStore) UpdateCredentials(tx *sql.Tx, hostID int, credentials
 	return nil
 }
 
-func (s *PostgreSQLStore) DeleteHostByID(ID int) (bool, error) {
+// RotateCredentials calls reseal on every stored credential inside a single
+// transaction, and saves the ones whose key changed. Credentials without a
+// key ID are pgcrypto ciphertexts, they're decrypted with legacyPassphrase
+// and handed to reseal as plaintext.
+func (s *PostgreSQLStore) RotateCredentials(legacyPassphrase string, reseal func(*domain.Credential) error) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
 	query := `
-    DELETE 
-    FROM hosts
-    WHERE id=$1
+    SELECT id, host_id, username,
+      CASE WHEN key_id IS NULL THEN pgp_sym_decrypt(password::bytea, $1) ELSE password END,
+      COALESCE(key_id, '')
+    FROM credentials
+    ORDER BY id
+    FOR UPDATE
   `
-	res, err := s.db.Exec(query, ID)
+	rows, err := tx.Query(query, legacyPassphrase)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch credentials: %w", err)
+	}
 
-	switch err {
-	case nil:
-		count, _ := res.RowsAffected()
-		return count == 1, nil
-	default:
+	credentials := []domain.Credential{}
+	for rows.Next() {
+		var cred domain.Credential
+		if err := rows.Scan(&cred.ID, &cred.HostID, &cred.Username, &cred.Password, &cred.KeyID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan credential: %w", err)
+		}
+		credentials = append(credentials, cred)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to fetch credentials: %w", err)
+	}
+
+	updateQuery := `UPDATE credentials SET password=$2, key_id=$3 WHERE id=$1`
+	rotated := 0
+	for _, cred := range credentials {
+		keyID := cred.KeyID
+		if err := reseal(&cred); err != nil {
+			return 0, fmt.Errorf("failed to reseal credential %d: %w", cred.ID, err)
+		}
+		if cred.KeyID == keyID {
+			continue
+		}
+		if _, err := tx.Exec(updateQuery, cred.ID, cred.Password, cred.KeyID); err != nil {
+			return 0, fmt.Errorf("failed to update credential %d: %w", cred.ID, err)
+		}
+		rotated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return rotated, nil
+}
+
+// DeleteHostByID soft deletes the host, it's hidden until it's restored or
+// purged. Its alias stays taken so a restore never conflicts.
+func (s *PostgreSQLStore) DeleteHostByID(ID int, tenantID string) (bool, error) {
+
+	query := `
+    UPDATE hosts
+    SET deleted_at=CURRENT_TIMESTAMP
+    WHERE id=$1 AND tenant_id=$2 AND deleted_at IS NULL
+  `
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
 		return false, err
 	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, ID, tenantID)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	count, _ := res.RowsAffected()
+	return count == 1, nil
+}
+
+// RestoreHostByID undoes the soft delete of a host
+func (s *PostgreSQLStore) RestoreHostByID(ID int, tenantID string) (*domain.Host, error) {
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+    UPDATE hosts
+    SET deleted_at=NULL, updated_at=CURRENT_TIMESTAMP
+        WHERE id=$1 AND tenant_id=$2 AND deleted_at IS NOT NULL
+    RETURNING ` + hostColumns
+
+	row := tx.QueryRow(query, ID, tenantID)
+	host := &domain.Host{}
+	if err := scanIntoHostRow(row, host); err != nil {
+		return nil, fmt.Errorf("failed to restore host: %w", err)
+	}
+
+	hosts := []*domain.Host{host}
+	if err := getHostsCredentials(tx, hosts); err != nil {
+		return nil, err
+	}
+	if err := getHostTags(tx, hosts); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return host, nil
+}
+
+// PurgeDeletedHosts hard deletes the hosts of every tenant soft deleted before
+// deletedBefore, with their credentials, tags and group memberships
+func (s *PostgreSQLStore) PurgeDeletedHosts(deletedBefore time.Time) (int64, error) {
+	query := `
+    DELETE
+    FROM hosts
+    WHERE deleted_at IS NOT NULL AND deleted_at < $1
+  `
+
+	res, err := s.db.Exec(query, deletedBefore.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge hosts: %w", err)
+	}
+
+	count, _ := res.RowsAffected()
+	return count, nil
+}
+
+// GetHostsByAliases returns the tenant hosts with the given aliases, without credentials.
+// Deleted hosts are included, reports and notifications of past scans still need them.
+func (s *PostgreSQLStore) GetHostsByAliases(tenantID string, aliases []string) ([]*domain.Host, error) {
+	query := `
+    SELECT ` + hostColumns + `
+    FROM hosts
+    WHERE tenant_id=$1 AND alias = ANY($2)
+  `
+
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, tenantID, pq.Array(aliases))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch hosts: %w", err)
+	}
+	defer rows.Close()
+
+	hosts := []*domain.Host{}
+	for rows.Next() {
+		host := &domain.Host{}
+		if err := scanIntoHost(rows, host); err != nil {
+			return nil, fmt.Errorf("failed to scan host: %w", err)
+		}
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
 }
 
 func scanIntoHost(rows *sql.Rows, host *domain.Host) error {
 	var rapporteurs []byte
-	if err := rows.Scan(&host.ID, &host.TenantID, &host.OperatorID, &host.Domain, &host.IP, &host.Name, &rapporteurs, &host.CreatedAt, &host.UpdatedAt); err != nil {
+	host.Tags = []string{}
+	if err := rows.Scan(&host.ID, &host.TenantID, &host.OperatorID, &host.Domain, &host.IP, &host.Name, &rapporteurs, &host.CreatedAt, &host.UpdatedAt, &host.DeletedAt); err != nil {
 		return fmt.Errorf("error scanning rows: %w", err)
 	}
 	// Unmarshal the rapporteurs bytes
@@ -295,7 +715,8 @@ func scanIntoHost(rows *sql.Rows, host *domain.Host) error {
 
 func scanIntoHostRow(row *sql.Row, host *domain.Host) error {
 	var rapporteurs []byte
-	if err := row.Scan(&host.ID, &host.TenantID, &host.OperatorID, &host.Domain, &host.IP, &host.Name, &rapporteurs, &host.CreatedAt, &host.UpdatedAt); err != nil {
+	host.Tags = []string{}
+	if err := row.Scan(&host.ID, &host.TenantID, &host.OperatorID, &host.Domain, &host.IP, &host.Name, &rapporteurs, &host.CreatedAt, &host.UpdatedAt, &host.DeletedAt); err != nil {
 		return fmt.Errorf("failed to scan host: %w", err)
 	}
 	if err := json.Unmarshal(rapporteurs, &host.Rapporteurs); err != nil {
@@ -311,7 +732,6 @@ func scanIntoCredential(rows *sql.Rows) (*domain.Credential, error) {
 		&credential.ID,
 		&credential.HostID,
 		&credential.Username,
-		&credential.Password,
 	)
 
 	if err != nil {
@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationsLockID keys the advisory lock that serializes migration runs
+const migrationsLockID = 7310431
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a versioned schema change, Up applies it and Down reverts it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a known migration and whether it has been applied
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+}
+
+// LoadMigrations reads the NNNN_name.up.sql and NNNN_name.down.sql pairs of fsys,
+// sorted by version. Every version needs both files.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name: `%s`", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version `%s`: %w", match[1], err)
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration `%s`: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: `%s` and `%s`", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Migrations returns the migrations embedded in the binary
+func Migrations() ([]Migration, error) {
+	sub, err := fs.Sub(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return LoadMigrations(sub)
+}
+
+func (s *PostgreSQLStore) createMigrationsTable() error {
+	query := `create table if not exists schema_migrations (
+      version BIGINT PRIMARY KEY,
+      name VARCHAR(255) NOT NULL,
+      applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+  )`
+
+	if _, err := s.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgreSQLStore) appliedMigrations() (map[int64]time.Time, error) {
+	if err := s.createMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// MigrationStatus lists every embedded migration, oldest first
+func (s *PostgreSQLStore) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	status := []MigrationStatus{}
+	for _, m := range migrations {
+		ms := MigrationStatus{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := applied[m.Version]; ok {
+			ms.AppliedAt = &appliedAt
+		}
+		status = append(status, ms)
+	}
+
+	return status, nil
+}
+
+// PendingMigrations returns the embedded migrations that haven't been applied yet
+func (s *PostgreSQLStore) PendingMigrations() ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []Migration{}
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+
+	return pending, nil
+}
+
+// MigrateUp applies up to steps pending migrations in order, all of them when
+// steps is 0. It returns the applied migrations.
+func (s *PostgreSQLStore) MigrateUp(steps int) ([]Migration, error) {
+	pending, err := s.PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if steps > 0 && steps < len(pending) {
+		pending = pending[:steps]
+	}
+
+	applied := []Migration{}
+	for _, m := range pending {
+		if err := s.runMigration(m, true); err != nil {
+			return applied, fmt.Errorf("failed to apply migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, m)
+	}
+
+	return applied, nil
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first.
+// It returns the reverted migrations.
+func (s *PostgreSQLStore) MigrateDown(steps int) ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	reverted := []Migration{}
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if err := s.runMigration(m, false); err != nil {
+			return reverted, fmt.Errorf("failed to revert migration %d_%s: %w", m.Version, m.Name, err)
+		}
+		reverted = append(reverted, m)
+	}
+
+	return reverted, nil
+}
+
+// runMigration applies or reverts m and records it in schema_migrations in a single
+// transaction, the advisory lock keeps concurrent runs from applying it twice
+func (s *PostgreSQLStore) runMigration(m Migration, up bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationsLockID); err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+
+	// Another run may have applied or reverted it while we waited for the lock
+	var applied bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version=$1)`, m.Version).Scan(&applied); err != nil {
+		return fmt.Errorf("failed to check migration: %w", err)
+	}
+	if applied == up {
+		return tx.Commit()
+	}
+
+	if up {
+		if _, err := tx.Exec(m.Up); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO schema_migrations (version, name) values ($1, $2)`, m.Version, m.Name)
+	} else {
+		if _, err := tx.Exec(m.Down); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM schema_migrations WHERE version=$1`, m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// CheckMigrations fails when the database schema is behind the embedded migrations
+func (s *PostgreSQLStore) CheckMigrations() error {
+	pending, err := s.PendingMigrations()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is behind, %d pending migrations starting at %d_%s: run `migrate up`",
+			len(pending), pending[0].Version, pending[0].Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func Test_LoadMigrations(t *testing.T) {
+	tests := []struct {
+		name          string
+		files         fstest.MapFS
+		expected      []int64
+		expectedError bool
+	}{
+		{
+			name: "Sorted by version",
+			files: fstest.MapFS{
+				"0010_add_tags.up.sql":    {Data: []byte("ALTER TABLE hosts ADD COLUMN tags JSONB;")},
+				"0010_add_tags.down.sql":  {Data: []byte("ALTER TABLE hosts DROP COLUMN tags;")},
+				"0002_add_hosts.up.sql":   {Data: []byte("CREATE TABLE hosts ();")},
+				"0002_add_hosts.down.sql": {Data: []byte("DROP TABLE hosts;")},
+				"0001_initial.up.sql":     {Data: []byte("SELECT 1;")},
+				"0001_initial.down.sql":   {Data: []byte("SELECT 1;")},
+			},
+			expected: []int64{1, 2, 10},
+		},
+		{
+			name: "Missing down file",
+			files: fstest.MapFS{
+				"0001_initial.up.sql": {Data: []byte("SELECT 1;")},
+			},
+			expectedError: true,
+		},
+		{
+			name: "Mismatched names",
+			files: fstest.MapFS{
+				"0001_initial.up.sql":    {Data: []byte("SELECT 1;")},
+				"0001_initials.down.sql": {Data: []byte("SELECT 1;")},
+			},
+			expectedError: true,
+		},
+		{
+			name: "Invalid file name",
+			files: fstest.MapFS{
+				"initial.sql": {Data: []byte("SELECT 1;")},
+			},
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := LoadMigrations(tt.files)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Expected error `%v`, got `%v`", tt.expectedError, err)
+			}
+			if len(migrations) != len(tt.expected) {
+				t.Fatalf("Expected `%d` migrations, got `%d`", len(tt.expected), len(migrations))
+			}
+			for i, m := range migrations {
+				if m.Version != tt.expected[i] {
+					t.Errorf("Expected version `%d`, got `%d`", tt.expected[i], m.Version)
+				}
+			}
+		})
+	}
+}
+
+func Test_EmbeddedMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("Expected version `%d`, got `%d` (%s)", i+1, m.Version, m.Name)
+		}
+	}
+}
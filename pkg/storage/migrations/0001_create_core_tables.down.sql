@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS scans;
+DROP TABLE IF EXISTS tenants;
+DROP TABLE IF EXISTS credentials;
+DROP TABLE IF EXISTS hosts;
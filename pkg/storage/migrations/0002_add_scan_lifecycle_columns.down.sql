@@ -0,0 +1,6 @@
+ALTER TABLE scans
+    DROP COLUMN IF EXISTS scan_status,
+    DROP COLUMN IF EXISTS tools,
+    DROP COLUMN IF EXISTS targets,
+    DROP COLUMN IF EXISTS operator_id,
+    DROP COLUMN IF EXISTS tenant_id;
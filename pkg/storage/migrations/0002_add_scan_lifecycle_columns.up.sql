@@ -0,0 +1,6 @@
+ALTER TABLE scans
+    ADD COLUMN IF NOT EXISTS tenant_id UUID,
+    ADD COLUMN IF NOT EXISTS operator_id UUID,
+    ADD COLUMN IF NOT EXISTS targets JSONB,
+    ADD COLUMN IF NOT EXISTS tools JSONB,
+    ADD COLUMN IF NOT EXISTS scan_status VARCHAR(32) NOT NULL DEFAULT 'pending';
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS scan_schedules (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL,
+    operator_id UUID,
+    name VARCHAR(255) NOT NULL,
+    host_ids INTEGER[] NOT NULL,
+    cron_expression VARCHAR(255),
+    interval_minutes INTEGER,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    last_scan_id UUID,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL,
+    scan_id UUID NOT NULL,
+    host_alias VARCHAR(255) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject TEXT NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    error TEXT,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (scan_id, host_alias, recipient)
+);
+
+CREATE TABLE IF NOT EXISTS notification_templates (
+    tenant_id UUID PRIMARY KEY,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- Credentials without a key ID are pgcrypto ciphertexts from before the keyring,
+-- `credentials rotate` re-encrypts them
+ALTER TABLE credentials ADD COLUMN IF NOT EXISTS key_id VARCHAR(64);
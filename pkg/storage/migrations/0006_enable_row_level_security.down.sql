@@ -0,0 +1,11 @@
+DROP POLICY IF EXISTS tenant_isolation ON credentials;
+ALTER TABLE credentials DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON scans;
+ALTER TABLE scans DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON hosts;
+ALTER TABLE hosts DISABLE ROW LEVEL SECURITY;
+
+REVOKE ALL PRIVILEGES ON ALL SEQUENCES IN SCHEMA public FROM core_admin, core_tenant;
+REVOKE ALL PRIVILEGES ON ALL TABLES IN SCHEMA public FROM core_admin, core_tenant;
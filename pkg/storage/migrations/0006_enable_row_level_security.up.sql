@@ -0,0 +1,36 @@
+-- core_tenant is the role tenant scoped transactions run as,
+-- core_admin bypasses row-level security for maintenance tools
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'core_tenant') THEN
+        CREATE ROLE core_tenant NOLOGIN NOBYPASSRLS;
+    END IF;
+    IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'core_admin') THEN
+        CREATE ROLE core_admin NOLOGIN BYPASSRLS;
+    END IF;
+END
+$$;
+
+GRANT core_tenant TO CURRENT_USER;
+GRANT core_admin TO CURRENT_USER;
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON hosts, credentials, scans TO core_tenant;
+GRANT USAGE, SELECT ON SEQUENCE hosts_id_seq, credentials_id_seq TO core_tenant;
+GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA public TO core_admin;
+GRANT ALL PRIVILEGES ON ALL SEQUENCES IN SCHEMA public TO core_admin;
+
+-- The table owner, which background jobs run as, isn't subject to the policies
+ALTER TABLE hosts ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON hosts;
+CREATE POLICY tenant_isolation ON hosts
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+ALTER TABLE scans ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON scans;
+CREATE POLICY tenant_isolation ON scans
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+ALTER TABLE credentials ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON credentials;
+CREATE POLICY tenant_isolation ON credentials
+    USING (EXISTS (SELECT 1 FROM hosts WHERE hosts.id = credentials.host_id));
@@ -0,0 +1,3 @@
+-- IPv6 addresses don't fit the old column, they're dropped
+ALTER TABLE hosts
+    ALTER COLUMN ip TYPE VARCHAR(15) USING CASE WHEN family(ip) = 4 THEN host(ip) ELSE '' END;
@@ -0,0 +1,3 @@
+-- VARCHAR(15) only fits IPv4 addresses, empty strings become NULL
+ALTER TABLE hosts
+    ALTER COLUMN ip TYPE INET USING NULLIF(ip, '')::inet;
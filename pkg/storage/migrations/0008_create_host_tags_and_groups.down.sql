@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS host_group_members;
+DROP TABLE IF EXISTS host_groups;
+DROP TABLE IF EXISTS host_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,58 @@
+CREATE TABLE IF NOT EXISTS tags (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS host_tags (
+    host_id INTEGER NOT NULL REFERENCES hosts (id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (host_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS host_tags_tag_id_idx ON host_tags (tag_id);
+
+CREATE TABLE IF NOT EXISTS host_groups (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS host_group_members (
+    group_id UUID NOT NULL REFERENCES host_groups (id) ON DELETE CASCADE,
+    host_id INTEGER NOT NULL REFERENCES hosts (id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, host_id)
+);
+CREATE INDEX IF NOT EXISTS host_group_members_host_id_idx ON host_group_members (host_id);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON tags, host_tags, host_groups, host_group_members TO core_tenant;
+GRANT ALL PRIVILEGES ON tags, host_tags, host_groups, host_group_members TO core_admin;
+
+ALTER TABLE tags ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON tags;
+CREATE POLICY tenant_isolation ON tags
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+ALTER TABLE host_groups ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON host_groups;
+CREATE POLICY tenant_isolation ON host_groups
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
+
+-- Both ends of a link must be visible, so hosts can't be linked to the tags
+-- or groups of another tenant
+ALTER TABLE host_tags ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON host_tags;
+CREATE POLICY tenant_isolation ON host_tags
+    USING (EXISTS (SELECT 1 FROM hosts WHERE hosts.id = host_tags.host_id)
+        AND EXISTS (SELECT 1 FROM tags WHERE tags.id = host_tags.tag_id));
+
+ALTER TABLE host_group_members ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON host_group_members;
+CREATE POLICY tenant_isolation ON host_group_members
+    USING (EXISTS (SELECT 1 FROM hosts WHERE hosts.id = host_group_members.host_id)
+        AND EXISTS (SELECT 1 FROM host_groups WHERE host_groups.id = host_group_members.group_id));
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS credentials_host_id_idx;
+DROP INDEX IF EXISTS hosts_tenant_operator_updated_at_idx;
+DROP INDEX IF EXISTS hosts_tenant_operator_created_at_idx;
+DROP INDEX IF EXISTS hosts_tenant_operator_alias_idx;
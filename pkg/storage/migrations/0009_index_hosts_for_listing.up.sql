@@ -0,0 +1,7 @@
+-- Keyset pagination of hosts sorts by one of these columns with the id as tiebreaker
+CREATE INDEX IF NOT EXISTS hosts_tenant_operator_alias_idx ON hosts (tenant_id, operator_id, alias, id);
+CREATE INDEX IF NOT EXISTS hosts_tenant_operator_created_at_idx ON hosts (tenant_id, operator_id, created_at, id);
+CREATE INDEX IF NOT EXISTS hosts_tenant_operator_updated_at_idx ON hosts (tenant_id, operator_id, updated_at, id);
+
+-- Credentials of a page of hosts are loaded with a single query
+CREATE INDEX IF NOT EXISTS credentials_host_id_idx ON credentials (host_id);
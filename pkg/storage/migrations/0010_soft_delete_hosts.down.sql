@@ -0,0 +1,5 @@
+-- Soft deleted hosts are dropped, they'd show up as live hosts otherwise
+DELETE FROM hosts WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS hosts_deleted_at_idx;
+ALTER TABLE hosts DROP COLUMN IF EXISTS deleted_at;
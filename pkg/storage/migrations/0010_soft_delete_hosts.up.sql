@@ -0,0 +1,4 @@
+-- Deleted hosts keep their row until the purge job removes them
+ALTER TABLE hosts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS hosts_deleted_at_idx ON hosts (deleted_at) WHERE deleted_at IS NOT NULL;
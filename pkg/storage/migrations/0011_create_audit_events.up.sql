@@ -0,0 +1,22 @@
+-- Audit events are append only, tenants can read and write them but never change them
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL,
+    user_id VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL,
+    resource_type VARCHAR(64) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL DEFAULT '',
+    changes JSONB NOT NULL DEFAULT '{}',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS audit_events_tenant_created_at_idx ON audit_events (tenant_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS audit_events_resource_idx ON audit_events (tenant_id, resource_type, resource_id);
+
+GRANT SELECT, INSERT ON audit_events TO core_tenant;
+GRANT ALL PRIVILEGES ON audit_events TO core_admin;
+
+ALTER TABLE audit_events ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON audit_events;
+CREATE POLICY tenant_isolation ON audit_events
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
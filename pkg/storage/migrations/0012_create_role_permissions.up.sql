@@ -0,0 +1,17 @@
+-- Per tenant overrides of the default permissions of a role, a row replaces
+-- every default permission of its role
+CREATE TABLE IF NOT EXISTS role_permissions (
+    tenant_id UUID NOT NULL,
+    role VARCHAR(64) NOT NULL,
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (tenant_id, role)
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON role_permissions TO core_tenant;
+GRANT ALL PRIVILEGES ON role_permissions TO core_admin;
+
+ALTER TABLE role_permissions ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON role_permissions;
+CREATE POLICY tenant_isolation ON role_permissions
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
@@ -0,0 +1,23 @@
+-- API keys of automation clients, only the hash of each key is stored.
+-- Requests look keys up by hash before knowing their tenant.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    tenant_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(32) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS api_keys_tenant_created_at_idx ON api_keys (tenant_id, created_at);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON api_keys TO core_tenant;
+GRANT ALL PRIVILEGES ON api_keys TO core_admin;
+
+ALTER TABLE api_keys ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON api_keys;
+CREATE POLICY tenant_isolation ON api_keys
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::uuid);
@@ -0,0 +1,2 @@
+ALTER TABLE scan_schedules
+    DROP COLUMN IF EXISTS tools;
@@ -0,0 +1,3 @@
+-- Tools and their options that the scans of a schedule run, NULL runs every tool
+ALTER TABLE scan_schedules
+    ADD COLUMN IF NOT EXISTS tools JSONB;
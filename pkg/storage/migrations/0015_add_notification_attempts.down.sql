@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS notifications_failed_idx;
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS attempts;
@@ -0,0 +1,4 @@
+-- Failed notifications are claimed again until they reach the attempt limit
+ALTER TABLE notifications
+    ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS notifications_failed_idx ON notifications (attempts) WHERE status = 'failed';
@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+func (s *PostgreSQLStore) ClearNotificationsTables() error {
+	query := `TRUNCATE TABLE notifications, notification_templates RESTART IDENTITY CASCADE`
+
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const notificationColumns = `id, tenant_id, scan_id, host_alias, recipient, subject, status, error, attempts, sent_at, created_at`
+
+// ClaimNotification records a pending notification. A failed notification is
+// claimed again while it has been attempted less than maxAttempts times, n then
+// takes its ID and attempts. It returns false when the rapporteur was already
+// notified about the host for this scan, or the notification is being sent or
+// ran out of attempts.
+func (s *PostgreSQLStore) ClaimNotification(n *domain.Notification, maxAttempts int) (bool, error) {
+	query := `
+    INSERT INTO notifications (id, tenant_id, scan_id, host_alias, recipient, subject, status, attempts, created_at)
+    values ($1, $2, $3, $4, $5, $6, $7, 1, $8)
+    ON CONFLICT (scan_id, host_alias, recipient) DO UPDATE
+    SET subject=EXCLUDED.subject, status=EXCLUDED.status, error=NULL, attempts=notifications.attempts + 1
+    WHERE notifications.status=$9 AND notifications.attempts < $10
+    RETURNING id, attempts
+  `
+
+	err := s.db.QueryRow(query, n.ID, n.TenantID, n.ScanID, n.HostAlias, n.Recipient, n.Subject, n.Status, n.CreatedAt,
+		domain.NotificationStatusFailed, maxAttempts).Scan(&n.ID, &n.Attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim notification: %w", err)
+	}
+
+	return true, nil
+}
+
+func (s *PostgreSQLStore) UpdateNotificationStatus(n *domain.Notification) error {
+	query := `
+    UPDATE notifications
+    SET status=$2, error=$3, sent_at=$4
+    WHERE id=$1
+  `
+
+	if _, err := s.db.Exec(query, n.ID, n.Status, nullableString(n.Error), n.SentAt); err != nil {
+		return fmt.Errorf("failed to update notification: %w", err)
+	}
+
+	return nil
+}
+
+// GetRetryableNotifications returns the failed notifications of every tenant
+// that were attempted less than maxAttempts times
+func (s *PostgreSQLStore) GetRetryableNotifications(maxAttempts int) ([]*domain.Notification, error) {
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM notifications
+    WHERE status=$1 AND attempts < $2
+    ORDER BY created_at
+  `, notificationColumns)
+
+	rows, err := s.db.Query(query, domain.NotificationStatusFailed, maxAttempts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch retryable notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIntoNotifications(rows)
+}
+
+func (s *PostgreSQLStore) GetNotificationsByScanID(scanID string, tenantID string) ([]*domain.Notification, error) {
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM notifications
+    WHERE scan_id=$1 AND tenant_id=$2
+    ORDER BY created_at, host_alias, recipient
+  `, notificationColumns)
+
+	rows, err := s.db.Query(query, scanID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch notifications: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIntoNotifications(rows)
+}
+
+func scanIntoNotifications(rows *sql.Rows) ([]*domain.Notification, error) {
+	notifications := []*domain.Notification{}
+	for rows.Next() {
+		n := new(domain.Notification)
+		var (
+			errMsg sql.NullString
+			sentAt sql.NullTime
+		)
+		if err := rows.Scan(&n.ID, &n.TenantID, &n.ScanID, &n.HostAlias, &n.Recipient, &n.Subject,
+			&n.Status, &errMsg, &n.Attempts, &sentAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		n.Error = errMsg.String
+		if sentAt.Valid {
+			n.SentAt = &sentAt.Time
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+// GetNotificationTemplate returns sql.ErrNoRows when the tenant has no template of its own
+func (s *PostgreSQLStore) GetNotificationTemplate(tenantID string) (*domain.NotificationTemplate, error) {
+	query := `
+    SELECT tenant_id, subject, body, updated_at
+    FROM notification_templates
+    WHERE tenant_id=$1
+  `
+
+	t := new(domain.NotificationTemplate)
+	if err := s.db.QueryRow(query, tenantID).Scan(&t.TenantID, &t.Subject, &t.Body, &t.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to fetch notification template: %w", err)
+	}
+
+	return t, nil
+}
+
+func (s *PostgreSQLStore) UpsertNotificationTemplate(t *domain.NotificationTemplate) (*domain.NotificationTemplate, error) {
+	query := `
+    INSERT INTO notification_templates (tenant_id, subject, body, updated_at)
+    values ($1, $2, $3, $4)
+    ON CONFLICT (tenant_id) DO UPDATE SET subject=EXCLUDED.subject, body=EXCLUDED.body, updated_at=EXCLUDED.updated_at
+    RETURNING tenant_id, subject, body, updated_at
+  `
+
+	saved := new(domain.NotificationTemplate)
+	err := s.db.QueryRow(query, t.TenantID, t.Subject, t.Body, time.Now().UTC()).
+		Scan(&saved.TenantID, &saved.Subject, &saved.Body, &saved.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save notification template: %w", err)
+	}
+
+	return saved, nil
+}
+
+func (s *PostgreSQLStore) DeleteNotificationTemplate(tenantID string) (bool, error) {
+	query := `
+    DELETE
+    FROM notification_templates
+    WHERE tenant_id=$1
+  `
+	res, err := s.db.Exec(query, tenantID)
+	if err != nil {
+		return false, err
+	}
+
+	count, _ := res.RowsAffected()
+	return count == 1, nil
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/lib/pq"
+)
+
+func (s *PostgreSQLStore) ClearRolePermissionsTable() error {
+	query := `TRUNCATE TABLE role_permissions`
+
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetRolePermissions returns the roles of the tenant that override their default permissions
+func (s *PostgreSQLStore) GetRolePermissions(tenantID string) ([]*domain.RolePermissions, error) {
+	query := `
+    SELECT tenant_id, role, permissions, updated_at
+    FROM role_permissions
+    WHERE tenant_id=$1
+    ORDER BY role
+  `
+
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching role permissions: %w", err)
+	}
+	defer rows.Close()
+
+	bindings := []*domain.RolePermissions{}
+	for rows.Next() {
+		b, err := scanIntoRolePermissions(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan role permissions: %w", err)
+		}
+		bindings = append(bindings, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating role permissions: %w", err)
+	}
+
+	return bindings, nil
+}
+
+func (s *PostgreSQLStore) UpsertRolePermissions(b *domain.RolePermissions) (*domain.RolePermissions, error) {
+	query := `
+    INSERT INTO role_permissions (tenant_id, role, permissions, updated_at)
+    values ($1, $2, $3, $4)
+    ON CONFLICT (tenant_id, role) DO UPDATE SET permissions=EXCLUDED.permissions, updated_at=EXCLUDED.updated_at
+    RETURNING tenant_id, role, permissions, updated_at
+  `
+
+	permissions := make([]string, len(b.Permissions))
+	for i, p := range b.Permissions {
+		permissions[i] = p.String()
+	}
+
+	tx, err := s.beginTenantTx(b.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	saved, err := scanIntoRolePermissions(tx.QueryRow(query, b.TenantID, b.Role, pq.Array(permissions), time.Now().UTC()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save role permissions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return saved, nil
+}
+
+func (s *PostgreSQLStore) DeleteRolePermissions(tenantID string, role domain.Role) (bool, error) {
+	query := `
+    DELETE
+    FROM role_permissions
+    WHERE tenant_id=$1 AND role=$2
+  `
+
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, tenantID, role)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	count, _ := res.RowsAffected()
+	return count == 1, nil
+}
+
+func scanIntoRolePermissions(row rowScanner) (*domain.RolePermissions, error) {
+	b := &domain.RolePermissions{Overridden: true}
+	var permissions pq.StringArray
+	var updatedAt time.Time
+	if err := row.Scan(&b.TenantID, &b.Role, &permissions, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	b.UpdatedAt = &updatedAt
+	b.Permissions = make([]domain.Permission, len(permissions))
+	for i, p := range permissions {
+		b.Permissions[i] = domain.Permission(p)
+	}
+	return b, nil
+}
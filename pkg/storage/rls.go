@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// The roles and the tenant_isolation policies are created by the
+// 0006_enable_row_level_security migration
+const (
+	// TenantRole is the role tenant scoped transactions run as,
+	// row-level security limits it to the rows of app.tenant_id
+	TenantRole = "core_tenant"
+	// AdminRole bypasses row-level security, it's meant for maintenance tools
+	AdminRole = "core_admin"
+)
+
+// beginTenantTx starts a transaction limited by row-level security to the rows of
+// tenantID. Callers that only read may roll it back instead of committing it.
+func (s *PostgreSQLStore) beginTenantTx(tenantID string) (*sql.Tx, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(`SET LOCAL ROLE %s`, TenantRole)); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to set tenant role: %w", err)
+	}
+	if _, err := tx.Exec(`SELECT set_config('app.tenant_id', $1, true)`, tenantID); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to set tenant: %w", err)
+	}
+
+	return tx, nil
+}
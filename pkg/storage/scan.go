@@ -5,28 +5,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"github.com/kptm-tools/core-service/pkg/domain"
+	"strings"
 	"time"
-)
-
-func (s *PostgreSQLStore) CreateScansTable() error {
-	query := `create table if not exists scans (
-      id UUID PRIMARY KEY,
-      status JSONB,
-      results JSONB,
-      created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-      updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-  )`
-
-	_, err := s.db.Query(query)
-
-	if err != nil {
-		return err
-	}
 
-	return nil
-
-}
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/lib/pq"
+)
 
 func (s *PostgreSQLStore) ClearScansTable() error {
 	query := `TRUNCATE TABLE scans RESTART IDENTITY CASCADE`
@@ -42,21 +26,284 @@ func (s *PostgreSQLStore) ClearScansTable() error {
 func (s *PostgreSQLStore) CreateScan(sc *domain.Scan) (*domain.Scan, error) {
 
 	status, _ := json.Marshal(sc.HostsStatus)
+	targets, _ := json.Marshal(sc.Targets)
+	tools, _ := json.Marshal(sc.Tools)
 	query := `
-    INSERT INTO scans (id, status, created_at, updated_at)
-    values ($1, $2, $3, $4)
-    RETURNING id, created_at, updated_at`
+    INSERT INTO scans (id, tenant_id, operator_id, scan_status, status, targets, tools, created_at, updated_at)
+    values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    RETURNING id, tenant_id, operator_id, scan_status, tools, created_at, updated_at`
+
+	tx, err := s.beginTenantTx(sc.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-	rows, err := s.db.Query(query, sc.ID, status, sc.CreatedAt, sc.UpdatedAt)
+	rows, err := tx.Query(query, sc.ID, nullableUUID(sc.TenantID), nullableUUID(sc.OperatorID), sc.Status, status, targets, tools, sc.CreatedAt, sc.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("error creating scan: `%v`", err)
 	}
 
+	if !rows.Next() {
+		rows.Close()
+		return nil, fmt.Errorf("error creating Scan")
+	}
+	scan, err := scanIntoScan(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return scan, nil
+}
+
+func (s *PostgreSQLStore) GetScanByID(ID string, tenantID string) (*domain.Scan, error) {
+	query := `
+    SELECT id, tenant_id, operator_id, scan_status, status, results, targets, tools, created_at, updated_at
+    FROM scans
+    WHERE id=$1 AND tenant_id=$2
+  `
+
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, ID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching scan: %w", err)
+	}
+	defer rows.Close()
+
 	for rows.Next() {
 		return scanIntoScan(rows)
 	}
 
-	return nil, fmt.Errorf("error creating Scan")
+	return nil, sql.ErrNoRows
+}
+
+func (s *PostgreSQLStore) GetScans(filter domain.ScanFilter) (*domain.ScanPage, error) {
+	conditions := []string{"tenant_id = ?"}
+	args := []interface{}{filter.TenantID}
+
+	if filter.Status != "" {
+		conditions = append(conditions, "scan_status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at <= ?")
+		args = append(args, filter.To.UTC())
+	}
+	if filter.HostAlias != "" {
+		// Targets is a JSONB array, match scans that contain a target with this alias
+		aliasJSONB, err := json.Marshal([]map[string]string{{"alias": filter.HostAlias}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal alias filter: %w", err)
+		}
+		conditions = append(conditions, "targets @> ?::jsonb")
+		args = append(args, aliasJSONB)
+	}
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		createdAt, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", err.Error(), domain.ErrInvalidCursor)
+		}
+		conditions = append(conditions, "(created_at, id) < (?, ?)")
+		args = append(args, createdAt, c.ID)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = domain.DefaultScanPageSize
+	}
+	if limit > domain.MaxScanPageSize {
+		limit = domain.MaxScanPageSize
+	}
+	// Fetch one extra row to know if there is a next page
+	args = append(args, limit+1)
+
+	query := replaceSQL(fmt.Sprintf(`
+    SELECT id, tenant_id, operator_id, scan_status, status, results, targets, tools, created_at, updated_at
+    FROM scans
+    WHERE %s
+    ORDER BY created_at DESC, id DESC
+    LIMIT ?
+  `, strings.Join(conditions, " AND ")), "?")
+
+	tx, err := s.beginTenantTx(filter.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching scans: %w", err)
+	}
+	defer rows.Close()
+
+	page := &domain.ScanPage{Scans: []*domain.Scan{}}
+	for rows.Next() {
+		scan, err := scanIntoScan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scan: %w", err)
+		}
+		page.Scans = append(page.Scans, scan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating scans: %w", err)
+	}
+
+	if len(page.Scans) > limit {
+		page.Scans = page.Scans[:limit]
+		last := page.Scans[limit-1]
+		page.NextCursor = encodeCursor(last.CreatedAt.Format(time.RFC3339Nano), last.ID)
+	}
+
+	return page, nil
+}
+
+// UpdateScanByID locks the scan row, lets update modify it and writes the
+// status and results back in the same transaction, so concurrent events
+// for the same scan don't overwrite each other.
+func (s *PostgreSQLStore) UpdateScanByID(ID string, update func(*domain.Scan) error) (*domain.Scan, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	selectQuery := `
+    SELECT id, tenant_id, operator_id, scan_status, status, results, targets, tools, created_at, updated_at
+    FROM scans
+    WHERE id=$1
+    FOR UPDATE
+  `
+	rows, err := tx.Query(selectQuery, ID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching scan: %w", err)
+	}
+
+	var scan *domain.Scan
+	if rows.Next() {
+		scan, err = scanIntoScan(rows)
+	}
+	rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan scan: %w", err)
+	}
+	if scan == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	if err := update(scan); err != nil {
+		return nil, err
+	}
+
+	status, err := json.Marshal(scan.HostsStatus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal status: %w", err)
+	}
+	results, err := json.Marshal(scan.HostsResults)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal results: %w", err)
+	}
+	// Postgres stores microseconds, truncate so the value matches what is read back
+	scan.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	updateQuery := `
+    UPDATE scans
+    SET scan_status=$2, status=$3, results=$4, updated_at=$5
+    WHERE id=$1
+  `
+	if _, err := tx.Exec(updateQuery, scan.ID, scan.Status, status, results, scan.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("failed to update scan: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return scan, nil
+}
+
+// GetScansByStatus returns the scans of every tenant that are in one of
+// the given statuses and were created before createdBefore
+func (s *PostgreSQLStore) GetScansByStatus(statuses []domain.ScanStatus, createdBefore time.Time) ([]*domain.Scan, error) {
+	strStatuses := make([]string, 0, len(statuses))
+	for _, st := range statuses {
+		strStatuses = append(strStatuses, st.String())
+	}
+
+	query := `
+    SELECT id, tenant_id, operator_id, scan_status, status, results, targets, tools, created_at, updated_at
+    FROM scans
+    WHERE scan_status = ANY($1) AND created_at < $2
+  `
+	rows, err := s.db.Query(query, pq.Array(strStatuses), createdBefore.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("error fetching scans: %w", err)
+	}
+	defer rows.Close()
+
+	scans := []*domain.Scan{}
+	for rows.Next() {
+		scan, err := scanIntoScan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scan: %w", err)
+		}
+		scans = append(scans, scan)
+	}
+
+	return scans, nil
+}
+
+// ExistsActiveScanForHosts reports whether the tenant has a pending or
+// running scan that targets any of the given host aliases
+func (s *PostgreSQLStore) ExistsActiveScanForHosts(tenantID string, aliases []string) (bool, error) {
+	active := []string{}
+	for _, st := range domain.ActiveScanStatuses() {
+		active = append(active, st.String())
+	}
+
+	var exists bool
+	query := `
+    SELECT EXISTS (
+      SELECT 1
+      FROM scans
+      WHERE tenant_id = $1
+        AND scan_status = ANY($2)
+        AND EXISTS (
+          SELECT 1 FROM jsonb_array_elements(targets) t
+          WHERE t->>'alias' = ANY($3)
+        )
+    )
+  `
+	tx, err := s.beginTenantTx(tenantID)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(query, tenantID, pq.Array(active), pq.Array(aliases)).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify active scans: %w", err)
+	}
+
+	return exists, nil
 }
 
 func scanIntoScan(rows *sql.Rows) (*domain.Scan, error) {
@@ -75,12 +322,37 @@ func scanIntoScan(rows *sql.Rows) (*domain.Scan, error) {
 	for i, colName := range cols {
 		val := columnPointers[i].(*interface{})
 		var x = *val
+		if x == nil {
+			continue
+		}
 		switch colName {
 		case "id":
 			{
 				z := bytes.NewBuffer(x.([]byte))
 				scan.ID = z.String()
 			}
+		case "tenant_id":
+			scan.TenantID = string(x.([]byte))
+		case "operator_id":
+			scan.OperatorID = string(x.([]byte))
+		case "scan_status":
+			scan.Status = domain.ScanStatus(columnString(x))
+		case "status":
+			if err := json.Unmarshal(x.([]byte), &scan.HostsStatus); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal status: %w", err)
+			}
+		case "results":
+			if err := json.Unmarshal(x.([]byte), &scan.HostsResults); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal results: %w", err)
+			}
+		case "targets":
+			if err := json.Unmarshal(x.([]byte), &scan.Targets); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal targets: %w", err)
+			}
+		case "tools":
+			if err := json.Unmarshal(x.([]byte), &scan.Tools); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal tools: %w", err)
+			}
 		case "created_at":
 			scan.CreatedAt, _ = x.(time.Time)
 		case "updated_at":
@@ -89,3 +361,23 @@ func scanIntoScan(rows *sql.Rows) (*domain.Scan, error) {
 	}
 	return scan, nil
 }
+
+// columnString reads a text column scanned into an interface{}
+func columnString(x interface{}) string {
+	switch v := x.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// nullableUUID maps empty strings to NULL so optional UUID columns can be left unset
+func nullableUUID(id string) interface{} {
+	if id == "" {
+		return nil
+	}
+	return id
+}
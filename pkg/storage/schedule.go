@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/lib/pq"
+)
+
+func (s *PostgreSQLStore) ClearSchedulesTable() error {
+	query := `TRUNCATE TABLE scan_schedules RESTART IDENTITY CASCADE`
+
+	_, err := s.db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+const scheduleColumns = `id, tenant_id, operator_id, name, host_ids, cron_expression, interval_minutes, tools, enabled, next_run_at, last_run_at, last_scan_id, created_at, updated_at`
+
+func (s *PostgreSQLStore) CreateSchedule(sc *domain.ScanSchedule) (*domain.ScanSchedule, error) {
+	query := fmt.Sprintf(`
+    INSERT INTO scan_schedules (id, tenant_id, operator_id, name, host_ids, cron_expression, interval_minutes, tools, enabled, next_run_at, created_at, updated_at)
+    values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    RETURNING %s`, scheduleColumns)
+
+	tools, err := scheduleTools(sc.Tools)
+	if err != nil {
+		return nil, err
+	}
+
+	row := s.db.QueryRow(query, sc.ID, sc.TenantID, nullableUUID(sc.OperatorID), sc.Name, pq.Array(sc.HostIDs),
+		nullableString(sc.CronExpression), nullableInt(sc.IntervalMinutes), tools, sc.Enabled, sc.NextRunAt, sc.CreatedAt, sc.UpdatedAt)
+
+	schedule, err := scanIntoSchedule(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+func (s *PostgreSQLStore) GetSchedulesByTenantID(tenantID string) ([]*domain.ScanSchedule, error) {
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM scan_schedules
+    WHERE tenant_id=$1
+    ORDER BY created_at DESC
+  `, scheduleColumns)
+
+	rows, err := s.db.Query(query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schedules: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIntoSchedules(rows)
+}
+
+func (s *PostgreSQLStore) GetScheduleByID(ID string, tenantID string) (*domain.ScanSchedule, error) {
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM scan_schedules
+    WHERE id=$1 AND tenant_id=$2
+  `, scheduleColumns)
+
+	schedule, err := scanIntoSchedule(s.db.QueryRow(query, ID, tenantID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+func (s *PostgreSQLStore) PatchScheduleByID(sc *domain.ScanSchedule) (*domain.ScanSchedule, error) {
+	query := fmt.Sprintf(`
+    UPDATE scan_schedules
+    SET name=$3, host_ids=$4, cron_expression=$5, interval_minutes=$6, tools=$7, enabled=$8, next_run_at=$9, updated_at=$10
+    WHERE id=$1 AND tenant_id=$2
+    RETURNING %s
+  `, scheduleColumns)
+
+	tools, err := scheduleTools(sc.Tools)
+	if err != nil {
+		return nil, err
+	}
+
+	row := s.db.QueryRow(query, sc.ID, sc.TenantID, sc.Name, pq.Array(sc.HostIDs),
+		nullableString(sc.CronExpression), nullableInt(sc.IntervalMinutes), tools, sc.Enabled, sc.NextRunAt, time.Now().UTC())
+
+	schedule, err := scanIntoSchedule(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+func (s *PostgreSQLStore) DeleteScheduleByID(ID string, tenantID string) (bool, error) {
+	query := `
+    DELETE
+    FROM scan_schedules
+    WHERE id=$1 AND tenant_id=$2
+  `
+	res, err := s.db.Exec(query, ID, tenantID)
+	if err != nil {
+		return false, err
+	}
+
+	count, _ := res.RowsAffected()
+	return count == 1, nil
+}
+
+// GetDueSchedules returns the enabled schedules of every tenant whose next run is due
+func (s *PostgreSQLStore) GetDueSchedules(now time.Time) ([]*domain.ScanSchedule, error) {
+	query := fmt.Sprintf(`
+    SELECT %s
+    FROM scan_schedules
+    WHERE enabled AND next_run_at <= $1
+    ORDER BY next_run_at
+  `, scheduleColumns)
+
+	rows, err := s.db.Query(query, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch due schedules: %w", err)
+	}
+	defer rows.Close()
+
+	return scanIntoSchedules(rows)
+}
+
+// MarkScheduleRun records a run of the schedule and when it's due next.
+// scanID is empty when the run was skipped.
+func (s *PostgreSQLStore) MarkScheduleRun(ID string, runAt time.Time, nextRunAt *time.Time, scanID string) error {
+	query := `
+    UPDATE scan_schedules
+    SET last_run_at=$2, next_run_at=$3, last_scan_id=COALESCE($4, last_scan_id), updated_at=$5
+    WHERE id=$1
+  `
+	if _, err := s.db.Exec(query, ID, runAt.UTC(), nextRunAt, nullableUUID(scanID), time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to mark schedule run: %w", err)
+	}
+
+	return nil
+}
+
+func scanIntoSchedules(rows *sql.Rows) ([]*domain.ScanSchedule, error) {
+	schedules := []*domain.ScanSchedule{}
+	for rows.Next() {
+		schedule, err := scanIntoSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schedule: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanIntoSchedule(row rowScanner) (*domain.ScanSchedule, error) {
+	schedule := new(domain.ScanSchedule)
+
+	var (
+		operatorID      sql.NullString
+		hostIDs         pq.Int64Array
+		cronExpression  sql.NullString
+		intervalMinutes sql.NullInt64
+		tools           []byte
+		nextRunAt       sql.NullTime
+		lastRunAt       sql.NullTime
+		lastScanID      sql.NullString
+	)
+
+	err := row.Scan(
+		&schedule.ID,
+		&schedule.TenantID,
+		&operatorID,
+		&schedule.Name,
+		&hostIDs,
+		&cronExpression,
+		&intervalMinutes,
+		&tools,
+		&schedule.Enabled,
+		&nextRunAt,
+		&lastRunAt,
+		&lastScanID,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.OperatorID = operatorID.String
+	schedule.CronExpression = cronExpression.String
+	schedule.IntervalMinutes = int(intervalMinutes.Int64)
+	schedule.LastScanID = lastScanID.String
+	if tools != nil {
+		if err := json.Unmarshal(tools, &schedule.Tools); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tools: %w", err)
+		}
+	}
+	for _, id := range hostIDs {
+		schedule.HostIDs = append(schedule.HostIDs, int(id))
+	}
+	if nextRunAt.Valid {
+		schedule.NextRunAt = &nextRunAt.Time
+	}
+	if lastRunAt.Valid {
+		schedule.LastRunAt = &lastRunAt.Time
+	}
+
+	return schedule, nil
+}
+
+// scheduleTools encodes the tools of a schedule, no tools are stored as NULL
+func scheduleTools(tools []domain.ScanTool) (interface{}, error) {
+	if len(tools) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(tools)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tools: %w", err)
+	}
+	return b, nil
+}
+
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func nullableInt(i int) interface{} {
+	if i == 0 {
+		return nil
+	}
+	return i
+}
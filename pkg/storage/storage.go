@@ -50,32 +50,42 @@ func (s *PostgreSQLStore) Init() error {
 	return nil
 }
 
-func (s *PostgreSQLStore) InitCoreDB() error {
-
-	// Attempt to create Hosts Table
-	if err := s.CreateHostsTable(); err != nil {
+func (s *PostgreSQLStore) ClearCoreDB() error {
+	// Attempt to clear Audit Events Table
+	if err := s.ClearAuditEventsTable(); err != nil {
 		return err
 	}
-	if err := s.CreateCredentialsTable(); err != nil {
+
+	// Attempt to clear Role Permissions Table
+	if err := s.ClearRolePermissionsTable(); err != nil {
 		return err
 	}
-	// Attempt to create Tenants Table
-	if err := s.CreateTenantsTable(); err != nil {
+
+	// Attempt to clear API Keys Table
+	if err := s.ClearAPIKeysTable(); err != nil {
 		return err
 	}
-	if err := s.CreateScansTable(); err != nil {
+
+	// Attempt to clear Notifications Tables
+	if err := s.ClearNotificationsTables(); err != nil {
 		return err
 	}
 
-	return nil
-}
+	// Attempt to clear Schedules Table
+	if err := s.ClearSchedulesTable(); err != nil {
+		return err
+	}
 
-func (s *PostgreSQLStore) ClearCoreDB() error {
 	// Attempt to clear Scans Table
 	if err := s.ClearScansTable(); err != nil {
 		return err
 	}
 
+	// Attempt to clear Tags and Groups Tables
+	if err := s.ClearHostGroupsTables(); err != nil {
+		return err
+	}
+
 	// Attempt to clear Hosts Table
 	if err := s.ClearHostsTable(); err != nil {
 		return err
@@ -0,0 +1,87 @@
+package subscribers
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	cmmn "github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/nats-io/nats.go"
+)
+
+// listenerBufferSize is how many updates a slow listener may fall behind before
+// updates are dropped. Each update carries the full scan status, so a dropped
+// update is superseded by the next one.
+const listenerBufferSize = 16
+
+// ScanUpdatesBroker holds a single event bus subscription to scan updates
+// and hands each update to the listeners of that scan
+type ScanUpdatesBroker struct {
+	eventBus cmmn.EventBus
+
+	mu        sync.Mutex
+	listeners map[string]map[chan *domain.ScanUpdatedEvent]struct{}
+}
+
+var _ interfaces.IScanUpdatesBroker = (*ScanUpdatesBroker)(nil)
+
+func NewScanUpdatesBroker(bus cmmn.EventBus) *ScanUpdatesBroker {
+	return &ScanUpdatesBroker{
+		eventBus:  bus,
+		listeners: make(map[string]map[chan *domain.ScanUpdatedEvent]struct{}),
+	}
+}
+
+func (b *ScanUpdatesBroker) Init() error {
+	return b.eventBus.Subscribe(domain.ScanUpdatedEventSubject, b.handleScanUpdated)
+}
+
+// Subscribe returns a channel with the updates of the scan,
+// and a function that must be called once the listener is done
+func (b *ScanUpdatesBroker) Subscribe(scanID string) (<-chan *domain.ScanUpdatedEvent, func()) {
+	ch := make(chan *domain.ScanUpdatedEvent, listenerBufferSize)
+
+	b.mu.Lock()
+	if b.listeners[scanID] == nil {
+		b.listeners[scanID] = make(map[chan *domain.ScanUpdatedEvent]struct{})
+	}
+	b.listeners[scanID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.listeners[scanID], ch)
+		if len(b.listeners[scanID]) == 0 {
+			delete(b.listeners, scanID)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+func (b *ScanUpdatesBroker) handleScanUpdated(msg *nats.Msg) {
+	event := new(domain.ScanUpdatedEvent)
+	if err := json.Unmarshal(msg.Data, event); err != nil {
+		log.Printf("Failed to unmarshal scan updated event: `%s`", err.Error())
+		return
+	}
+
+	b.Publish(event)
+}
+
+// Publish hands the update to every listener of its scan without blocking
+func (b *ScanUpdatesBroker) Publish(event *domain.ScanUpdatedEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.listeners[event.ScanID] {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Dropping update for slow listener of scan `%s`", event.ScanID)
+		}
+	}
+}
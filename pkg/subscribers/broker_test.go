@@ -0,0 +1,50 @@
+package subscribers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kptm-tools/core-service/pkg/domain"
+)
+
+func Test_ScanUpdatesBroker(t *testing.T) {
+	broker := NewScanUpdatesBroker(nil)
+
+	updates, unsubscribe := broker.Subscribe("scan-1")
+	otherUpdates, unsubscribeOther := broker.Subscribe("scan-2")
+	defer unsubscribeOther()
+
+	broker.Publish(&domain.ScanUpdatedEvent{ScanID: "scan-1", Status: domain.ScanStatusRunning, UpdatedAt: time.Now()})
+
+	select {
+	case event := <-updates:
+		if event.Status != domain.ScanStatusRunning {
+			t.Errorf("Expected status `%s`, got `%s`", domain.ScanStatusRunning, event.Status)
+		}
+	default:
+		t.Fatal("Expected an update for scan-1")
+	}
+
+	select {
+	case event := <-otherUpdates:
+		t.Errorf("Expected no update for scan-2, got `%+v`", event)
+	default:
+	}
+
+	unsubscribe()
+	broker.Publish(&domain.ScanUpdatedEvent{ScanID: "scan-1", Status: domain.ScanStatusCompleted, UpdatedAt: time.Now()})
+
+	select {
+	case event := <-updates:
+		t.Errorf("Expected no update after unsubscribing, got `%+v`", event)
+	default:
+	}
+
+	// A slow listener must never block the broker
+	for i := 0; i < listenerBufferSize+5; i++ {
+		broker.Publish(&domain.ScanUpdatedEvent{ScanID: "scan-2", UpdatedAt: time.Now()})
+	}
+	if len(otherUpdates) != listenerBufferSize {
+		t.Errorf("Expected `%d` buffered updates, got `%d`", listenerBufferSize, len(otherUpdates))
+	}
+}
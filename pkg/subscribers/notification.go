@@ -0,0 +1,54 @@
+package subscribers
+
+import (
+	"encoding/json"
+	"log"
+
+	cmmn "github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/nats-io/nats.go"
+)
+
+// NotificationSubscribers notifies rapporteurs when a scan reaches a terminal status
+type NotificationSubscribers struct {
+	notificationService interfaces.INotificationService
+	eventBus            cmmn.EventBus
+}
+
+var _ interfaces.INotificationSubscribers = (*NotificationSubscribers)(nil)
+
+func NewNotificationSubscribers(notificationService interfaces.INotificationService, bus cmmn.EventBus) *NotificationSubscribers {
+	return &NotificationSubscribers{
+		notificationService: notificationService,
+		eventBus:            bus,
+	}
+}
+
+func (s *NotificationSubscribers) Init() error {
+	return s.eventBus.Init(func() error {
+		return s.eventBus.Subscribe(domain.ScanUpdatedEventSubject, s.handleScanUpdated)
+	})
+}
+
+func (s *NotificationSubscribers) handleScanUpdated(msg *nats.Msg) {
+	event := new(domain.ScanUpdatedEvent)
+	if err := json.Unmarshal(msg.Data, event); err != nil {
+		log.Printf("Failed to unmarshal scan updated event: `%s`", err.Error())
+		return
+	}
+	if !event.Status.IsTerminal() {
+		return
+	}
+
+	// Sending emails is slow, don't hold up the subscription
+	go func() {
+		sent, err := s.notificationService.NotifyScanFinished(event.ScanID, event.TenantID)
+		if err != nil {
+			log.Printf("Failed to notify rapporteurs of scan `%s`: `%s`", event.ScanID, err.Error())
+		}
+		if len(sent) > 0 {
+			log.Printf("Sent %d notifications for scan `%s`", len(sent), event.ScanID)
+		}
+	}()
+}
@@ -0,0 +1,100 @@
+package subscribers
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/kptm-tools/common/common/enums"
+	cmmn "github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/common/common/results"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/kptm-tools/core-service/pkg/services"
+	"github.com/nats-io/nats.go"
+)
+
+// serviceSubjects maps each scanning service to the subjects it publishes its results and progress on
+var serviceSubjects = map[enums.ServiceName]struct {
+	results  enums.EventSubjectName
+	progress string
+}{
+	enums.ServiceWhoIs:     {enums.WhoIsEventSubject, domain.WhoIsProgressSubject},
+	enums.ServiceHarvester: {enums.HarvesterEventSubject, domain.HarvesterProgressSubject},
+	enums.ServiceDNSLookup: {enums.DNSLookupEventSubject, domain.DNSLookupProgressSubject},
+	enums.ServiceNmap:      {enums.NmapEventSubject, domain.NmapProgressSubject},
+}
+
+// serviceResultEvent has the shape shared by every service event in [cmmn.ServiceEventMap]
+type serviceResultEvent struct {
+	cmmn.BaseEvent
+	Results []results.TargetResult `json:"results"`
+}
+
+type ScanSubscribers struct {
+	scanService interfaces.IScanService
+	eventBus    cmmn.EventBus
+}
+
+var _ interfaces.IScanSubscribers = (*ScanSubscribers)(nil)
+
+func NewScanSubscribers(scanService interfaces.IScanService, bus cmmn.EventBus) *ScanSubscribers {
+	return &ScanSubscribers{
+		scanService: scanService,
+		eventBus:    bus,
+	}
+}
+
+// Init subscribes to the progress and completion events of every scanning service
+func (s *ScanSubscribers) Init() error {
+	return s.eventBus.Init(func() error {
+		for service, subjects := range serviceSubjects {
+			if err := s.eventBus.Subscribe(string(subjects.results), s.handleServiceResult(service)); err != nil {
+				return err
+			}
+			if err := s.eventBus.Subscribe(subjects.progress, s.handleServiceProgress(service)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *ScanSubscribers) handleServiceResult(service enums.ServiceName) func(msg *nats.Msg) {
+	return func(msg *nats.Msg) {
+		event := new(serviceResultEvent)
+		if err := json.Unmarshal(msg.Data, event); err != nil {
+			log.Printf("Failed to unmarshal %s event: `%s`", service, err.Error())
+			return
+		}
+
+		scan, err := s.scanService.SaveServiceResults(event.ScanID, service, event.Results, event.Error)
+		if err != nil {
+			log.Printf("Failed to save %s results for scan `%s`: `%s`", service, event.ScanID, err.Error())
+			return
+		}
+		s.publishScanUpdated(scan)
+	}
+}
+
+func (s *ScanSubscribers) handleServiceProgress(service enums.ServiceName) func(msg *nats.Msg) {
+	return func(msg *nats.Msg) {
+		event := new(domain.ServiceProgressEvent)
+		if err := json.Unmarshal(msg.Data, event); err != nil {
+			log.Printf("Failed to unmarshal %s progress event: `%s`", service, err.Error())
+			return
+		}
+
+		scan, err := s.scanService.UpdateServiceProgress(event.ScanID, service, event.Target, event.Progress)
+		if err != nil {
+			log.Printf("Failed to update %s progress for scan `%s`: `%s`", service, event.ScanID, err.Error())
+			return
+		}
+		s.publishScanUpdated(scan)
+	}
+}
+
+func (s *ScanSubscribers) publishScanUpdated(scan *domain.Scan) {
+	if err := services.PublishScanUpdated(s.eventBus, scan); err != nil {
+		log.Printf("Failed to publish update for scan `%s`: `%s`", scan.ID, err.Error())
+	}
+}
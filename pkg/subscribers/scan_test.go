@@ -0,0 +1,117 @@
+package subscribers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kptm-tools/common/common/enums"
+	cmmn "github.com/kptm-tools/common/common/events"
+	"github.com/kptm-tools/common/common/results"
+	"github.com/kptm-tools/core-service/pkg/domain"
+	"github.com/kptm-tools/core-service/pkg/interfaces"
+	"github.com/nats-io/nats.go"
+)
+
+// fakeBus keeps the handler of every subject and records the published subjects
+type fakeBus struct {
+	handlers  map[string]func(*nats.Msg)
+	published []string
+}
+
+func (b *fakeBus) Init(setupSubscriptions func() error) error {
+	return setupSubscriptions()
+}
+
+func (b *fakeBus) Subscribe(subject string, handler func(*nats.Msg)) error {
+	b.handlers[subject] = handler
+	return nil
+}
+
+func (b *fakeBus) Publish(subject string, payload []byte) error {
+	b.published = append(b.published, subject)
+	return nil
+}
+
+// fakeScans records the progress and results it's given
+type fakeScans struct {
+	interfaces.IScanService
+	progress []string
+	results  []string
+}
+
+func (f *fakeScans) UpdateServiceProgress(scanID string, service enums.ServiceName, target string, progress string) (*domain.Scan, error) {
+	f.progress = append(f.progress, string(service)+" "+target+" "+progress)
+	return &domain.Scan{ID: scanID}, nil
+}
+
+func (f *fakeScans) SaveServiceResults(scanID string, service enums.ServiceName, targetResults []results.TargetResult, eventErr *cmmn.EventError) (*domain.Scan, error) {
+	for _, tr := range targetResults {
+		f.results = append(f.results, string(service)+" "+tr.Target)
+	}
+	return &domain.Scan{ID: scanID}, nil
+}
+
+func Test_ScanSubscribers(t *testing.T) {
+	tests := []struct {
+		name             string
+		subject          string
+		data             string
+		expectedProgress []string
+		expectedResults  []string
+		expectedUpdates  int
+	}{
+		{
+			name:             "Progress",
+			subject:          domain.NmapProgressSubject,
+			data:             `{"scan_id": "scan-1", "service": "Nmap", "target": "example.com", "progress": "50%"}`,
+			expectedProgress: []string{"Nmap example.com 50%"},
+			expectedUpdates:  1,
+		},
+		{
+			name:             "Progress of every target",
+			subject:          domain.WhoIsProgressSubject,
+			data:             `{"scan_id": "scan-1", "service": "WhoIs", "progress": "20%"}`,
+			expectedProgress: []string{"WhoIs  20%"},
+			expectedUpdates:  1,
+		},
+		{
+			name:            "Results",
+			subject:         string(enums.HarvesterEventSubject),
+			data:            `{"scan_id": "scan-1", "results": [{"target": "example.com", "results": {"Harvester": {}}}]}`,
+			expectedResults: []string{"Harvester example.com"},
+			expectedUpdates: 1,
+		},
+		{
+			name:            "Malformed event",
+			subject:         domain.NmapProgressSubject,
+			data:            `{"scan_id": `,
+			expectedUpdates: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &fakeBus{handlers: map[string]func(*nats.Msg){}}
+			scans := &fakeScans{}
+			if err := NewScanSubscribers(scans, bus).Init(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			handler, ok := bus.handlers[tt.subject]
+			if !ok {
+				t.Fatalf("Expected a subscription to `%s`", tt.subject)
+			}
+			handler(&nats.Msg{Subject: tt.subject, Data: []byte(tt.data)})
+
+			if !reflect.DeepEqual(scans.progress, tt.expectedProgress) {
+				t.Errorf("Expected progress `%v`, got `%v`", tt.expectedProgress, scans.progress)
+			}
+			if !reflect.DeepEqual(scans.results, tt.expectedResults) {
+				t.Errorf("Expected results `%v`, got `%v`", tt.expectedResults, scans.results)
+			}
+			if len(bus.published) != tt.expectedUpdates {
+				t.Errorf("Expected %d scan updates, got %d", tt.expectedUpdates, len(bus.published))
+			}
+		})
+	}
+}